@@ -0,0 +1,204 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diff
+
+import "slices"
+
+// Compose returns a single list of edits to src that has the effect of
+// applying x to src and then y to the result.
+//
+// Text inserted by x and deleted by y does not appear in the result, and
+// the edits of y within text inserted by x are folded into the edits of x.
+// Compose returns an error if x is not valid for src, or y is not valid
+// for the result of x.
+func Compose(src string, x, y []Edit) ([]Edit, error) {
+	x, _, err := validate(src, x)
+	if err != nil {
+		return nil, err
+	}
+	mid, err := Apply(src, x)
+	if err != nil {
+		return nil, err
+	}
+	y, _, err = validate(mid, y)
+	if err != nil {
+		return nil, err
+	}
+
+	// Represent the result of x as pieces of src and inserted text,
+	// and cut the pieces deleted by y.
+	var (
+		in  = editPieces(src, x)
+		out []piece
+		off int // offset in mid of in[0]
+	)
+	for _, e := range y {
+		for len(in) > 0 && off+in[0].len() <= e.Start {
+			out = append(out, in[0])
+			off += in[0].len()
+			in = in[1:]
+		}
+		if len(in) > 0 && off < e.Start {
+			head, tail := in[0].split(e.Start - off)
+			out = append(out, head)
+			in[0] = tail
+			off = e.Start
+		}
+		for len(in) > 0 && off+in[0].len() <= e.End {
+			off += in[0].len()
+			in = in[1:]
+		}
+		if len(in) > 0 && off < e.End {
+			_, in[0] = in[0].split(e.End - off)
+			off = e.End
+		}
+		if e.New != "" {
+			out = append(out, piece{text: e.New})
+		}
+	}
+	out = append(out, in...)
+
+	// Convert the pieces back to edits of src.
+	var (
+		res     []Edit
+		pending string // text inserted since the last piece of src
+		last    int    // end of the last piece of src
+	)
+	for _, p := range append(out, piece{start: len(src), end: len(src)}) {
+		if p.text != "" {
+			pending += p.text
+			continue
+		}
+		if last < p.start || pending != "" {
+			res = append(res, Edit{last, p.start, pending})
+		}
+		pending, last = "", p.end
+	}
+	return res, nil
+}
+
+// A piece is part of a text derived from src by edits: either inserted
+// text, or the non-empty range [start, end) of src.
+type piece struct {
+	start, end int
+	text       string
+}
+
+func (p piece) len() int {
+	if p.text != "" {
+		return len(p.text)
+	}
+	return p.end - p.start
+}
+
+// split divides a piece at offset n within it.
+func (p piece) split(n int) (piece, piece) {
+	if p.text != "" {
+		return piece{text: p.text[:n]}, piece{text: p.text[n:]}
+	}
+	return piece{start: p.start, end: p.start + n}, piece{start: p.start + n, end: p.end}
+}
+
+// editPieces returns the pieces of the result of applying the sorted,
+// valid edits to src.
+func editPieces(src string, edits []Edit) []piece {
+	var pieces []piece
+	last := 0
+	for _, e := range edits {
+		if last < e.Start {
+			pieces = append(pieces, piece{start: last, end: e.Start})
+		}
+		if e.New != "" {
+			pieces = append(pieces, piece{text: e.New})
+		}
+		last = e.End
+	}
+	if last < len(src) {
+		pieces = append(pieces, piece{start: last, end: len(src)})
+	}
+	return pieces
+}
+
+// Invert returns the edits that restore src from the result of applying
+// edits to it. It returns an error if the edits are not valid for src.
+func Invert(src string, edits []Edit) ([]Edit, error) {
+	edits, _, err := validate(src, edits)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]Edit, len(edits))
+	delta := 0 // offset in the result of a position in src
+	for i, e := range edits {
+		start := e.Start + delta
+		res[i] = Edit{start, start + len(e.New), src[e.Start:e.End]}
+		delta += len(e.New) - (e.End - e.Start)
+	}
+	return res, nil
+}
+
+// Rebase transforms x, a valid, ordered list of edits, so that it applies
+// to the result of y, another such list for the same text, in the manner
+// of operational transformation. Applying y and then the result has the
+// same effect as applying Merge(x, y); in particular, edits in x that are
+// identical to edits in y are dropped, and where x and y insert at the
+// same point, the insertion from x comes first. Rebase reports false if
+// Merge reports a conflict.
+//
+// To transform concurrent edits x and y consistently, use Rebase(x, y)
+// for one and Rebase(y, x) for the other only if they do not insert text
+// at the same point, as each places its own insertions first.
+func Rebase(x, y []Edit) ([]Edit, bool) {
+	// This follows the logic of Merge.
+	x = slices.Clone(x)
+	y = slices.Clone(y)
+
+	var res []Edit
+	delta := 0 // change in length due to the edits of y so far
+	var xi, yi int
+	for xi < len(x) && yi < len(y) {
+		px := &x[xi]
+		py := &y[yi]
+
+		if *px == *py {
+			// Already applied by y.
+			delta += len(py.New) - (py.End - py.Start)
+			xi++
+			yi++
+
+		} else if px.End <= py.Start {
+			// x is entirely before y,
+			// or an insertion at start of y.
+			res = append(res, Edit{px.Start + delta, px.End + delta, px.New})
+			xi++
+
+		} else if py.End <= px.Start {
+			// y is entirely before x,
+			// or an insertion at start of x.
+			delta += len(py.New) - (py.End - py.Start)
+			yi++
+
+		} else if px.Start < py.Start {
+			// x is partly before y:
+			// delete that part.
+			res = append(res, Edit{px.Start + delta, py.Start + delta, ""})
+			px.Start = py.Start
+
+		} else if py.Start < px.Start {
+			// y is partly before x:
+			// that part is deleted by y.
+			delta -= px.Start - py.Start
+			py.Start = px.Start
+
+		} else {
+			// x and y are unequal non-insertions
+			// at the same point: conflict.
+			return nil, false
+		}
+	}
+	for ; xi < len(x); xi++ {
+		res = append(res, Edit{x[xi].Start + delta, x[xi].End + delta, x[xi].New})
+	}
+	return res, true
+}
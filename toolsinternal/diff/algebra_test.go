@@ -0,0 +1,152 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diff_test
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/tenntenn/exp/toolsinternal/diff"
+)
+
+// checkAlgebra checks the properties of Compose, Invert and Rebase for
+// the edits between three texts: x transforms a to b, y transforms b to
+// c, and z transforms a to c.
+func checkAlgebra(t *testing.T, a, b, c string) {
+	t.Helper()
+	x, y, z := diff.Strings(a, b), diff.Strings(b, c), diff.Strings(a, c)
+	apply := func(src string, edits []diff.Edit) string {
+		t.Helper()
+		res, err := diff.Apply(src, edits)
+		if err != nil {
+			t.Fatalf("Apply(%q, %v) failed: %v", src, edits, err)
+		}
+		return res
+	}
+
+	// Composition is equivalent to sequential application.
+	xy, err := diff.Compose(a, x, y)
+	if err != nil {
+		t.Fatalf("Compose(%q, %v, %v) failed: %v", a, x, y, err)
+	}
+	if got := apply(a, xy); got != c {
+		t.Fatalf("Compose(%q, %v, %v) = %v, which gives %q, want %q", a, x, y, xy, got, c)
+	}
+
+	// Composition with the inverse is the identity.
+	inv, err := diff.Invert(a, x)
+	if err != nil {
+		t.Fatalf("Invert(%q, %v) failed: %v", a, x, err)
+	}
+	if got := apply(b, inv); got != a {
+		t.Fatalf("Invert(%q, %v) = %v, which gives %q, want %q", a, x, inv, got, a)
+	}
+	if id, err := diff.Compose(a, x, inv); err != nil {
+		t.Fatalf("Compose(%q, %v, %v) failed: %v", a, x, inv, err)
+	} else if got := apply(a, id); got != a {
+		t.Fatalf("Compose(%q, x, Invert(x)) gives %q", a, got)
+	}
+
+	// Rebasing x onto z is equivalent to merging them.
+	merged, ok := diff.Merge(x, z)
+	rebased, ok2 := diff.Rebase(x, z)
+	if ok != ok2 {
+		t.Fatalf("Merge(%v, %v) reports %t, but Rebase reports %t", x, z, ok, ok2)
+	}
+	if ok {
+		want := apply(a, merged)
+		if got := apply(c, rebased); got != want {
+			t.Fatalf("Rebase(%v, %v) = %v, which gives %q, want %q", x, z, rebased, got, want)
+		}
+	}
+}
+
+func TestAlgebra(t *testing.T) {
+	for _, test := range []struct{ a, b, c string }{
+		{"", "", ""},
+		{"abc", "aXbc", "abc"},     // y undoes x
+		{"abc", "aXYZbc", "aXbc"},  // y edits text inserted by x
+		{"abcdef", "af", "aXYf"},   // y inserts where x deleted
+		{"abcdef", "aXdef", "aXf"}, // y deletes across x's edit
+		{"héllo", "hello", "hallo"},
+	} {
+		checkAlgebra(t, test.a, test.b, test.c)
+	}
+
+	rand.Seed(2)
+	for range 1000 {
+		a := randstr("abω", 16)
+		b := randstr("abωc", 16)
+		c := randstr("abd", 16)
+		checkAlgebra(t, a, b, c)
+	}
+}
+
+func TestCompose(t *testing.T) {
+	// Text inserted by x and deleted by y leaves no trace.
+	x := []diff.Edit{{Start: 1, End: 1, New: "XYZ"}, {Start: 2, End: 3, New: "W"}}
+	y := []diff.Edit{{Start: 1, End: 4, New: ""}, {Start: 5, End: 6, New: "V"}}
+	got, err := diff.Compose("abcd", x, y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []diff.Edit{{Start: 2, End: 3, New: "V"}}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Compose = %v, want %v", got, want)
+	}
+
+	// Invalid edits are reported.
+	if _, err := diff.Compose("abcd", x, []diff.Edit{{Start: 7, End: 8}}); err == nil {
+		t.Errorf("Compose with out-of-bounds edit succeeded")
+	}
+}
+
+func TestRebase(t *testing.T) {
+	// A user types while edits from a server are pending: transforming
+	// each against the other converges on the same text.
+	const src = "func f() {\n\treturn\n}\n"
+	user := []diff.Edit{{Start: 18, End: 18, New: " nil"}}
+	server := []diff.Edit{{Start: 7, End: 7, New: "x int"}, {Start: 8, End: 8, New: " error"}}
+	u, ok := diff.Rebase(user, server)
+	if !ok {
+		t.Fatal("Rebase(user, server) failed")
+	}
+	s, ok := diff.Rebase(server, user)
+	if !ok {
+		t.Fatal("Rebase(server, user) failed")
+	}
+	afterServer, _ := diff.Apply(src, server)
+	afterUser, _ := diff.Apply(src, user)
+	got1, err := diff.Apply(afterServer, u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got2, err := diff.Apply(afterUser, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "func f(x int) error {\n\treturn nil\n}\n"
+	if got1 != want || got2 != want {
+		t.Errorf("got %q and %q, want %q", got1, got2, want)
+	}
+
+	// Overlapping replacements conflict.
+	if _, ok := diff.Rebase([]diff.Edit{{Start: 0, End: 2, New: "x"}}, []diff.Edit{{Start: 1, End: 3, New: "y"}}); ok {
+		t.Errorf("Rebase of overlapping edits succeeded")
+	}
+}
+
+// $ go test -fuzz=FuzzAlgebra ./internal/diff
+func FuzzAlgebra(f *testing.F) {
+	f.Add("abcdef", "aXdef", "aXf")
+	f.Fuzz(func(t *testing.T, a, b, c string) {
+		if !utf8.ValidString(a) || !utf8.ValidString(b) || !utf8.ValidString(c) {
+			return // inputs must be text
+		}
+		checkAlgebra(t, a, b, c)
+	})
+}
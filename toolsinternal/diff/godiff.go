@@ -0,0 +1,460 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diff
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tenntenn/exp/toolsinternal/diff/lcs"
+)
+
+// A GoDiff is the result of GoSource.
+type GoDiff struct {
+	// Edits transform the original text into one that has the same tokens
+	// as the modified text, though its layout may differ. Edits are sorted
+	// and do not overlap.
+	Edits []Edit
+
+	// Moves lists the top-level declarations and import specs that moved
+	// without otherwise changing, in order of their original position.
+	// Each move also appears in Edits, as a deletion and an insertion.
+	Moves []DeclMove
+}
+
+// A DeclMove records that a declaration moved within a Go file.
+type DeclMove struct {
+	Name          string // for example "func (*T).M" or `import "fmt"`
+	Before, After Span   // extent in each version, including any doc comment
+}
+
+// GoSource computes the differences between two versions of a Go source
+// file, token by token, so that changes only to white space, such as
+// those made by gofmt, are not reported.
+//
+// Each group of adjacent line comments is compared as a sequence of
+// words, so that rewrapping a comment is not a change either. Directives
+// such as //go:build are compared exactly.
+//
+// Text that is not valid Go is still compared token by token, but moved
+// declarations are detected only if both versions parse.
+func GoSource(before, after string) *GoDiff {
+	res := new(GoDiff)
+	if before == after {
+		return res
+	}
+	a, b := goTokens(before), goTokens(after)
+
+	// Replace each moved declaration by a single token that matches
+	// nothing, so that it is deleted and inserted as a whole.
+	var movedA, movedB []goUnit
+	overlaps := func(units []goUnit, u goUnit) bool {
+		for _, v := range units {
+			if u.start < v.end && v.start < u.end {
+				return true
+			}
+		}
+		return false
+	}
+	for _, m := range goMoves(before, after, a, b) {
+		if overlaps(movedA, m.a) || overlaps(movedB, m.b) {
+			continue // within a declaration that moved
+		}
+		movedA, movedB = append(movedA, m.a), append(movedB, m.b)
+		res.Moves = append(res.Moves, DeclMove{
+			Name:   m.a.name,
+			Before: Span{a[m.a.start].start, a[m.a.end-1].end},
+			After:  Span{b[m.b.start].start, b[m.b.end-1].end},
+		})
+	}
+	sort.Slice(res.Moves, func(i, j int) bool { return res.Moves[i].Before.Start < res.Moves[j].Before.Start })
+	a, b = collapseTokens(a, movedA, "\x00a"), collapseTokens(b, movedB, "\x00b")
+
+	ka, kb := goKeys(a), goKeys(b)
+	x, y := tokenIDs(ka, kb)
+	diffs := myers(x, y)
+	slideHunks(diffs, ka, kb, x, y, goSplitScore)
+	for i, d := range diffs {
+		// Hunks that slid together share the text between them.
+		if i+1 < len(diffs) && d.End == diffs[i+1].Start && d.ReplEnd == diffs[i+1].ReplStart {
+			diffs[i+1].Start, diffs[i+1].ReplStart = d.Start, d.ReplStart
+			continue
+		}
+		if e := goEdit(before, after, a, b, d); e.Start < e.End || e.New != "" {
+			res.Edits = append(res.Edits, e)
+		}
+	}
+	return res
+}
+
+// A goToken is a token of a Go file, or a group of comments.
+type goToken struct {
+	key        string // normalized text, compared between versions
+	start, end int    // extent in the file
+	trailing   bool   // a comment that follows other tokens on its line
+}
+
+// goTokens returns the tokens of a Go file. Semicolons inserted before
+// closing brackets or at EOF, which depend only on layout, are omitted.
+//
+// Semicolons inserted at line ends extend over the newline, and comments
+// over the newline that follows them, so that edits tend to begin and end
+// at line boundaries.
+func goTokens(src string) []goToken {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", -1, len(src))
+	var s scanner.Scanner
+	s.Init(file, []byte(src), nil, scanner.ScanComments)
+
+	var toks []goToken
+	group := false // whether the last token is a group that may be extended
+	auto := false  // whether the last token is an inserted semicolon
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		start := file.Offset(pos)
+		if n := len(toks); n > 0 && start < toks[n-1].end {
+			// A semicolon within an unterminated comment.
+			start = toks[n-1].end
+		}
+		t := goToken{key: lit, start: start, end: start + len(lit)}
+		switch tok {
+		case token.SEMICOLON:
+			if start == len(src) {
+				continue // inserted at EOF
+			}
+			t.key, t.end = ";", start+1
+			if lit == "\n" && src[start] != '\n' {
+				t.end = start // inserted before a comment
+			}
+			toks = append(toks, t)
+			group, auto = false, lit == "\n"
+			continue
+
+		case token.COMMENT:
+			if strings.HasPrefix(lit, "//") {
+				t.end = start + strings.IndexByte(src[start:]+"\n", '\n')
+			} else {
+				t.end = len(src) // unterminated
+				if i := strings.Index(src[start+2:], "*/"); i >= 0 {
+					t.end = start + 2 + i + len("*/")
+				}
+			}
+			if t.end < len(src) && src[t.end] == '\n' {
+				t.end++
+			}
+			lineStart := strings.LastIndexByte(src[:start], '\n') + 1
+			t.trailing = strings.TrimLeft(src[lineStart:start], " \t") != ""
+
+			words := strings.Join(strings.Fields(lit[2:]), " ")
+			switch {
+			case isDirective(lit):
+				t.key = lit
+			case strings.HasPrefix(lit, "//"):
+				t.key = "// " + words
+			default:
+				t.key = "/* " + strings.Join(strings.Fields(strings.TrimSuffix(lit[2:], "*/")), " ")
+			}
+			n := len(toks)
+			extend := group && !t.trailing && strings.HasPrefix(t.key, "// ") &&
+				src[toks[n-1].end-1] == '\n' && !strings.Contains(src[toks[n-1].end:start], "\n")
+			group, auto = !t.trailing && strings.HasPrefix(t.key, "// "), false
+			if extend {
+				if words != "" {
+					toks[n-1].key += " " + words
+				}
+				toks[n-1].end = t.end
+				continue
+			}
+			toks = append(toks, t)
+			continue
+
+		case token.RPAREN, token.RBRACE:
+			// Semicolons inserted before closing brackets depend on
+			// whether they begin a line.
+			if n := len(toks); n > 0 && toks[n-1].key == ";" && auto {
+				toks = toks[:n-1]
+			}
+			t.key, t.end = tok.String(), start+1
+
+		default:
+			if lit == "" {
+				t.key, t.end = tok.String(), start+len(tok.String())
+			} else if strings.HasPrefix(lit, "`") {
+				// The scanner removes carriage returns from raw strings.
+				t.end = start + 1 + strings.IndexByte(src[start+1:]+"`", '`') + 1
+				t.end = min(t.end, len(src))
+			}
+		}
+		group, auto = false, false
+		toks = append(toks, t)
+	}
+	return toks
+}
+
+// isDirective reports whether a comment is a directive such as //go:build
+// or //line, which is compared exactly.
+// This code is also in go/printer.
+func isDirective(comment string) bool {
+	c, ok := strings.CutPrefix(comment, "//")
+	if !ok {
+		return false
+	}
+	// "//line " is a line directive.
+	// "//extern " is for gccgo.
+	// "//export " is for cgo.
+	if strings.HasPrefix(c, "line ") || strings.HasPrefix(c, "extern ") || strings.HasPrefix(c, "export ") {
+		return true
+	}
+
+	// "//[a-z0-9]+:[a-z0-9]"
+	colon := strings.Index(c, ":")
+	if colon <= 0 || colon+1 >= len(c) {
+		return false
+	}
+	for i := 0; i <= colon+1; i++ {
+		if i == colon {
+			continue
+		}
+		b := c[i]
+		if !('a' <= b && b <= 'z' || '0' <= b && b <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func goKeys(toks []goToken) []string {
+	keys := make([]string, len(toks))
+	for i, t := range toks {
+		keys[i] = t.key
+	}
+	return keys
+}
+
+// goSplitScore returns the badness of a hunk boundary just before
+// tokens[pos]. Boundaries at the ends of statements, declarations and
+// comments are preferred.
+func goSplitScore(tokens []string, pos int) int {
+	if pos <= 0 || pos >= len(tokens) {
+		return -100 // start or end of file
+	}
+	switch prev := tokens[pos-1]; {
+	case prev == ";", prev == "{",
+		strings.HasPrefix(prev, "//"), strings.HasPrefix(prev, "/*"), strings.HasPrefix(prev, "\x00"):
+		return 0
+	}
+	return 10
+}
+
+// goEdit converts a difference between the tokens a and b of two texts
+// into an edit of the first. The edit replaces all the text between the
+// unchanged tokens on either side, so that no tokens are joined or split,
+// less any common prefix and suffix.
+func goEdit(before, after string, a, b []goToken, d lcs.Diff) Edit {
+	gap := func(src string, toks []goToken, start, end int) (int, int) {
+		lo, hi := 0, len(src)
+		if start > 0 {
+			lo = toks[start-1].end
+		}
+		if end < len(toks) {
+			hi = toks[end].start
+		}
+		return lo, hi
+	}
+	start, end := gap(before, a, d.Start, d.End)
+	newStart, newEnd := gap(after, b, d.ReplStart, d.ReplEnd)
+
+	// Trim the suffix first, so that a deletion or insertion of whole
+	// lines includes their indentation.
+	old, new := before[start:end], after[newStart:newEnd]
+	suf := 0
+	for suf < len(old) && suf < len(new) && old[len(old)-1-suf] == new[len(new)-1-suf] {
+		suf++
+	}
+	for suf > 0 && !utf8.RuneStart(old[len(old)-suf]) {
+		suf--
+	}
+	old, new = old[:len(old)-suf], new[:len(new)-suf]
+	pre := 0
+	for pre < len(old) && pre < len(new) && old[pre] == new[pre] {
+		pre++
+	}
+	for pre > 0 && pre < len(old) && !utf8.RuneStart(old[pre]) {
+		pre--
+	}
+	return Edit{start + pre, start + len(old), new[pre:]}
+}
+
+// A goUnit is a top-level declaration or an import spec, and the range
+// [start, end) of its tokens. It includes the doc comment, the semicolon
+// that ends it, and any comment that follows on the same line.
+type goUnit struct {
+	name       string
+	start, end int
+}
+
+// A goMove is a unit that moved from a in one version to b in the other.
+type goMove struct{ a, b goUnit }
+
+// goMoves returns the declarations and import specs of two versions of a
+// Go file that moved without otherwise changing. Units are paired by name,
+// and those that are out of order with respect to the largest set of
+// ordered pairs are considered to have moved.
+func goMoves(before, after string, a, b []goToken) []goMove {
+	declsA, importsA := goUnits(before, a)
+	declsB, importsB := goUnits(after, b)
+	var moves []goMove
+	for _, units := range [][2][]goUnit{{declsA, declsB}, {importsA, importsB}} {
+		ua, ub := units[0], units[1]
+		countA := make(map[string]int)
+		countB := make(map[string]int)
+		index := make(map[string]int)
+		for _, u := range ua {
+			countA[u.name]++
+		}
+		for j, u := range ub {
+			countB[u.name]++
+			index[u.name] = j
+		}
+		var pairs []match
+		for i, u := range ua {
+			if countA[u.name] != 1 || countB[u.name] != 1 {
+				continue
+			}
+			v := ub[index[u.name]]
+			if u.start < u.end && equalKeys(a[u.start:u.end], b[v.start:v.end]) {
+				pairs = append(pairs, match{i, index[u.name]})
+			}
+		}
+		if len(pairs) == 0 {
+			continue
+		}
+		kept := longestIncreasing(pairs)
+		for _, p := range pairs {
+			if len(kept) > 0 && kept[0] == p {
+				kept = kept[1:]
+				continue
+			}
+			moves = append(moves, goMove{ua[p.X], ub[p.Y]})
+		}
+	}
+	return moves
+}
+
+// equalKeys reports whether two sequences of tokens are equal, apart
+// from a final semicolon, which is absent before a closing bracket.
+func equalKeys(a, b []goToken) bool {
+	trim := func(toks []goToken) []goToken {
+		if n := len(toks); n > 0 && toks[n-1].key == ";" {
+			return toks[:n-1]
+		}
+		return toks
+	}
+	a, b = trim(a), trim(b)
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].key != b[i].key {
+			return false
+		}
+	}
+	return true
+}
+
+// goUnits returns the top-level declarations and import specs of a Go
+// file whose tokens are toks, or nil if it does not parse.
+func goUnits(src string, toks []goToken) (decls, imports []goUnit) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, nil
+	}
+	tf := fset.File(f.FileStart)
+	tokenAt := func(pos token.Pos) int {
+		offset := tf.Offset(pos)
+		return sort.Search(len(toks), func(i int) bool { return toks[i].start >= offset })
+	}
+	unit := func(name string, doc *ast.CommentGroup, n ast.Node) goUnit {
+		start := n.Pos()
+		if doc != nil {
+			start = doc.Pos()
+		}
+		u := goUnit{name, tokenAt(start), tokenAt(n.End())}
+		if u.end < len(toks) && toks[u.end].key == ";" {
+			u.end++
+		}
+		if u.end < len(toks) && toks[u.end].trailing {
+			u.end++
+		}
+		return u
+	}
+
+	for _, decl := range f.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			name := "func " + decl.Name.Name
+			if decl.Recv != nil && len(decl.Recv.List) > 0 {
+				name = "func (" + types.ExprString(decl.Recv.List[0].Type) + ")." + decl.Name.Name
+			}
+			decls = append(decls, unit(name, decl.Doc, decl))
+
+		case *ast.GenDecl:
+			var names []string
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.ImportSpec:
+					name := spec.Path.Value
+					if spec.Name != nil {
+						name = spec.Name.Name + " " + name
+					}
+					names = append(names, name)
+					imports = append(imports, unit("import "+name, spec.Doc, spec))
+				case *ast.ValueSpec:
+					for _, id := range spec.Names {
+						names = append(names, id.Name)
+					}
+				case *ast.TypeSpec:
+					names = append(names, spec.Name.Name)
+				}
+			}
+			decls = append(decls, unit(decl.Tok.String()+" "+strings.Join(names, ", "), decl.Doc, decl))
+		}
+	}
+	return decls, imports
+}
+
+// collapseTokens returns toks with the tokens of each of the given units,
+// which do not overlap, replaced by a single token whose key is prefix
+// followed by a number.
+func collapseTokens(toks []goToken, units []goUnit, prefix string) []goToken {
+	if len(units) == 0 {
+		return toks
+	}
+	units = append([]goUnit(nil), units...)
+	sort.Slice(units, func(i, j int) bool { return units[i].start < units[j].start })
+	var res []goToken
+	last := 0
+	for i, u := range units {
+		res = append(res, toks[last:u.start]...)
+		res = append(res, goToken{
+			key:   prefix + strconv.Itoa(i),
+			start: toks[u.start].start,
+			end:   toks[u.end-1].end,
+		})
+		last = u.end
+	}
+	return append(res, toks[last:]...)
+}
@@ -0,0 +1,178 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diff_test
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/tenntenn/exp/toolsinternal/diff"
+	"github.com/tenntenn/exp/toolsinternal/diff/difftest"
+)
+
+// checkGoSource checks that the edits of GoSource transform before into a
+// text with the same tokens as after, and returns that text.
+func checkGoSource(t *testing.T, before, after string) (string, *diff.GoDiff) {
+	t.Helper()
+	d := diff.GoSource(before, after)
+	got, err := diff.Apply(before, d.Edits)
+	if err != nil {
+		t.Fatalf("Apply(%q, %v) failed: %v", before, d.Edits, err)
+	}
+	if rest := diff.GoSource(got, after).Edits; len(rest) > 0 {
+		t.Fatalf("GoSource(%q, %q) = %v, leaving %q, which differs by %v", before, after, d.Edits, got, rest)
+	}
+	return got, d
+}
+
+func TestGoSourceLayout(t *testing.T) {
+	// Changes to layout and to the wrapping of comments are ignored.
+	const before = `package p
+
+// F does
+// something.
+func F(x int) int { return x }
+
+var (
+	a = 1
+	bb = 2 // two
+)
+`
+	const after = `package p
+
+// F does something.
+func F(x int) int {
+	return x
+}
+
+var (
+	a  = 1
+	bb = 2 // two
+)
+`
+	if d := diff.GoSource(before, after); len(d.Edits) > 0 || len(d.Moves) > 0 {
+		t.Errorf("GoSource = %+v, want no changes", d)
+	}
+
+	// Comments that merely begin with a lower-case letter, such as
+	// commented-out code, are not directives.
+	for _, test := range []struct{ before, after string }{
+		{"package p\n\n//fmt.Println(x)\nvar x = 1\n", "package p\n\n// fmt.Println(x)\nvar x = 1\n"},
+		{"package p\n\n//see\n//below\nvar x = 1\n", "package p\n\n// see below\nvar x = 1\n"},
+	} {
+		if d := diff.GoSource(test.before, test.after); len(d.Edits) > 0 || len(d.Moves) > 0 {
+			t.Errorf("GoSource(%q, %q) = %+v, want no changes", test.before, test.after, d)
+		}
+	}
+
+	// But changes to directives and code are not.
+	for _, test := range []struct{ before, after, want string }{
+		{"//go:build linux\n\npackage p\n", "//go:build darwin\n\npackage p\n", "//go:build darwin\n\npackage p\n"},
+		{"// go:build linux\n\npackage p\n", "//go:build linux\n\npackage p\n", "//go:build linux\n\npackage p\n"},
+		{"package p\n\n//line a.go:1\nvar x = 1\n", "package p\n\n//line  a.go:1\nvar x = 1\n", "package p\n\n//line  a.go:1\nvar x = 1\n"},
+		{"package p\n\n// a b\nvar x = 1\n", "package p\n\n// a c\nvar x = 1\n", "package p\n\n// a c\nvar x = 1\n"},
+		{
+			"package p\n\nfunc f() {\n\tx := 1\n\ty := 2\n}\n",
+			"package p\n\nfunc f() {\n\tx := 1\n\tz := 3\n\ty := 2\n}\n",
+			"package p\n\nfunc f() {\n\tx := 1\n\tz := 3\n\ty := 2\n}\n",
+		},
+		{
+			"package p\n\nfunc f() {\n\tx := 1\n\ty := 2\n\tz := 3\n}\n",
+			"package p\n\nfunc f() {\n\tx := 1\n\tz := 3\n}\n",
+			"package p\n\nfunc f() {\n\tx := 1\n\tz := 3\n}\n",
+		},
+		{
+			// Layout is kept except between the changed tokens.
+			"package p\nvar x = f(1,2)\n",
+			"package p\n\nvar x = f(1, 3)\n",
+			"package p\nvar x = f(1, 3)\n",
+		},
+		{
+			// Text inserted after a line comment starts a new line.
+			"package p\nvar x = 1 // x\n",
+			"package p\nvar x = 1 // x\nvar y = 2\n",
+			"package p\nvar x = 1 // x\nvar y = 2\n",
+		},
+	} {
+		got, _ := checkGoSource(t, test.before, test.after)
+		if got != test.want {
+			t.Errorf("GoSource(%q, %q) produces %q, want %q", test.before, test.after, got, test.want)
+		}
+	}
+}
+
+func TestGoSourceMoves(t *testing.T) {
+	const before = `package p
+
+import (
+	"os"
+	"fmt"
+	"bytes"
+)
+
+// A is a.
+func A() {}
+
+func (*T) M() {
+	fmt.Println()
+}
+
+type T int
+`
+	const after = `package p
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+)
+
+type T int
+
+// A is a.
+func A() {}
+
+func (*T) M() {
+	fmt.Println()
+}
+`
+	got, d := checkGoSource(t, before, after)
+	if got != after {
+		t.Errorf("GoSource produces:\n%s\nwant:\n%s", got, after)
+	}
+	var moves []string
+	for _, m := range d.Moves {
+		text := before[m.Before.Start:m.Before.End]
+		if moved := after[m.After.Start:m.After.End]; strings.TrimSpace(moved) != strings.TrimSpace(text) {
+			t.Errorf("%s: moved %q to %q", m.Name, text, moved)
+		}
+		moves = append(moves, fmt.Sprintf("%s %q", m.Name, text))
+	}
+	want := []string{
+		`import "os" "\"os\"\n"`,
+		`import "fmt" "\"fmt\"\n"`,
+		`type T "type T int\n"`,
+	}
+	if fmt.Sprint(moves) != fmt.Sprint(want) {
+		t.Errorf("moves = %q, want %q", moves, want)
+	}
+}
+
+func TestGoSourceDifftest(t *testing.T) {
+	// Text that is not Go is compared token by token too.
+	for _, tc := range difftest.TestCases {
+		checkGoSource(t, tc.In, tc.Out)
+	}
+	// Random inputs avoid unterminated comments and raw strings, which
+	// absorb all the text that follows them.
+	rng := rand.New(rand.NewSource(1))
+	for range 1000 {
+		a := randLines(rng, "ab(){}\n +", 40)
+		b := randLines(rng, "abc(){}\n +", 40)
+		checkGoSource(t, a, b)
+	}
+}
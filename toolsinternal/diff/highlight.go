@@ -0,0 +1,276 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diff
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/tenntenn/exp/toolsinternal/diff/lcs"
+)
+
+// A HighlightedDiff is a unified diff of a single file in which each
+// deleted line is paired, where possible, with an inserted line, and the
+// parts of those lines that changed are identified.
+type HighlightedDiff struct {
+	From, To string // names of the original and modified files
+	Hunks    []HighlightedHunk
+}
+
+// A HighlightedHunk is a hunk of a HighlightedDiff.
+type HighlightedHunk struct {
+	OldStart, OldLines int
+	NewStart, NewLines int
+	Lines              []HighlightedLine
+}
+
+// A HighlightedLine is a line of a HighlightedHunk.
+type HighlightedLine struct {
+	Op      byte   // ' ' for context, '-' for a deletion, '+' for an insertion
+	Content string // text of the line, including its newline if it has one
+
+	// Changed holds the parts of Content, as byte ranges in increasing
+	// order, that differ from the line it is paired with. It is empty for
+	// context lines, and covers the whole line (without its newline) for
+	// lines that are not paired.
+	Changed []Span
+}
+
+// A Span is a range of bytes [Start, End).
+type Span struct{ Start, End int }
+
+// HighlightUnified is like ToUnified, but returns the diff with intra-line
+// changes highlighted.
+func HighlightUnified(oldLabel, newLabel, content string, edits []Edit, contextLines int) (*HighlightedDiff, error) {
+	u, err := toUnified(oldLabel, newLabel, content, edits, contextLines)
+	if err != nil {
+		return nil, err
+	}
+	file := &FileDiff{OldName: u.from, NewName: u.to}
+	for _, h := range u.hunks {
+		file.Hunks = append(file.Hunks, h.export())
+	}
+	return Highlight(file), nil
+}
+
+// export returns the exported form of a hunk computed by toUnified.
+func (h *hunk) export() *Hunk {
+	res := &Hunk{OldStart: h.fromLine, NewStart: h.toLine}
+	for _, l := range h.lines {
+		var op byte
+		switch l.kind {
+		case opDelete:
+			op = '-'
+			res.OldLines++
+		case opInsert:
+			op = '+'
+			res.NewLines++
+		default:
+			op = ' '
+			res.OldLines++
+			res.NewLines++
+		}
+		res.Lines = append(res.Lines, HunkLine{Op: op, Content: l.content})
+	}
+	// An empty range starts at the line before it.
+	if res.OldLines == 0 {
+		res.OldStart--
+	}
+	if res.NewLines == 0 {
+		res.NewStart--
+	}
+	return res
+}
+
+// Highlight computes the intra-line changes of the hunks of file.
+//
+// Within each block of deleted lines followed by inserted lines, the nth
+// deleted line is paired with the nth inserted line, and their rune-level
+// differences are computed. Pairs that have less than half of their text
+// in common are not considered similar, and are highlighted as a whole.
+func Highlight(file *FileDiff) *HighlightedDiff {
+	d := &HighlightedDiff{From: file.OldName, To: file.NewName}
+	for _, h := range file.Hunks {
+		hh := HighlightedHunk{
+			OldStart: h.OldStart,
+			OldLines: h.OldLines,
+			NewStart: h.NewStart,
+			NewLines: h.NewLines,
+			Lines:    make([]HighlightedLine, len(h.Lines)),
+		}
+		for i, l := range h.Lines {
+			hh.Lines[i] = HighlightedLine{Op: l.Op, Content: l.Content}
+			if l.Op != ' ' {
+				hh.Lines[i].Changed = []Span{{0, len(strings.TrimSuffix(l.Content, "\n"))}}
+			}
+		}
+		// Pair the lines of each block of deletions and insertions.
+		for i := 0; i < len(hh.Lines); {
+			dels := i
+			for i < len(hh.Lines) && hh.Lines[i].Op == '-' {
+				i++
+			}
+			ins := i
+			for i < len(hh.Lines) && hh.Lines[i].Op == '+' {
+				i++
+			}
+			if dels == i {
+				i++ // context
+				continue
+			}
+			for k := 0; dels+k < ins && ins+k < i; k++ {
+				highlightPair(&hh.Lines[dels+k], &hh.Lines[ins+k])
+			}
+		}
+		d.Hunks = append(d.Hunks, hh)
+	}
+	return d
+}
+
+// highlightPair computes the changed spans of a deleted line and the
+// inserted line that replaces it.
+func highlightPair(del, ins *HighlightedLine) {
+	before := []rune(strings.TrimSuffix(del.Content, "\n"))
+	after := []rune(strings.TrimSuffix(ins.Content, "\n"))
+	diffs := lcs.DiffRunes(before, after)
+
+	changed := 0
+	for _, d := range diffs {
+		changed += d.End - d.Start + d.ReplEnd - d.ReplStart
+	}
+	if total := len(before) + len(after); 2*changed > total {
+		return // too different: leave whole lines highlighted
+	}
+
+	// Convert rune offsets to byte offsets.
+	del.Changed, ins.Changed = nil, nil
+	var x, y, bx, by int // rune and byte offsets in before and after
+	for _, d := range diffs {
+		bx += runesLen(before[x:d.Start])
+		by += runesLen(after[y:d.ReplStart])
+		x, y = d.Start, d.ReplStart
+		if d.End > d.Start {
+			n := runesLen(before[d.Start:d.End])
+			del.Changed = append(del.Changed, Span{bx, bx + n})
+			bx, x = bx+n, d.End
+		}
+		if d.ReplEnd > d.ReplStart {
+			n := runesLen(after[d.ReplStart:d.ReplEnd])
+			ins.Changed = append(ins.Changed, Span{by, by + n})
+			by, y = by+n, d.ReplEnd
+		}
+	}
+}
+
+// header returns the "@@" line of a hunk, without its newline.
+func (h *HighlightedHunk) header() string {
+	return fmt.Sprintf("@@ %s %s @@", hunkRange("-", h.OldStart, h.OldLines), hunkRange("+", h.NewStart, h.NewLines))
+}
+
+// ANSI escape sequences used by the ANSI method.
+const (
+	ansiReset   = "\x1b[m"
+	ansiBold    = "\x1b[1m"
+	ansiCyan    = "\x1b[36m"
+	ansiRed     = "\x1b[31m"
+	ansiGreen   = "\x1b[32m"
+	ansiReverse = "\x1b[7m"
+	ansiNoRev   = "\x1b[27m"
+)
+
+// ANSI returns the diff in unified form, colored with ANSI escape sequences
+// for display in a terminal. Changed parts of lines are shown in reverse
+// video.
+func (d *HighlightedDiff) ANSI() string {
+	if len(d.Hunks) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s--- %s%s\n", ansiBold, d.From, ansiReset)
+	fmt.Fprintf(&b, "%s+++ %s%s\n", ansiBold, d.To, ansiReset)
+	for _, h := range d.Hunks {
+		fmt.Fprintf(&b, "%s%s%s\n", ansiCyan, h.header(), ansiReset)
+		for _, l := range h.Lines {
+			color := ""
+			switch l.Op {
+			case '-':
+				color = ansiRed
+			case '+':
+				color = ansiGreen
+			}
+			b.WriteString(color)
+			b.WriteByte(l.Op)
+			writeSpans(&b, l, ansiReverse, ansiNoRev, func(s string) string { return s })
+			if color != "" {
+				b.WriteString(ansiReset)
+			}
+			b.WriteString(lineEnd(l.Content))
+		}
+	}
+	return b.String()
+}
+
+// HTML returns the diff in unified form as an HTML fragment: a pre
+// element of class "diff" containing a span element for each line, of
+// class "file", "hunk", "ctx", "del" or "ins". Changed parts of deleted and
+// inserted lines are enclosed in del and ins elements.
+func (d *HighlightedDiff) HTML() string {
+	if len(d.Hunks) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(`<pre class="diff">`)
+	fmt.Fprintf(&b, "<span class=\"file\">--- %s</span>\n", html.EscapeString(d.From))
+	fmt.Fprintf(&b, "<span class=\"file\">+++ %s</span>\n", html.EscapeString(d.To))
+	for _, h := range d.Hunks {
+		fmt.Fprintf(&b, "<span class=\"hunk\">%s</span>\n", h.header())
+		for _, l := range h.Lines {
+			class, open, close := "ctx", "", ""
+			switch l.Op {
+			case '-':
+				class, open, close = "del", "<del>", "</del>"
+			case '+':
+				class, open, close = "ins", "<ins>", "</ins>"
+			}
+			fmt.Fprintf(&b, `<span class="%s">%c`, class, l.Op)
+			writeSpans(&b, l, open, close, html.EscapeString)
+			b.WriteString("</span>")
+			b.WriteString(html.EscapeString(lineEnd(l.Content)))
+		}
+	}
+	b.WriteString("</pre>\n")
+	return b.String()
+}
+
+// writeSpans writes the content of a line, without its newline, with the
+// changed spans enclosed by open and close and all text passed through
+// escape. Lines that changed as a whole are not marked, as the emphasis
+// would add nothing.
+func writeSpans(b *strings.Builder, l HighlightedLine, open, close string, escape func(string) string) {
+	text := strings.TrimSuffix(l.Content, "\n")
+	spans := l.Changed
+	if len(spans) == 1 && spans[0] == (Span{0, len(text)}) {
+		spans = nil
+	}
+	last := 0
+	for _, s := range spans {
+		b.WriteString(escape(text[last:s.Start]))
+		b.WriteString(open)
+		b.WriteString(escape(text[s.Start:s.End]))
+		b.WriteString(close)
+		last = s.End
+	}
+	b.WriteString(escape(text[last:]))
+}
+
+// lineEnd returns the text that ends the display of a line: its newline,
+// or a marker if it has none.
+func lineEnd(content string) string {
+	if strings.HasSuffix(content, "\n") {
+		return "\n"
+	}
+	return "\n\\ No newline at end of file\n"
+}
@@ -0,0 +1,162 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diff_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/tenntenn/exp/toolsinternal/diff"
+	"github.com/tenntenn/exp/toolsinternal/diff/difftest"
+)
+
+// bracket returns the content of a line with its changed spans in brackets.
+func bracket(l diff.HighlightedLine) string {
+	var b strings.Builder
+	last := 0
+	for _, s := range l.Changed {
+		fmt.Fprintf(&b, "%s[%s]", l.Content[last:s.Start], l.Content[s.Start:s.End])
+		last = s.End
+	}
+	b.WriteString(strings.TrimSuffix(l.Content[last:], "\n"))
+	return string(l.Op) + b.String()
+}
+
+func TestHighlight(t *testing.T) {
+	const before = `package p
+
+func f(x int) int {
+	return x + 1
+}
+
+var s = "héllo"
+var t = 1
+`
+	const after = `package p
+
+func f(x, y int) int {
+	return x * y
+}
+
+var s = "hello, wörld"
+// unrelated
+`
+	d, err := diff.HighlightUnified("a", "b", before, diff.Strings(before, after), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, h := range d.Hunks {
+		got = append(got, fmt.Sprintf("@@ -%d,%d +%d,%d", h.OldStart, h.OldLines, h.NewStart, h.NewLines))
+		for _, l := range h.Lines {
+			got = append(got, bracket(l))
+		}
+	}
+	want := []string{
+		"@@ -2,7 +2,7",
+		" ",
+		"-func f(x int) int {",
+		"+func f(x[, y] int) int {",
+		"-	return x [+] [1]",
+		"+	return x [*] [y]",
+		" }",
+		" ",
+		`-var s = "h[é]llo"`,
+		`+var s = "h[e]llo[, wörld]"`,
+		"-[var t = 1]",
+		"+[// unrelated]",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// Without its colors, the diff is the same as that of ToUnified.
+	want2, _ := diff.ToUnified("a", "b", before, diff.Strings(before, after), 1)
+	if got := ansiStrip(d.ANSI()); got != want2 {
+		t.Errorf("ANSI():\n%s\nwant:\n%s", got, want2)
+	}
+}
+
+// ansiStrip removes ANSI escape sequences from s.
+func ansiStrip(s string) string {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "\x1b[")
+		if i < 0 {
+			break
+		}
+		b.WriteString(s[:i])
+		s = s[i+2:]
+		s = s[strings.IndexByte(s, 'm')+1:]
+	}
+	b.WriteString(s)
+	return b.String()
+}
+
+func TestHighlightRender(t *testing.T) {
+	// A pure insertion within a line highlights only the inserted line.
+	const before = "a := x < y\nold\n"
+	const after = "a := x <= y\nold\nnew"
+	d, err := diff.HighlightUnified("a.go", "b.go", before, diff.Strings(before, after), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantANSI := "\x1b[1m--- a.go\x1b[m\n" +
+		"\x1b[1m+++ b.go\x1b[m\n" +
+		"\x1b[36m@@ -1 +1 @@\x1b[m\n" +
+		"\x1b[31m-a := x < y\x1b[m\n" +
+		"\x1b[32m+a := x <\x1b[7m=\x1b[27m y\x1b[m\n" +
+		"\x1b[36m@@ -2,0 +3 @@\x1b[m\n" +
+		"\x1b[32m+new\x1b[m\n\\ No newline at end of file\n"
+	if got := d.ANSI(); got != wantANSI {
+		t.Errorf("ANSI():\n%q\nwant:\n%q", got, wantANSI)
+	}
+	wantHTML := `<pre class="diff"><span class="file">--- a.go</span>
+<span class="file">+++ b.go</span>
+<span class="hunk">@@ -1 +1 @@</span>
+<span class="del">-a := x &lt; y</span>
+<span class="ins">+a := x &lt;<ins>=</ins> y</span>
+<span class="hunk">@@ -2,0 +3 @@</span>
+<span class="ins">+new</span>
+\ No newline at end of file
+</pre>
+`
+	if got := d.HTML(); got != wantHTML {
+		t.Errorf("HTML():\n%s\nwant:\n%s", got, wantHTML)
+	}
+}
+
+func TestHighlightDifftest(t *testing.T) {
+	// Highlighting a parsed patch preserves its lines, and spans are
+	// well-formed.
+	for _, tc := range difftest.TestCases {
+		u, err := diff.ToUnified(difftest.FileA, difftest.FileB, tc.In, tc.Edits, diff.DefaultContextLines)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files, err := diff.ParseUnified(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range files {
+			d := diff.Highlight(f)
+			for i, h := range d.Hunks {
+				for j, l := range h.Lines {
+					if l.Content != f.Hunks[i].Lines[j].Content || l.Op != f.Hunks[i].Lines[j].Op {
+						t.Errorf("%s: line %d of hunk %d changed", tc.Name, j, i)
+					}
+					last := 0
+					for _, s := range l.Changed {
+						if s.Start < last || s.End < s.Start || s.End > len(l.Content) {
+							t.Errorf("%s: bad spans %v for %q", tc.Name, l.Changed, l.Content)
+						}
+						last = s.End
+					}
+				}
+			}
+		}
+	}
+}
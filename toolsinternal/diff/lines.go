@@ -0,0 +1,455 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diff
+
+import (
+	"strings"
+
+	"github.com/tenntenn/exp/toolsinternal/diff/lcs"
+)
+
+// An Algorithm is a method of computing line differences.
+type Algorithm int
+
+const (
+	// Myers uses the lcs package, which finds a near-minimal diff using
+	// Myers' algorithm with a limit on the search effort.
+	Myers Algorithm = iota
+
+	// Patience anchors the diff on lines that occur exactly once in both
+	// texts, which tends to align hunks with code structure such as
+	// function declarations rather than with braces and blank lines.
+	Patience
+
+	// Histogram is git's refinement of Patience that anchors the diff on
+	// the least frequent common lines, so that it also works well when few
+	// lines are unique.
+	Histogram
+)
+
+func (a Algorithm) String() string {
+	switch a {
+	case Myers:
+		return "myers"
+	case Patience:
+		return "patience"
+	case Histogram:
+		return "histogram"
+	}
+	return "unknown"
+}
+
+// Options configures Lines.
+type Options struct {
+	Algorithm Algorithm
+
+	// IndentHeuristic slides each hunk that inserts or deletes lines, if its
+	// position is ambiguous, to where it best matches the indentation and
+	// blank lines of the surrounding text, as "git diff --indent-heuristic"
+	// does.
+	IndentHeuristic bool
+}
+
+// Lines computes the line differences between two strings, using the
+// algorithm chosen by opts. Each resulting edit replaces whole lines.
+// A nil opts is equivalent to a pointer to a zero Options.
+//
+// Unlike Strings, which finds the differences within lines, Lines is
+// suited to producing diffs that are read by people, for example with
+// ToUnified.
+func Lines(before, after string, opts *Options) []Edit {
+	if before == after {
+		return nil
+	}
+	if opts == nil {
+		opts = new(Options)
+	}
+	a, b := splitLines(before), splitLines(after)
+	return tokenEdits(a, b, lineDiffs(a, b, opts))
+}
+
+// lineDiffs computes the differences between two sequences of lines.
+func lineDiffs(a, b []string, opts *Options) []lcs.Diff {
+	x, y := tokenIDs(a, b)
+	var diffs []lcs.Diff
+	switch opts.Algorithm {
+	case Patience:
+		diffs = matchesToDiffs(patience(x, y, 0, 0, nil), len(x), len(y))
+	case Histogram:
+		diffs = matchesToDiffs(histogram(x, y, 0, 0, nil), len(x), len(y))
+	default:
+		diffs = myers(x, y)
+	}
+	if opts.IndentHeuristic {
+		slideHunks(diffs, a, b, x, y, splitScore)
+	}
+	return diffs
+}
+
+// tokenDiff returns the edits that transform the concatenation of the
+// before tokens into that of the after tokens, replacing whole tokens.
+func tokenDiff(before, after []string) []Edit {
+	x, y := tokenIDs(before, after)
+	return tokenEdits(before, after, myers(x, y))
+}
+
+// tokenEdits converts differences between two sequences of tokens into
+// edits of the concatenation of the a tokens.
+func tokenEdits(a, b []string, diffs []lcs.Diff) []Edit {
+	res := make([]Edit, len(diffs))
+	offset, lastEnd := 0, 0
+	for i, d := range diffs {
+		offset += tokensLen(a[lastEnd:d.Start])
+		start := offset
+		offset += tokensLen(a[d.Start:d.End])
+		res[i] = Edit{start, offset, strings.Join(b[d.ReplStart:d.ReplEnd], "")}
+		lastEnd = d.End
+	}
+	return res
+}
+
+func tokensLen(tokens []string) (n int) {
+	for _, tok := range tokens {
+		n += len(tok)
+	}
+	return n
+}
+
+// tokenIDs maps each distinct token of a and b to a small integer.
+func tokenIDs(a, b []string) (x, y []int) {
+	ids := make(map[string]int)
+	encode := func(tokens []string) []int {
+		res := make([]int, len(tokens))
+		for i, tok := range tokens {
+			id, ok := ids[tok]
+			if !ok {
+				id = len(ids)
+				ids[tok] = id
+			}
+			res[i] = id
+		}
+		return res
+	}
+	return encode(a), encode(b)
+}
+
+// myers computes the differences between two sequences of token IDs using
+// the lcs package.
+func myers(x, y []int) []lcs.Diff {
+	toRunes := func(ids []int) []rune {
+		runes := make([]rune, len(ids))
+		for i, id := range ids {
+			runes[i] = rune(id)
+		}
+		return runes
+	}
+	return lcs.DiffRunes(toRunes(x), toRunes(y))
+}
+
+// A match records that line X of one text is paired with line Y of the
+// other.
+type match struct{ X, Y int }
+
+// matchesToDiffs converts an ordered list of matched lines into the
+// differences between sequences of lengths nx and ny.
+func matchesToDiffs(matches []match, nx, ny int) []lcs.Diff {
+	var diffs []lcs.Diff
+	px, py := 0, 0 // ends of the previous match
+	for _, m := range append(matches, match{nx, ny}) {
+		if m.X > px || m.Y > py {
+			diffs = append(diffs, lcs.Diff{Start: px, End: m.X, ReplStart: py, ReplEnd: m.Y})
+		}
+		px, py = m.X+1, m.Y+1
+	}
+	return diffs
+}
+
+// trimmed appends the matches of the common prefix and suffix of x and y,
+// which begin at offsets ox and oy of the whole sequences, and between them
+// those found by inner for the remainder, if neither side of it is empty.
+func trimmed(x, y []int, ox, oy int, matches []match, inner func(x, y []int, ox, oy int, matches []match) []match) []match {
+	pre := 0
+	for pre < len(x) && pre < len(y) && x[pre] == y[pre] {
+		matches = append(matches, match{ox + pre, oy + pre})
+		pre++
+	}
+	suf := 0
+	for suf < len(x)-pre && suf < len(y)-pre && x[len(x)-1-suf] == y[len(y)-1-suf] {
+		suf++
+	}
+	if pre < len(x)-suf && pre < len(y)-suf {
+		matches = inner(x[pre:len(x)-suf], y[pre:len(y)-suf], ox+pre, oy+pre, matches)
+	}
+	for i := suf; i > 0; i-- {
+		matches = append(matches, match{ox + len(x) - i, oy + len(y) - i})
+	}
+	return matches
+}
+
+// fallback appends the matches found by the myers algorithm.
+func fallback(x, y []int, ox, oy int, matches []match) []match {
+	px, py := 0, 0
+	for _, d := range myers(x, y) {
+		for ; px < d.Start; px, py = px+1, py+1 {
+			matches = append(matches, match{ox + px, oy + py})
+		}
+		px, py = d.End, d.ReplEnd
+	}
+	for ; px < len(x); px, py = px+1, py+1 {
+		matches = append(matches, match{ox + px, oy + py})
+	}
+	return matches
+}
+
+// patience appends the matches of the patience diff of x and y, which
+// begin at offsets ox and oy of the whole sequences.
+func patience(x, y []int, ox, oy int, matches []match) []match {
+	return trimmed(x, y, ox, oy, matches, func(x, y []int, ox, oy int, matches []match) []match {
+		// Find the lines that are unique in both sequences.
+		type occurrence struct{ nx, ny, iy int }
+		occ := make(map[int]*occurrence)
+		for _, id := range x {
+			o := occ[id]
+			if o == nil {
+				o = new(occurrence)
+				occ[id] = o
+			}
+			o.nx++
+		}
+		for i, id := range y {
+			if o := occ[id]; o != nil {
+				o.ny++
+				o.iy = i
+			}
+		}
+		var unique []match // in order of x
+		for i, id := range x {
+			if o := occ[id]; o.nx == 1 && o.ny == 1 {
+				unique = append(unique, match{i, o.iy})
+			}
+		}
+		if len(unique) == 0 {
+			return fallback(x, y, ox, oy, matches)
+		}
+
+		// Recursively diff the gaps between the longest increasing
+		// subsequence of unique matches.
+		px, py := 0, 0
+		for _, m := range longestIncreasing(unique) {
+			matches = patience(x[px:m.X], y[py:m.Y], ox+px, oy+py, matches)
+			matches = append(matches, match{ox + m.X, oy + m.Y})
+			px, py = m.X+1, m.Y+1
+		}
+		return patience(x[px:], y[py:], ox+px, oy+py, matches)
+	})
+}
+
+// longestIncreasing returns the longest subsequence of ms, which are
+// ordered by X, whose Y values are also increasing.
+// It uses patience sorting, from which the diff algorithm takes its name.
+func longestIncreasing(ms []match) []match {
+	var tops []int               // index in ms of the top card of each pile
+	prev := make([]int, len(ms)) // index in ms of the card below and to the left
+	for i, m := range ms {
+		// Find the leftmost pile whose top exceeds m.Y.
+		lo, hi := 0, len(tops)
+		for lo < hi {
+			mid := (lo + hi) / 2
+			if ms[tops[mid]].Y < m.Y {
+				lo = mid + 1
+			} else {
+				hi = mid
+			}
+		}
+		prev[i] = -1
+		if lo > 0 {
+			prev[i] = tops[lo-1]
+		}
+		if lo == len(tops) {
+			tops = append(tops, i)
+		} else {
+			tops[lo] = i
+		}
+	}
+	res := make([]match, len(tops))
+	for i, k := len(tops)-1, tops[len(tops)-1]; i >= 0; i, k = i-1, prev[k] {
+		res[i] = ms[k]
+	}
+	return res
+}
+
+// maxChain is the number of occurrences of a line beyond which histogram
+// considers it too common to anchor a diff, and falls back to myers.
+const maxChain = 64
+
+// histogram appends the matches of the histogram diff of x and y, which
+// begin at offsets ox and oy of the whole sequences.
+func histogram(x, y []int, ox, oy int, matches []match) []match {
+	return trimmed(x, y, ox, oy, matches, func(x, y []int, ox, oy int, matches []match) []match {
+		// Index the occurrences of each line of x.
+		where := make(map[int][]int)
+		for i, id := range x {
+			where[id] = append(where[id], i)
+		}
+
+		// Find the longest common region whose rarest line is rarest.
+		var bestX, bestY, bestN int
+		bestCount := maxChain + 1
+		tooCommon := false
+		for j := 0; j < len(y); {
+			occ := where[y[j]]
+			next := j + 1 // skip lines within regions already considered
+			if len(occ) > maxChain {
+				tooCommon = true
+				occ = nil
+			} else if len(occ) > bestCount {
+				occ = nil
+			}
+			for _, i := range occ {
+				s, t := i, j
+				for s > 0 && t > 0 && x[s-1] == y[t-1] {
+					s--
+					t--
+				}
+				n, count := 0, len(occ)
+				for s+n < len(x) && t+n < len(y) && x[s+n] == y[t+n] {
+					count = min(count, len(where[x[s+n]]))
+					n++
+				}
+				if count < bestCount || count == bestCount && n > bestN {
+					bestX, bestY, bestN, bestCount = s, t, n, count
+				}
+				next = max(next, t+n)
+			}
+			j = next
+		}
+		if bestN == 0 {
+			if tooCommon {
+				return fallback(x, y, ox, oy, matches)
+			}
+			return matches // no lines in common
+		}
+
+		matches = histogram(x[:bestX], y[:bestY], ox, oy, matches)
+		for k := range bestN {
+			matches = append(matches, match{ox + bestX + k, oy + bestY + k})
+		}
+		endX, endY := bestX+bestN, bestY+bestN
+		return histogram(x[endX:], y[endY:], ox+endX, oy+endY, matches)
+	})
+}
+
+// slideHunks moves each hunk that only inserts or only deletes tokens, and
+// could equally be placed higher or lower, to the position at which its
+// boundaries have the lowest badness according to score, such as
+// splitScore for the indentation heuristic. a and b are the tokens of the
+// texts, and x and y their IDs.
+func slideHunks(diffs []lcs.Diff, a, b []string, x, y []int, score func(tokens []string, pos int) int) {
+	for i := range diffs {
+		d := &diffs[i]
+		// The hunk may not slide into its neighbors.
+		loX, loY := 0, 0
+		if i > 0 {
+			loX, loY = diffs[i-1].End, diffs[i-1].ReplEnd
+		}
+		hiX, hiY := len(x), len(y)
+		if i+1 < len(diffs) {
+			hiX, hiY = diffs[i+1].Start, diffs[i+1].ReplStart
+		}
+
+		// lines and ids are those of the side that has the hunk's tokens,
+		// and start, end, lo and hi the positions in that side.
+		var (
+			lines      []string
+			ids        []int
+			start, end int
+			lo, hi     int
+			otherLo    int
+			otherStart int
+			otherHi    int
+		)
+		switch {
+		case d.Start == d.End: // insertion
+			lines, ids, start, end = b, y, d.ReplStart, d.ReplEnd
+			lo, hi, otherStart, otherLo, otherHi = loY, hiY, d.Start, loX, hiX
+		case d.ReplStart == d.ReplEnd: // deletion
+			lines, ids, start, end = a, x, d.Start, d.End
+			lo, hi, otherStart, otherLo, otherHi = loX, hiX, d.ReplStart, loY, hiY
+		default:
+			continue
+		}
+
+		// Find the range of shifts that leave the diff valid.
+		up := 0
+		for start-up > lo && otherStart-up > otherLo && ids[start-up-1] == ids[end-up-1] {
+			up++
+		}
+		down := 0
+		for end+down < hi && otherStart+down < otherHi && ids[start+down] == ids[end+down] {
+			down++
+		}
+		if up+down == 0 {
+			continue
+		}
+
+		// Choose the best shift, preferring lower positions on ties.
+		bestShift, bestScore := 0, 0
+		for shift := -up; shift <= down; shift++ {
+			badness := score(lines, start+shift) + score(lines, end+shift)
+			if shift == -up || badness <= bestScore {
+				bestShift, bestScore = shift, badness
+			}
+		}
+		d.Start += bestShift
+		d.End += bestShift
+		d.ReplStart += bestShift
+		d.ReplEnd += bestShift
+	}
+}
+
+// splitScore returns the badness of a hunk boundary just before lines[pos].
+// Boundaries next to blank lines, and those before less indented lines, are
+// preferred.
+func splitScore(lines []string, pos int) int {
+	if pos <= 0 || pos >= len(lines) {
+		return -100 // start or end of file
+	}
+	score := 0
+	if isBlank(lines[pos-1]) {
+		score -= 20
+	}
+	if isBlank(lines[pos]) {
+		score -= 20
+	}
+	// The indentation of the next non-blank line.
+	for _, l := range lines[pos:] {
+		if !isBlank(l) {
+			score += indentation(l)
+			break
+		}
+	}
+	return score
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+// indentation returns the width of the leading space of a line, counting
+// tabs as 8 columns.
+func indentation(line string) int {
+	n := 0
+	for _, c := range line {
+		switch c {
+		case ' ':
+			n++
+		case '\t':
+			n += 8 - n%8
+		default:
+			return n
+		}
+	}
+	return n
+}
@@ -0,0 +1,235 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diff_test
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/tenntenn/exp/toolsinternal/diff"
+	"github.com/tenntenn/exp/toolsinternal/diff/difftest"
+)
+
+var algorithms = []diff.Algorithm{diff.Myers, diff.Patience, diff.Histogram}
+
+// checkLines checks that edits transform before into after and replace
+// whole lines.
+func checkLines(t *testing.T, before, after string, edits []diff.Edit) {
+	t.Helper()
+	got, err := diff.Apply(before, edits)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if got != after {
+		t.Fatalf("Apply(%q, %v) = %q, want %q", before, edits, got, after)
+	}
+	for _, e := range edits {
+		if e.Start > 0 && before[e.Start-1] != '\n' ||
+			e.End > 0 && e.End < len(before) && before[e.End-1] != '\n' {
+			t.Fatalf("edit %v of %q is not line-aligned", e, before)
+		}
+	}
+}
+
+func TestLines(t *testing.T) {
+	for _, algo := range algorithms {
+		for _, heuristic := range []bool{false, true} {
+			opts := &diff.Options{Algorithm: algo, IndentHeuristic: heuristic}
+			t.Run(fmt.Sprintf("%v/%t", algo, heuristic), func(t *testing.T) {
+				for _, tc := range difftest.TestCases {
+					checkLines(t, tc.In, tc.Out, diff.Lines(tc.In, tc.Out, opts))
+				}
+				rng := rand.New(rand.NewSource(1))
+				for range 1000 {
+					a := randLines(rng, "ab{}\n \t", 40)
+					b := randLines(rng, "abc{}\n \t", 40)
+					checkLines(t, a, b, diff.Lines(a, b, opts))
+				}
+			})
+		}
+	}
+}
+
+// randLines returns a random string of n characters from s, which
+// should include newlines.
+func randLines(rng *rand.Rand, s string, n int) string {
+	src := []rune(s)
+	x := make([]rune, n)
+	for i := range n {
+		x[i] = src[rng.Intn(len(src))]
+	}
+	return string(x)
+}
+
+func TestLinesQuality(t *testing.T) {
+	// Swapping two functions. Myers finds a minimal diff that deletes
+	// and inserts "func b", and patience and histogram do the same for
+	// "func a"; with the indent heuristic, the latter two show whole
+	// functions and the blank line that separates them.
+	const before = `func a() {
+	x := 1
+	return x
+}
+
+func b() {
+	y := 2
+	return y
+}
+`
+	const after = `func b() {
+	y := 2
+	return y
+}
+
+func a() {
+	x := 1
+	return x
+}
+`
+	for _, test := range []struct {
+		algo diff.Algorithm
+		want string
+	}{
+		{diff.Patience, `
+@@ -1,5 +0,0 @@
+-func a() {
+-	x := 1
+-	return x
+-}
+-
+@@ -10 +5,5 @@
++
++func a() {
++	x := 1
++	return x
++}
+`},
+		{diff.Histogram, `
+@@ -1,5 +0,0 @@
+-func a() {
+-	x := 1
+-	return x
+-}
+-
+@@ -10 +5,5 @@
++
++func a() {
++	x := 1
++	return x
++}
+`},
+	} {
+		edits := diff.Lines(before, after, &diff.Options{Algorithm: test.algo, IndentHeuristic: true})
+		checkLines(t, before, after, edits)
+		got, err := diff.ToUnified("a", "b", before, edits, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "--- a\n+++ b" + test.want; got != want {
+			t.Errorf("%v: got\n%s\nwant\n%s", test.algo, got, want)
+		}
+	}
+}
+
+func TestIndentHeuristic(t *testing.T) {
+	// Adding a function after another: without the heuristic, the
+	// inserted lines begin with the closing brace of the existing function.
+	const before = `func a() {
+	if x {
+		f()
+	}
+}
+`
+	const after = `func a() {
+	if x {
+		f()
+	}
+}
+
+func b() {
+	if x {
+		f()
+	}
+}
+`
+	for _, algo := range algorithms {
+		edits := diff.Lines(before, after, &diff.Options{Algorithm: algo, IndentHeuristic: true})
+		checkLines(t, before, after, edits)
+		if len(edits) != 1 || edits[0].Start != len(before) || edits[0].End != len(before) {
+			t.Errorf("%v: got %v, want insertion at end", algo, edits)
+		}
+	}
+
+	// Deleting a block from the middle.
+	const nested = "{\n\ta\n\t{\n\t\tb\n\t}\n\t{\n\t\tb\n\t}\n}\n"
+	const deleted = "{\n\ta\n\t{\n\t\tb\n\t}\n}\n"
+	for _, algo := range algorithms {
+		edits := diff.Lines(nested, deleted, &diff.Options{Algorithm: algo, IndentHeuristic: true})
+		checkLines(t, nested, deleted, edits)
+		want := []diff.Edit{{Start: strings.Index(nested, "}\n\t{") + 2, End: strings.LastIndex(nested, "}"), New: ""}}
+		if fmt.Sprint(edits) != fmt.Sprint(want) {
+			t.Errorf("%v: got %v, want %v", algo, edits, want)
+		}
+	}
+}
+
+func BenchmarkLines(b *testing.B) {
+	// A synthetic source file, and an edited version of it
+	// with functions reordered, lines changed and blocks removed.
+	var before, after strings.Builder
+	var moved []string
+	rng := rand.New(rand.NewSource(1))
+	for i := range 500 {
+		fn := fmt.Sprintf("func f%d() {\n\tif x {\n\t\treturn %d\n\t}\n\treturn 0\n}\n\n", i, i)
+		before.WriteString(fn)
+		switch rng.Intn(10) {
+		case 0:
+			// deleted
+		case 1:
+			after.WriteString(strings.Replace(fn, "return 0", "return 1", 1))
+		case 2:
+			moved = append(moved, fn)
+		default:
+			after.WriteString(fn)
+		}
+	}
+	for _, fn := range moved {
+		after.WriteString(fn)
+	}
+	benchmarkLines(b, [][2]string{{before.String(), after.String()}})
+}
+
+func BenchmarkLinesDifftest(b *testing.B) {
+	var pairs [][2]string
+	for _, tc := range difftest.TestCases {
+		pairs = append(pairs, [2]string{tc.In, tc.Out})
+	}
+	benchmarkLines(b, pairs)
+}
+
+// benchmarkLines compares the algorithms on pairs of texts. Besides the
+// speed, it reports the number of hunks and changed lines, as measures of
+// the quality of the diffs.
+func benchmarkLines(b *testing.B, pairs [][2]string) {
+	for _, algo := range algorithms {
+		b.Run(algo.String(), func(b *testing.B) {
+			var hunks, changed int
+			for b.Loop() {
+				hunks, changed = 0, 0
+				for _, pair := range pairs {
+					edits := diff.Lines(pair[0], pair[1], &diff.Options{Algorithm: algo})
+					hunks += len(edits)
+					for _, e := range edits {
+						changed += strings.Count(pair[0][e.Start:e.End], "\n") + strings.Count(e.New, "\n")
+					}
+				}
+			}
+			b.ReportMetric(float64(hunks), "hunks")
+			b.ReportMetric(float64(changed), "lines")
+		})
+	}
+}
@@ -0,0 +1,272 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diff
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A ConflictStyle determines how Merge3 marks conflicts in its output.
+type ConflictStyle int
+
+const (
+	// StyleMerge shows both versions of a conflict, as git does by default:
+	//
+	//	<<<<<<< ours
+	//	...
+	//	=======
+	//	...
+	//	>>>>>>> theirs
+	StyleMerge ConflictStyle = iota
+
+	// StyleDiff3 also shows the base version, after a "|||||||" marker.
+	StyleDiff3
+
+	// StyleZDiff3 is like StyleDiff3, but lines at the start and end of a
+	// conflict that are the same in both versions are moved out of it.
+	StyleZDiff3
+)
+
+// A Resolution determines how hard Merge3 tries to resolve conflicts.
+type Resolution int
+
+const (
+	// ResolveNone leaves all conflicting hunks as conflicts.
+	ResolveNone Resolution = iota
+
+	// ResolveLines merges conflicting hunks line by line, accepting
+	// changes to adjacent lines that do not overlap.
+	ResolveLines
+
+	// ResolveWords merges conflicting hunks word by word, accepting
+	// changes within the same line that do not overlap.
+	ResolveWords
+)
+
+// Merge3Options holds the optional parameters of Merge3.
+type Merge3Options struct {
+	Style   ConflictStyle
+	Resolve Resolution
+
+	// The labels that follow the conflict markers.
+	// If empty, "ours", "base" and "theirs" are used.
+	OursLabel, BaseLabel, TheirsLabel string
+}
+
+// A Merge3Result is the result of a three-way merge.
+type Merge3Result struct {
+	Text      string     // merged text, including conflict markers
+	Conflicts []Conflict // conflicts, in order
+}
+
+// A Conflict is a region of the base text that was changed differently in
+// each version.
+type Conflict struct {
+	Base, Ours, Theirs string // conflicting versions of the region
+
+	// Start and End are the byte offsets of the region in the merged text,
+	// from the start of the "<<<<<<<" line to the end of the ">>>>>>>" line.
+	Start, End int
+}
+
+// Merge3 merges the changes made to base by ours and by theirs.
+//
+// The texts are compared line by line. Changes that touch the same or
+// adjacent lines of base conflict, unless they are identical. Conflicts are
+// first resolved at finer granularity, if opts.Resolve permits, and any that
+// remain are reported and marked in the merged text in the chosen style.
+// A nil opts is equivalent to a pointer to a zero Merge3Options.
+func Merge3(base, ours, theirs string, opts *Merge3Options) *Merge3Result {
+	if opts == nil {
+		opts = new(Merge3Options)
+	}
+	type sideEdit struct {
+		Edit
+		theirs bool
+	}
+	var edits []sideEdit
+	for _, e := range Lines(base, ours, nil) {
+		edits = append(edits, sideEdit{e, false})
+	}
+	for _, e := range Lines(base, theirs, nil) {
+		edits = append(edits, sideEdit{e, true})
+	}
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].Start < edits[j].Start })
+
+	m := &merger{opts: opts}
+	last := 0 // end of the part of base already merged
+	for i := 0; i < len(edits); {
+		// Gather a cluster of edits that overlap or touch.
+		start, end := edits[i].Start, edits[i].End
+		var oursEdits, theirsEdits []Edit
+		for ; i < len(edits) && edits[i].Start <= end; i++ {
+			end = max(end, edits[i].End)
+			if edits[i].theirs {
+				theirsEdits = append(theirsEdits, edits[i].Edit)
+			} else {
+				oursEdits = append(oursEdits, edits[i].Edit)
+			}
+		}
+		m.out.WriteString(base[last:start])
+		last = end
+
+		region := base[start:end]
+		oursText := applyWithin(base, start, end, oursEdits)
+		theirsText := applyWithin(base, start, end, theirsEdits)
+		switch {
+		case theirsEdits == nil:
+			m.out.WriteString(oursText)
+		case oursEdits == nil, oursText == theirsText:
+			m.out.WriteString(theirsText)
+		default:
+			if merged, ok := resolve(region, oursText, theirsText, opts.Resolve); ok {
+				m.out.WriteString(merged)
+			} else {
+				m.conflict(region, oursText, theirsText)
+			}
+		}
+	}
+	m.out.WriteString(base[last:])
+	return &Merge3Result{Text: m.out.String(), Conflicts: m.conflicts}
+}
+
+// merger holds the output of Merge3.
+type merger struct {
+	opts      *Merge3Options
+	out       strings.Builder
+	conflicts []Conflict
+}
+
+// conflict writes a marked conflict to the output.
+func (m *merger) conflict(base, ours, theirs string) {
+	if m.opts.Style == StyleZDiff3 {
+		// Move common leading and trailing lines out of the conflict.
+		o, t := splitLines(ours), splitLines(theirs)
+		pre := 0
+		for pre < len(o) && pre < len(t) && o[pre] == t[pre] && strings.HasSuffix(o[pre], "\n") {
+			pre++
+		}
+		suf := 0
+		for suf < len(o)-pre && suf < len(t)-pre && o[len(o)-1-suf] == t[len(t)-1-suf] {
+			suf++
+		}
+		prefix := strings.Join(o[:pre], "")
+		suffix := strings.Join(o[len(o)-suf:], "")
+		m.out.WriteString(prefix)
+		ours = strings.Join(o[pre:len(o)-suf], "")
+		theirs = strings.Join(t[pre:len(t)-suf], "")
+		defer m.out.WriteString(suffix)
+	}
+
+	label := func(s, def string) string {
+		if s == "" {
+			return def
+		}
+		return s
+	}
+	section := func(marker, label, text string) {
+		m.out.WriteString(marker + " " + label + "\n")
+		m.out.WriteString(text)
+		if text != "" && !strings.HasSuffix(text, "\n") {
+			m.out.WriteByte('\n')
+		}
+	}
+	c := Conflict{Base: base, Ours: ours, Theirs: theirs, Start: m.out.Len()}
+	section("<<<<<<<", label(m.opts.OursLabel, "ours"), ours)
+	if m.opts.Style != StyleMerge {
+		section("|||||||", label(m.opts.BaseLabel, "base"), base)
+	}
+	m.out.WriteString("=======\n")
+	m.out.WriteString(theirs)
+	if theirs != "" && !strings.HasSuffix(theirs, "\n") {
+		m.out.WriteByte('\n')
+	}
+	m.out.WriteString(">>>>>>> " + label(m.opts.TheirsLabel, "theirs") + "\n")
+	c.End = m.out.Len()
+	m.conflicts = append(m.conflicts, c)
+}
+
+// applyWithin returns the text of base[start:end] after applying edits,
+// which lie within that region.
+func applyWithin(base string, start, end int, edits []Edit) string {
+	var b strings.Builder
+	last := start
+	for _, e := range edits {
+		b.WriteString(base[last:e.Start])
+		b.WriteString(e.New)
+		last = e.End
+	}
+	b.WriteString(base[last:end])
+	return b.String()
+}
+
+// resolve attempts to merge a conflicting region at the granularity chosen
+// by res, accepting only changes that neither overlap nor insert different
+// text at the same point.
+func resolve(base, ours, theirs string, res Resolution) (string, bool) {
+	var split func(string) []string
+	switch res {
+	case ResolveLines:
+		split = splitLines
+	case ResolveWords:
+		split = splitWords
+	default:
+		return "", false
+	}
+	x := tokenDiff(split(base), split(ours))
+	y := tokenDiff(split(base), split(theirs))
+	for _, ex := range x {
+		for _, ey := range y {
+			if ex == ey {
+				continue // identical changes are coalesced
+			}
+			if ex.Start < ey.End && ey.Start < ex.End || // overlapping
+				ex.Start == ey.Start && (ex.Start == ex.End || ey.Start == ey.End) { // colocated insertion
+				return "", false
+			}
+		}
+	}
+	merged, ok := Merge(x, y)
+	if !ok {
+		return "", false
+	}
+	out, err := Apply(base, merged)
+	return out, err == nil
+}
+
+// splitWords splits text into words, runs of spaces, and single other
+// characters. Newlines are tokens of their own.
+func splitWords(text string) []string {
+	var words []string
+	class := func(r rune) int {
+		switch {
+		case r == '\n':
+			return 0
+		case unicode.IsSpace(r):
+			return 1
+		case r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+			return 2
+		}
+		return 3
+	}
+	for len(text) > 0 {
+		r, size := utf8.DecodeRuneInString(text)
+		if c := class(r); c == 1 || c == 2 {
+			for size < len(text) {
+				r, n := utf8.DecodeRuneInString(text[size:])
+				if class(r) != c {
+					break
+				}
+				size += n
+			}
+		}
+		words = append(words, text[:size])
+		text = text[size:]
+	}
+	return words
+}
@@ -0,0 +1,173 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diff_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tenntenn/exp/toolsinternal/diff"
+)
+
+func TestMerge3(t *testing.T) {
+	// Each text is a list of lines, written on one line with "|" for "\n".
+	lines := func(s string) string { return strings.ReplaceAll(s, "|", "\n") }
+	for _, test := range []struct {
+		name               string
+		base, ours, theirs string
+		opts               diff.Merge3Options
+		want               string
+		conflicts          int
+	}{
+		{
+			name:   "independent",
+			base:   "a|b|c|d|e|",
+			ours:   "A|b|c|d|e|",
+			theirs: "a|b|c|d|E|",
+			want:   "A|b|c|d|E|",
+		},
+		{
+			name:   "identical",
+			base:   "a|b|c|",
+			ours:   "a|B|c|",
+			theirs: "a|B|c|",
+			want:   "a|B|c|",
+		},
+		{
+			name:   "one side only",
+			base:   "a|b|c|",
+			ours:   "a|b|c|",
+			theirs: "x|a|c|",
+			want:   "x|a|c|",
+		},
+		{
+			name:      "conflict",
+			base:      "a|b|c|",
+			ours:      "a|B1|c|",
+			theirs:    "a|B2|c|",
+			want:      "a|<<<<<<< ours|B1|=======|B2|>>>>>>> theirs|c|",
+			conflicts: 1,
+		},
+		{
+			name:      "diff3",
+			base:      "a|b|c|",
+			ours:      "a|B1|c|",
+			theirs:    "a|B2|c|",
+			opts:      diff.Merge3Options{Style: diff.StyleDiff3, OursLabel: "HEAD", TheirsLabel: "fix"},
+			want:      "a|<<<<<<< HEAD|B1|||||||| base|b|=======|B2|>>>>>>> fix|c|",
+			conflicts: 1,
+		},
+		{
+			name:      "zdiff3",
+			base:      "a|b|c|",
+			ours:      "a|x|B1|y|c|",
+			theirs:    "a|x|B2|y|c|",
+			opts:      diff.Merge3Options{Style: diff.StyleZDiff3},
+			want:      "a|x|<<<<<<< ours|B1|||||||| base|b|=======|B2|>>>>>>> theirs|y|c|",
+			conflicts: 1,
+		},
+		{
+			name:      "adjacent lines conflict",
+			base:      "a|b|c|",
+			ours:      "A|b|c|",
+			theirs:    "a|B|c|",
+			want:      "<<<<<<< ours|A|b|=======|a|B|>>>>>>> theirs|c|",
+			conflicts: 1,
+		},
+		{
+			name:   "adjacent lines resolved",
+			base:   "a|b|c|",
+			ours:   "A|b|c|",
+			theirs: "a|B|c|",
+			opts:   diff.Merge3Options{Resolve: diff.ResolveLines},
+			want:   "A|B|c|",
+		},
+		{
+			name:      "same line, by lines",
+			base:      "f(a, b)|",
+			ours:      "f(x, b)|",
+			theirs:    "f(a, y)|",
+			opts:      diff.Merge3Options{Resolve: diff.ResolveLines},
+			want:      "<<<<<<< ours|f(x, b)|=======|f(a, y)|>>>>>>> theirs|",
+			conflicts: 1,
+		},
+		{
+			name:   "same line, by words",
+			base:   "f(a, b)|",
+			ours:   "f(x, b)|",
+			theirs: "f(a, y)|",
+			opts:   diff.Merge3Options{Resolve: diff.ResolveWords},
+			want:   "f(x, y)|",
+		},
+		{
+			name:      "same word",
+			base:      "f(a, b)|",
+			ours:      "f(x, b)|",
+			theirs:    "f(z, b)|",
+			opts:      diff.Merge3Options{Resolve: diff.ResolveWords},
+			want:      "<<<<<<< ours|f(x, b)|=======|f(z, b)|>>>>>>> theirs|",
+			conflicts: 1,
+		},
+		{
+			name:      "insertions at same point",
+			base:      "a|b|",
+			ours:      "a|x|b|",
+			theirs:    "a|y|b|",
+			opts:      diff.Merge3Options{Resolve: diff.ResolveWords},
+			want:      "a|<<<<<<< ours|x|=======|y|>>>>>>> theirs|b|",
+			conflicts: 1,
+		},
+		{
+			name:      "missing final newline",
+			base:      "a|b",
+			ours:      "a|c",
+			theirs:    "a|d",
+			want:      "a|<<<<<<< ours|c|=======|d|>>>>>>> theirs|",
+			conflicts: 1,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			res := diff.Merge3(lines(test.base), lines(test.ours), lines(test.theirs), &test.opts)
+			if got := strings.ReplaceAll(res.Text, "\n", "|"); got != test.want {
+				t.Errorf("got  %s\nwant %s", got, test.want)
+			}
+			if len(res.Conflicts) != test.conflicts {
+				t.Errorf("got %d conflicts, want %d", len(res.Conflicts), test.conflicts)
+			}
+			for _, c := range res.Conflicts {
+				region := res.Text[c.Start:c.End]
+				if !strings.HasPrefix(region, "<<<<<<< ") || !strings.HasSuffix(region, "\n") ||
+					!strings.HasPrefix(region[strings.LastIndex(region[:len(region)-1], "\n")+1:], ">>>>>>> ") {
+					t.Errorf("conflict region is not delimited by markers: %q", region)
+				}
+				if !strings.Contains(region, c.Ours) || !strings.Contains(region, c.Theirs) {
+					t.Errorf("conflict region %q does not contain %q and %q", region, c.Ours, c.Theirs)
+				}
+			}
+		})
+	}
+}
+
+func TestMerge3Clean(t *testing.T) {
+	// Without conflicts, the merge is the same as applying diff.Merge.
+	base := "package p\n\nfunc f() {\n\tg()\n}\n\nfunc h() {\n}\n"
+	ours := "package p\n\nfunc f() {\n\tg(1)\n}\n\nfunc h() {\n}\n"
+	theirs := "package p\n\nimport \"fmt\"\n\nfunc f() {\n\tg()\n}\n\nfunc h() {\n\tfmt.Println()\n}\n"
+	res := diff.Merge3(base, ours, theirs, nil)
+	if len(res.Conflicts) > 0 {
+		t.Fatalf("unexpected conflicts: %s", res.Text)
+	}
+	edits, ok := diff.Merge(diff.Strings(base, ours), diff.Strings(base, theirs))
+	if !ok {
+		t.Fatal("Merge failed")
+	}
+	want, err := diff.Apply(base, edits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Text != want {
+		t.Errorf("got:\n%s\nwant:\n%s", res.Text, want)
+	}
+}
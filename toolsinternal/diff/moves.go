@@ -0,0 +1,263 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A FilePair holds a file, the edits that transform it, and the labels
+// of the two versions, as passed to ToUnified.
+type FilePair struct {
+	OldLabel, NewLabel string
+	Content            string
+	Edits              []Edit
+}
+
+// MoveOptions holds the optional parameters of FindMoves.
+type MoveOptions struct {
+	// MinLines is the least number of lines in a moved block.
+	// If zero, 3 is used.
+	MinLines int
+
+	// Copies enables the detection of inserted blocks that match lines
+	// that were not deleted.
+	Copies bool
+
+	// IgnoreIndent compares lines without their leading and trailing
+	// space, so that blocks that moved into or out of a nested scope are
+	// found.
+	IgnoreIndent bool
+}
+
+// A MovedBlock is a block of inserted lines that matches a block of lines
+// of one of the original files.
+type MovedBlock struct {
+	// Copy reports whether the original lines remain. If not, the block
+	// moved, and the original lines are deleted.
+	Copy bool
+
+	FromFile, FromLine int // index of the pair, and 1-based line in its Content
+	ToFile, ToLine     int // index of the pair, and 1-based line in its result
+	Lines              int // number of lines
+}
+
+// A MoveResult is the result of FindMoves.
+type MoveResult struct {
+	Pairs  []FilePair
+	Blocks []MovedBlock // in order of ToFile and ToLine
+}
+
+// FindMoves finds the blocks of lines that moved, or if opts.Copies is
+// set were copied, within or between the given files. Each file's edits
+// are expanded to whole lines, and an inserted block matches a deleted
+// block (or for copies an unchanged one) if it has at least opts.MinLines
+// identical lines, the first of which is not blank. Longer blocks are
+// preferred, and each deleted line is considered to have moved only once.
+// A nil opts is equivalent to a pointer to a zero MoveOptions.
+//
+// It returns an error if the edits of a file are inconsistent.
+func FindMoves(pairs []FilePair, opts *MoveOptions) (*MoveResult, error) {
+	if opts == nil {
+		opts = new(MoveOptions)
+	}
+	minLines := opts.MinLines
+	if minLines <= 0 {
+		minLines = 3
+	}
+	key := func(line string) string {
+		line = strings.TrimSuffix(line, "\n")
+		if opts.IgnoreIndent {
+			line = strings.TrimSpace(line)
+		}
+		return line
+	}
+
+	// Find the deleted and inserted lines of each file, and index
+	// the candidate original lines by their key.
+	type loc struct{ file, line int } // line is 0-based
+	var (
+		lines    = make([][]string, len(pairs)) // keys of the original lines
+		deleted  = make(map[loc]bool)
+		used     = make(map[loc]bool) // deleted lines that moved
+		inserted = make([][]string, len(pairs))
+		insLine  = make([][]int, len(pairs)) // 0-based line of each inserted line
+		index    = make(map[string][]loc)
+	)
+	for f, p := range pairs {
+		u, err := toUnified(p.OldLabel, p.NewLabel, p.Content, p.Edits, 0)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", p.OldLabel, err)
+		}
+		for _, l := range splitLines(p.Content) {
+			lines[f] = append(lines[f], key(l))
+		}
+		for _, h := range u.hunks {
+			old, new := h.fromLine-1, h.toLine-1
+			for _, l := range h.lines {
+				switch l.kind {
+				case opDelete:
+					deleted[loc{f, old}] = true
+					old++
+				case opInsert:
+					inserted[f] = append(inserted[f], key(l.content))
+					insLine[f] = append(insLine[f], new)
+					new++
+				}
+			}
+		}
+		for i, k := range lines[f] {
+			if k != "" && (opts.Copies || deleted[loc{f, i}]) {
+				index[k] = append(index[k], loc{f, i})
+			}
+		}
+	}
+
+	// Match blocks of inserted lines greedily.
+	res := &MoveResult{Pairs: pairs}
+	for f := range pairs {
+		ins, at := inserted[f], insLine[f]
+		for i := 0; i < len(ins); {
+			// length returns the number of lines from inserted line i
+			// that match the original lines from c, and whether they
+			// form a copy.
+			length := func(c loc) (int, bool) {
+				isCopy := !deleted[c]
+				n := 0
+				for i+n < len(ins) && at[i+n] == at[i]+n && c.line+n < len(lines[c.file]) {
+					o := loc{c.file, c.line + n}
+					// A block is either wholly moved or wholly copied.
+					if deleted[o] == isCopy || used[o] || lines[c.file][o.line] != ins[i+n] {
+						break
+					}
+					n++
+				}
+				return n, isCopy
+			}
+			var best loc
+			bestN, bestCopy := 0, false
+			for _, c := range index[ins[i]] {
+				n, isCopy := length(c)
+				// Prefer moves to copies of the same length.
+				if n > bestN || n == bestN && n > 0 && bestCopy && !isCopy {
+					best, bestN, bestCopy = c, n, isCopy
+				}
+			}
+			if bestN < minLines {
+				i++
+				continue
+			}
+			if !bestCopy {
+				for k := range bestN {
+					used[loc{best.file, best.line + k}] = true
+				}
+			}
+			res.Blocks = append(res.Blocks, MovedBlock{
+				Copy:     bestCopy,
+				FromFile: best.file,
+				FromLine: best.line + 1,
+				ToFile:   f,
+				ToLine:   at[i] + 1,
+				Lines:    bestN,
+			})
+			i += bestN
+		}
+	}
+	return res, nil
+}
+
+// ANSI escape sequences for moved lines, as used by "git diff
+// --color-moved".
+const (
+	ansiMovedOld    = "\x1b[1;35m"
+	ansiMovedOldAlt = "\x1b[1;34m"
+	ansiMovedNew    = "\x1b[1;36m"
+	ansiMovedNewAlt = "\x1b[1;33m"
+)
+
+// ANSI returns the unified diffs of the pairs, with contextLines lines of
+// context, colored with ANSI escape sequences in the style of "git diff
+// --color-moved=zebra": deleted and inserted lines of moved blocks are
+// shown in their own colors, which alternate between adjacent blocks.
+// The inserted lines of copied blocks are colored like those of moved
+// ones.
+func (r *MoveResult) ANSI(contextLines int) string {
+	type loc struct{ file, line int } // line is 1-based
+	from := make(map[loc]int)         // block index of each moved original line
+	to := make(map[loc]int)           // block index of each moved inserted line
+	for i, b := range r.Blocks {
+		for k := range b.Lines {
+			if !b.Copy {
+				from[loc{b.FromFile, b.FromLine + k}] = i
+			}
+			to[loc{b.ToFile, b.ToLine + k}] = i
+		}
+	}
+
+	var b strings.Builder
+	for f, p := range r.Pairs {
+		u, err := toUnified(p.OldLabel, p.NewLabel, p.Content, p.Edits, contextLines)
+		if err != nil || len(u.hunks) == 0 {
+			continue // errors were reported by FindMoves
+		}
+		fmt.Fprintf(&b, "%s--- %s%s\n", ansiBold, u.from, ansiReset)
+		fmt.Fprintf(&b, "%s+++ %s%s\n", ansiBold, u.to, ansiReset)
+		for _, h := range u.hunks {
+			fmt.Fprintf(&b, "%s%s%s\n", ansiCyan, h.header(), ansiReset)
+			old, new := h.fromLine, h.toLine
+			// The block and op of the previous line, if it was moved,
+			// and whether it used the alternate color.
+			prev, prevOp, alt := -1, byte(0), false
+			for _, l := range h.lines {
+				var (
+					op     byte
+					color  string
+					block  int
+					moved  bool
+					colors [2]string
+				)
+				switch l.kind {
+				case opDelete:
+					op, color = '-', ansiRed
+					block, moved = from[loc{f, old}]
+					colors = [2]string{ansiMovedOld, ansiMovedOldAlt}
+					old++
+				case opInsert:
+					op, color = '+', ansiGreen
+					block, moved = to[loc{f, new}]
+					colors = [2]string{ansiMovedNew, ansiMovedNewAlt}
+					new++
+				default:
+					op = ' '
+					old++
+					new++
+				}
+				if moved {
+					if prev < 0 || op != prevOp {
+						alt = false
+					} else if block != prev {
+						alt = !alt
+					}
+					prev, prevOp = block, op
+					color = colors[0]
+					if alt {
+						color = colors[1]
+					}
+				} else {
+					prev = -1
+				}
+				b.WriteString(color)
+				b.WriteByte(op)
+				b.WriteString(strings.TrimSuffix(l.content, "\n"))
+				if color != "" {
+					b.WriteString(ansiReset)
+				}
+				b.WriteString(lineEnd(l.content))
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,156 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diff_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/tenntenn/exp/toolsinternal/diff"
+)
+
+// pair returns the FilePair of two versions of a file, with the edits of
+// diff.Lines.
+func pair(name, before, after string) diff.FilePair {
+	return diff.FilePair{
+		OldLabel: "a/" + name,
+		NewLabel: "b/" + name,
+		Content:  before,
+		Edits:    diff.Lines(before, after, nil),
+	}
+}
+
+func TestFindMoves(t *testing.T) {
+	const a = `package a
+
+func F() {
+	x := 1
+	return x
+}
+
+func G() {
+	y := 2
+	y++
+	y++
+	return y
+}
+`
+	const g = "func G() {\n\ty := 2\n\ty++\n\ty++\n\treturn y\n}\n"
+	const f = "func F() {\n\tx := 1\n\treturn x\n}\n"
+	for _, test := range []struct {
+		name  string
+		pairs []diff.FilePair
+		opts  *diff.MoveOptions
+		want  []string
+	}{
+		{
+			name:  "within file",
+			pairs: []diff.FilePair{pair("a.go", a, "package a\n\n"+g+"\n"+f)},
+			want:  []string{"move 0:3 -> 0:10 (4 lines)"},
+		},
+		{
+			name: "between files",
+			pairs: []diff.FilePair{
+				pair("a.go", a, "package a\n\n"+g),
+				pair("b.go", "package a\n", "package a\n\n"+f),
+			},
+			want: []string{"move 0:3 -> 1:3 (4 lines)"},
+		},
+		{
+			name:  "short",
+			pairs: []diff.FilePair{pair("a.go", a, "package a\n\n"+g+"\n"+f)},
+			opts:  &diff.MoveOptions{MinLines: 5},
+			want:  nil,
+		},
+		{
+			name:  "copy",
+			pairs: []diff.FilePair{pair("a.go", a, a+"\nfunc F2() {\n\tx := 1\n\treturn x\n}\n")},
+			opts:  &diff.MoveOptions{Copies: true},
+			want:  []string{"copy 0:4 -> 0:16 (3 lines)"},
+		},
+		{
+			name:  "no copies",
+			pairs: []diff.FilePair{pair("a.go", a, a+"\nfunc F2() {\n\tx := 1\n\treturn x\n}\n")},
+			want:  nil,
+		},
+		{
+			name:  "reindented",
+			pairs: []diff.FilePair{pair("a.go", a, "package a\n\nfunc H() {\n\tfunc F() {\n\t\tx := 1\n\t\treturn x\n\t}\n}\n\n"+g)},
+			opts:  &diff.MoveOptions{IgnoreIndent: true},
+			want:  []string{"move 0:3 -> 0:4 (3 lines)"}, // the brace is matched by Lines
+		},
+		{
+			name:  "not reindented",
+			pairs: []diff.FilePair{pair("a.go", a, "package a\n\nfunc H() {\n\tfunc F() {\n\t\tx := 1\n\t\treturn x\n\t}\n}\n\n"+g)},
+			want:  nil,
+		},
+	} {
+		res, err := diff.FindMoves(test.pairs, test.opts)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		var got []string
+		for _, b := range res.Blocks {
+			kind := "move"
+			if b.Copy {
+				kind = "copy"
+			}
+			got = append(got, fmt.Sprintf("%s %d:%d -> %d:%d (%d lines)", kind, b.FromFile, b.FromLine, b.ToFile, b.ToLine, b.Lines))
+		}
+		if fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestFindMovesANSI(t *testing.T) {
+	// Two blocks swap places, and a line changes.
+	const before = "a1\na2\na3\nb1\nb2\nb3\nc\n"
+	const after = "b1\nb2\nb3\na1\na2\na3\nd\n"
+	res, err := diff.FindMoves([]diff.FilePair{{
+		OldLabel: "old",
+		NewLabel: "new",
+		Content:  before,
+		Edits:    []diff.Edit{{Start: 0, End: len(before), New: after}},
+	}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := res.ANSI(3)
+	// Expand the escape sequences for legibility.
+	got = strings.NewReplacer(
+		"\x1b[m", "</>",
+		"\x1b[1m", "<bold>",
+		"\x1b[36m", "<cyan>",
+		"\x1b[31m", "<red>",
+		"\x1b[32m", "<green>",
+		"\x1b[1;35m", "<old>",
+		"\x1b[1;34m", "<old-alt>",
+		"\x1b[1;36m", "<new>",
+		"\x1b[1;33m", "<new-alt>",
+	).Replace(got)
+	const want = `<bold>--- old</>
+<bold>+++ new</>
+<cyan>@@ -1,7 +1,7 @@</>
+<old>-a1</>
+<old>-a2</>
+<old>-a3</>
+<old-alt>-b1</>
+<old-alt>-b2</>
+<old-alt>-b3</>
+<red>-c</>
+<new>+b1</>
+<new>+b2</>
+<new>+b3</>
+<new-alt>+a1</>
+<new-alt>+a2</>
+<new-alt>+a3</>
+<green>+d</>
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
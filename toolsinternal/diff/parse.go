@@ -0,0 +1,305 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diff
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A FileDiff is the part of a unified diff that describes changes to a
+// single file.
+//
+// The names are as they appear in the patch, except that timestamps are
+// removed and quoted names are unquoted. In particular, names from git diffs
+// usually carry "a/" and "b/" prefixes, but those from "rename from" and
+// "rename to" lines do not. A name of "/dev/null" indicates that the file
+// is created or deleted.
+type FileDiff struct {
+	OldName, NewName string
+	OldMode, NewMode string // octal file modes from git extended headers, if any
+
+	IsNew     bool // the file is created
+	IsDeleted bool // the file is deleted
+	IsRename  bool // the file is renamed from OldName to NewName
+	IsCopy    bool // the file is copied from OldName to NewName
+	IsBinary  bool // the file is binary; its changes are not described
+
+	Hunks []*Hunk
+}
+
+// A Hunk is a contiguous group of changed lines, with surrounding context.
+type Hunk struct {
+	OldStart, OldLines int    // range of lines in the original file (1-based)
+	NewStart, NewLines int    // range of lines in the modified file (1-based)
+	Section            string // text following the range header, such as a function name
+	Lines              []HunkLine
+}
+
+// A HunkLine is a single line of a hunk.
+type HunkLine struct {
+	Op      byte   // ' ' for context, '-' for a deletion, '+' for an insertion
+	Content string // text of the line, including its newline if it has one
+}
+
+// Old returns the text of the hunk's lines in the original file.
+func (h *Hunk) Old() string { return h.text('-') }
+
+// New returns the text of the hunk's lines in the modified file.
+func (h *Hunk) New() string { return h.text('+') }
+
+func (h *Hunk) text(op byte) string {
+	var b strings.Builder
+	for _, l := range h.Lines {
+		if l.Op == ' ' || l.Op == op {
+			b.WriteString(l.Content)
+		}
+	}
+	return b.String()
+}
+
+// ParseUnified parses a patch in unified diff format, such as one produced
+// by ToUnified, "diff -u" or "git diff", and returns its files in order.
+//
+// Text before, between and after file diffs, such as the message of a mail
+// created by "git format-patch", is ignored. Git extended headers for
+// renames, copies, mode changes, creations and deletions are recognized, as
+// are "\ No newline at end of file" markers.
+//
+// ParseUnified returns an error if a hunk is malformed or truncated.
+func ParseUnified(patch string) ([]*FileDiff, error) {
+	if patch == "" {
+		return nil, nil
+	}
+	p := &patchParser{lines: splitLines(patch)}
+	return p.parse()
+}
+
+// patchParser holds the state of ParseUnified.
+type patchParser struct {
+	lines []string
+	i     int // index of next line
+}
+
+func (p *patchParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", p.i, fmt.Sprintf(format, args...))
+}
+
+func (p *patchParser) parse() ([]*FileDiff, error) {
+	var (
+		files    []*FileDiff
+		file     *FileDiff
+		inHeader bool // file was started by a "diff --git" line and has no hunks yet
+	)
+	for p.i < len(p.lines) {
+		text := strings.TrimSuffix(p.lines[p.i], "\n")
+		p.i++
+		switch {
+		case strings.HasPrefix(text, "diff --git "):
+			file = &FileDiff{}
+			files = append(files, file)
+			file.OldName, file.NewName = parseGitNames(text[len("diff --git "):])
+			inHeader = true
+
+		case inHeader && parseGitHeader(file, text):
+			// Binary diffs have no "---" and "+++" lines.
+			inHeader = !file.IsBinary
+
+		case strings.HasPrefix(text, "--- ") && p.i < len(p.lines) && strings.HasPrefix(p.lines[p.i], "+++ "):
+			if !inHeader {
+				file = &FileDiff{}
+				files = append(files, file)
+			}
+			inHeader = false
+			file.OldName = parseName(text[len("--- "):])
+			file.NewName = parseName(strings.TrimSuffix(p.lines[p.i], "\n")[len("+++ "):])
+			p.i++
+			if file.OldName == "/dev/null" {
+				file.IsNew = true
+			}
+			if file.NewName == "/dev/null" {
+				file.IsDeleted = true
+			}
+
+		case strings.HasPrefix(text, "@@ "):
+			if file == nil {
+				return nil, p.errorf("hunk before file header")
+			}
+			inHeader = false
+			h, err := p.parseHunk(text)
+			if err != nil {
+				return nil, err
+			}
+			file.Hunks = append(file.Hunks, h)
+		}
+	}
+	return files, nil
+}
+
+// parseGitHeader records the information of a git extended header line in
+// file, and reports whether text was such a line.
+func parseGitHeader(file *FileDiff, text string) bool {
+	cut := func(prefix string) (string, bool) { return strings.CutPrefix(text, prefix) }
+	if s, ok := cut("old mode "); ok {
+		file.OldMode = s
+	} else if s, ok := cut("new mode "); ok {
+		file.NewMode = s
+	} else if s, ok := cut("new file mode "); ok {
+		file.NewMode = s
+		file.IsNew = true
+	} else if s, ok := cut("deleted file mode "); ok {
+		file.OldMode = s
+		file.IsDeleted = true
+	} else if s, ok := cut("rename from "); ok {
+		file.OldName = unquoteName(s)
+		file.IsRename = true
+	} else if s, ok := cut("rename to "); ok {
+		file.NewName = unquoteName(s)
+		file.IsRename = true
+	} else if s, ok := cut("copy from "); ok {
+		file.OldName = unquoteName(s)
+		file.IsCopy = true
+	} else if s, ok := cut("copy to "); ok {
+		file.NewName = unquoteName(s)
+		file.IsCopy = true
+	} else if strings.HasPrefix(text, "Binary files ") || text == "GIT binary patch" {
+		file.IsBinary = true
+	} else if !strings.HasPrefix(text, "index ") &&
+		!strings.HasPrefix(text, "similarity index ") &&
+		!strings.HasPrefix(text, "dissimilarity index ") {
+		return false
+	}
+	return true
+}
+
+// parseGitNames parses the names of a "diff --git" line. They are ambiguous
+// if they contain spaces and are not quoted; in that case the names are
+// assumed to have the usual "a/" and "b/" prefixes.
+func parseGitNames(s string) (oldName, newName string) {
+	if strings.HasPrefix(s, `"`) {
+		if q, err := strconv.QuotedPrefix(s); err == nil {
+			return unquoteName(q), unquoteName(strings.TrimPrefix(s[len(q):], " "))
+		}
+	}
+	i := strings.Index(s, " b/")
+	if i < 0 {
+		i = strings.LastIndexByte(s, ' ')
+	}
+	if i < 0 {
+		return s, s
+	}
+	return s[:i], unquoteName(s[i+1:])
+}
+
+// parseName parses the name of a "---" or "+++" line, removing any
+// timestamp that follows it.
+func parseName(s string) string {
+	if strings.HasPrefix(s, `"`) {
+		if q, err := strconv.QuotedPrefix(s); err == nil {
+			return unquoteName(q)
+		}
+	}
+	if i := strings.IndexByte(s, '\t'); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimRight(s, " ")
+}
+
+// unquoteName unquotes a name that git has quoted because it contains
+// special characters.
+func unquoteName(s string) string {
+	if strings.HasPrefix(s, `"`) {
+		if u, err := strconv.Unquote(s); err == nil {
+			return u
+		}
+	}
+	return s
+}
+
+// parseHunk parses a hunk, given its header line.
+func (p *patchParser) parseHunk(header string) (*Hunk, error) {
+	h := new(Hunk)
+	rest, ok := strings.CutPrefix(header, "@@ -")
+	ranges, section, ok2 := strings.Cut(rest, " @@")
+	oldRange, newRange, ok3 := strings.Cut(ranges, " +")
+	if !ok || !ok2 || !ok3 {
+		return nil, p.errorf("malformed hunk header %q", header)
+	}
+	var err1, err2 error
+	h.OldStart, h.OldLines, err1 = parseRange(oldRange)
+	h.NewStart, h.NewLines, err2 = parseRange(newRange)
+	if err1 != nil || err2 != nil {
+		return nil, p.errorf("malformed hunk header %q", header)
+	}
+	h.Section = strings.TrimPrefix(section, " ")
+
+	oldLeft, newLeft := h.OldLines, h.NewLines
+	for oldLeft > 0 || newLeft > 0 {
+		if p.i >= len(p.lines) {
+			return nil, p.errorf("hunk is truncated")
+		}
+		l := p.lines[p.i]
+		p.i++
+		if !strings.HasSuffix(l, "\n") {
+			l += "\n" // the patch itself lacks a final newline
+		}
+		op := l[0]
+		switch op {
+		case ' ':
+			oldLeft--
+			newLeft--
+		case '-':
+			oldLeft--
+		case '+':
+			newLeft--
+		case '\n':
+			// An empty context line whose leading space was lost,
+			// as often happens to patches sent by mail.
+			op, l = ' ', " \n"
+			oldLeft--
+			newLeft--
+		case '\\':
+			noNewline(h)
+			continue
+		default:
+			return nil, p.errorf("unexpected line in hunk: %q", l)
+		}
+		if oldLeft < 0 || newLeft < 0 {
+			return nil, p.errorf("hunk has more lines than its header states")
+		}
+		h.Lines = append(h.Lines, HunkLine{Op: op, Content: l[1:]})
+	}
+	if p.i < len(p.lines) && strings.HasPrefix(p.lines[p.i], `\`) {
+		p.i++
+		noNewline(h)
+	}
+	return h, nil
+}
+
+// noNewline handles a "\ No newline at end of file" marker, which applies
+// to the preceding line of the hunk.
+func noNewline(h *Hunk) {
+	if n := len(h.Lines); n > 0 {
+		h.Lines[n-1].Content = strings.TrimSuffix(h.Lines[n-1].Content, "\n")
+	}
+}
+
+// parseRange parses a hunk range of the form "start" or "start,count".
+func parseRange(s string) (start, count int, err error) {
+	startStr, countStr, ok := strings.Cut(s, ",")
+	start, err = strconv.Atoi(startStr)
+	if err != nil {
+		return 0, 0, err
+	}
+	count = 1
+	if ok {
+		count, err = strconv.Atoi(countStr)
+	}
+	if start < 0 || count < 0 {
+		err = fmt.Errorf("negative range")
+	}
+	return start, count, err
+}
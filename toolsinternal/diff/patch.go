@@ -0,0 +1,159 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diff
+
+import "strings"
+
+// PatchOptions controls how ApplyPatch locates hunks in the original text.
+type PatchOptions struct {
+	// MaxOffset is the maximum number of lines by which a hunk may be
+	// displaced from the position stated in its header, after allowing for
+	// the displacement of the preceding hunk. Zero requires hunks to be in
+	// exactly the stated position; a negative value allows any displacement.
+	MaxOffset int
+
+	// Fuzz is the maximum number of context lines that may be ignored at
+	// each end of a hunk when it does not match in full, as with the -F
+	// flag of patch(1).
+	Fuzz int
+}
+
+// A PatchResult is the result of applying the hunks of a FileDiff.
+type PatchResult struct {
+	File    *FileDiff
+	Edits   []Edit        // line-aligned edits for the applied hunks
+	Applied []HunkApplied // hunks that were applied, in order
+	Rejects []*Hunk       // hunks that could not be applied, in order
+}
+
+// A HunkApplied describes where a hunk was applied.
+type HunkApplied struct {
+	Hunk   *Hunk
+	Line   int // line of the original text at which the hunk starts (1-based)
+	Offset int // displacement from the position stated by the hunk, in lines
+	Fuzz   int // number of context lines ignored at each end of the hunk
+}
+
+// ApplyPatch computes the edits that apply the hunks of file to src, the
+// content of its original file.
+//
+// Each hunk is looked for first at its stated position, then at increasing
+// distances from it up to opts.MaxOffset lines. If the hunk's lines are not
+// found, up to opts.Fuzz lines of leading and trailing context are ignored
+// in turn. Hunks must match in order and without overlapping; those that
+// cannot be placed are reported as rejects, and the others are still
+// applied.
+//
+// To obtain the patched text, pass the edits to Apply.
+func ApplyPatch(src string, file *FileDiff, opts PatchOptions) *PatchResult {
+	lines := splitLines(src)
+	// offsets[i] is the byte offset of line i; offsets[len(lines)] is len(src).
+	offsets := make([]int, len(lines)+1)
+	for i, l := range lines {
+		offsets[i+1] = offsets[i] + len(l)
+	}
+
+	res := &PatchResult{File: file}
+	delta := 0 // displacement of the previous applied hunk
+	next := 0  // first line not consumed by previous hunks
+	for _, h := range file.Hunks {
+		var old, new []string
+		for _, l := range h.Lines {
+			if l.Op != '+' {
+				old = append(old, l.Content)
+			}
+			if l.Op != '-' {
+				new = append(new, l.Content)
+			}
+		}
+		lead, trail := contextLines(h)
+
+		// stated is the zero-based line at which the hunk's old lines begin.
+		// A range with no lines refers to the line before the insertion.
+		stated := h.OldStart - 1
+		if h.OldLines == 0 {
+			stated = h.OldStart
+		}
+
+		applied := false
+		for fuzz := 0; fuzz <= opts.Fuzz && !applied; fuzz++ {
+			dropLead, dropTrail := min(fuzz, lead), min(fuzz, trail)
+			if fuzz > 0 && dropLead+dropTrail == 0 {
+				break // no more context to ignore
+			}
+			want := old[dropLead : len(old)-dropTrail]
+			at, ok := findLines(lines, want, stated+dropLead+delta, next, opts.MaxOffset)
+			if !ok {
+				continue
+			}
+			applied = true
+			delta = at - (stated + dropLead)
+			next = at + len(want)
+			res.Applied = append(res.Applied, HunkApplied{
+				Hunk:   h,
+				Line:   at - dropLead + 1,
+				Offset: delta,
+				Fuzz:   fuzz,
+			})
+
+			// The edit replaces only the lines between the remaining context.
+			keepLead, keepTrail := lead-dropLead, trail-dropTrail
+			repl := new[dropLead+keepLead : len(new)-dropTrail-keepTrail]
+			edit := Edit{
+				Start: offsets[at+keepLead],
+				End:   offsets[at+len(want)-keepTrail],
+				New:   strings.Join(repl, ""),
+			}
+			if edit.Start != edit.End || edit.New != "" {
+				res.Edits = append(res.Edits, edit)
+			}
+		}
+		if !applied {
+			res.Rejects = append(res.Rejects, h)
+		}
+	}
+	return res
+}
+
+// contextLines returns the number of context lines at the start and end of
+// a hunk. They do not overlap, even if the hunk consists only of context.
+func contextLines(h *Hunk) (lead, trail int) {
+	for lead < len(h.Lines) && h.Lines[lead].Op == ' ' {
+		lead++
+	}
+	for trail < len(h.Lines)-lead && h.Lines[len(h.Lines)-1-trail].Op == ' ' {
+		trail++
+	}
+	return lead, trail
+}
+
+// findLines returns the position nearest to want at which lines contains
+// the sequence seq, searching no earlier than from and no further than
+// maxOffset lines away (if maxOffset is non-negative).
+func findLines(lines, seq []string, want, from, maxOffset int) (int, bool) {
+	matches := func(at int) bool {
+		if at < from || at+len(seq) > len(lines) {
+			return false
+		}
+		for i, l := range seq {
+			if lines[at+i] != l {
+				return false
+			}
+		}
+		return true
+	}
+	for d := 0; maxOffset < 0 || d <= maxOffset; d++ {
+		if matches(want + d) {
+			return want + d, true
+		}
+		if d > 0 && matches(want-d) {
+			return want - d, true
+		}
+		if want+d >= len(lines) && want-d <= from {
+			break // out of range in both directions
+		}
+	}
+	return 0, false
+}
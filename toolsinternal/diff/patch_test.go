@@ -0,0 +1,270 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diff_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/tenntenn/exp/toolsinternal/diff"
+	"github.com/tenntenn/exp/toolsinternal/diff/difftest"
+)
+
+// applyPatch parses a single-file patch and applies it to src.
+func applyPatch(t *testing.T, src, patch string, opts diff.PatchOptions) (string, *diff.PatchResult) {
+	t.Helper()
+	files, err := diff.ParseUnified(patch)
+	if err != nil {
+		t.Fatalf("ParseUnified: %v", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("ParseUnified returned %d files, want 1", len(files))
+	}
+	res := diff.ApplyPatch(src, files[0], opts)
+	got, err := diff.Apply(src, res.Edits)
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	return got, res
+}
+
+func TestPatchRoundTrip(t *testing.T) {
+	for _, tc := range difftest.TestCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			for _, edits := range [][]diff.Edit{tc.Edits, diff.Strings(tc.In, tc.Out)} {
+				unified, err := diff.ToUnified(difftest.FileA, difftest.FileB, tc.In, edits, diff.DefaultContextLines)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if unified == "" {
+					continue
+				}
+				got, res := applyPatch(t, tc.In, unified, diff.PatchOptions{})
+				if len(res.Rejects) > 0 {
+					t.Fatalf("%d hunks rejected from patch:\n%s", len(res.Rejects), unified)
+				}
+				if got != tc.Out {
+					t.Errorf("got %q, want %q; patch:\n%s", got, tc.Out, unified)
+				}
+			}
+		})
+	}
+}
+
+func TestParseUnified(t *testing.T) {
+	const patch = `From 1234 Mon Sep 17 00:00:00 2001
+Subject: [PATCH] various changes
+
+---
+diff --git a/old.go b/new.go
+similarity index 90%
+rename from old.go
+rename to new.go
+index 1111111..2222222 100644
+--- a/old.go
++++ b/new.go
+@@ -1,3 +1,3 @@ package p
+ package p
+-var x = 1
++var x = 2
+ // end
+diff --git a/script.sh b/script.sh
+old mode 100644
+new mode 100755
+diff --git a/created.txt b/created.txt
+new file mode 100644
+index 0000000..3333333
+--- /dev/null
++++ b/created.txt
+@@ -0,0 +1,2 @@
++hello
++world
+\ No newline at end of file
+diff --git a/gone.txt b/gone.txt
+deleted file mode 100644
+--- a/gone.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-bye
+diff --git "a/with space.bin" "b/with space.bin"
+index 4444444..5555555 100644
+Binary files "a/with space.bin" and "b/with space.bin" differ
+--- plain.txt	2025-01-01 00:00:00
++++ plain.txt	2025-01-02 00:00:00
+@@ -1,2 +1,2 @@
+-a
++b
+ c
+--
+2.40.0
+`
+	files, err := diff.ParseUnified(patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range files {
+		s := fmt.Sprintf("%s -> %s", f.OldName, f.NewName)
+		for _, flag := range []struct {
+			set  bool
+			name string
+		}{{f.IsNew, "new"}, {f.IsDeleted, "deleted"}, {f.IsRename, "rename"}, {f.IsCopy, "copy"}, {f.IsBinary, "binary"}} {
+			if flag.set {
+				s += " " + flag.name
+			}
+		}
+		if f.OldMode != "" || f.NewMode != "" {
+			s += fmt.Sprintf(" mode %q->%q", f.OldMode, f.NewMode)
+		}
+		for _, h := range f.Hunks {
+			s += fmt.Sprintf(" [-%d,%d +%d,%d %q %q->%q]", h.OldStart, h.OldLines, h.NewStart, h.NewLines, h.Section, h.Old(), h.New())
+		}
+		got = append(got, s)
+	}
+	want := []string{
+		`a/old.go -> b/new.go rename [-1,3 +1,3 "package p" "package p\nvar x = 1\n// end\n"->"package p\nvar x = 2\n// end\n"]`,
+		`a/script.sh -> b/script.sh mode "100644"->"100755"`,
+		`/dev/null -> b/created.txt new mode ""->"100644" [-0,0 +1,2 "" ""->"hello\nworld"]`,
+		`a/gone.txt -> /dev/null deleted mode "100644"->"" [-1,1 +0,0 "" "bye\n"->""]`,
+		`a/with space.bin -> b/with space.bin binary`,
+		`plain.txt -> plain.txt [-1,2 +1,2 "" "a\nc\n"->"b\nc\n"]`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// Creation and deletion.
+	if got, _ := applyPatch(t, "", `--- /dev/null
++++ b/created.txt
+@@ -0,0 +1,2 @@
++hello
++world
+\ No newline at end of file
+`, diff.PatchOptions{}); got != "hello\nworld" {
+		t.Errorf("creation: got %q", got)
+	}
+	if got, _ := applyPatch(t, "bye\n", "--- a/gone.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-bye\n", diff.PatchOptions{}); got != "" {
+		t.Errorf("deletion: got %q", got)
+	}
+}
+
+func TestParseUnifiedErrors(t *testing.T) {
+	for _, test := range []struct {
+		patch, want string
+	}{
+		{"@@ -1 +1 @@\n-a\n+b\n", "line 1: hunk before file header"},
+		{"--- a\n+++ b\n@@ -1 +1\n-a\n+b\n", `line 3: malformed hunk header "@@ -1 +1"`},
+		{"--- a\n+++ b\n@@ -x +1 @@\n-a\n+b\n", `line 3: malformed hunk header "@@ -x +1 @@"`},
+		{"--- a\n+++ b\n@@ -1,2 +1,2 @@\n-a\n+b\n", "line 5: hunk is truncated"},
+		{"--- a\n+++ b\n@@ -1 +1,2 @@\n a\n b\n", "line 5: hunk has more lines than its header states"},
+		{"--- a\n+++ b\n@@ -1 +1 @@\n*a\n+b\n", `line 4: unexpected line in hunk: "*a\n"`},
+	} {
+		_, err := diff.ParseUnified(test.patch)
+		if err == nil || err.Error() != test.want {
+			t.Errorf("ParseUnified(%q) = %v, want %q", test.patch, err, test.want)
+		}
+	}
+}
+
+func TestApplyPatchFuzzy(t *testing.T) {
+	// The patch was made against this text.
+	const orig = "1\n2\n3\n4\n5\n6\n7\n8\n9\n"
+	const patch = `--- a
++++ b
+@@ -2,5 +2,5 @@
+ 2
+ 3
+-4
++four
+ 5
+ 6
+@@ -7,3 +7,3 @@
+ 7
+-8
++eight
+ 9
+`
+	for _, test := range []struct {
+		name    string
+		src     string
+		opts    diff.PatchOptions
+		want    string // "" => all hunks rejected
+		applied string // Line/Offset/Fuzz of applied hunks
+	}{
+		{
+			name:    "exact",
+			src:     orig,
+			want:    "1\n2\n3\nfour\n5\n6\n7\neight\n9\n",
+			applied: "2/0/0 7/0/0",
+		},
+		{
+			name:    "offset",
+			src:     "0\n00\n" + orig,
+			opts:    diff.PatchOptions{MaxOffset: 2},
+			want:    "0\n00\n1\n2\n3\nfour\n5\n6\n7\neight\n9\n",
+			applied: "4/2/0 9/2/0",
+		},
+		{
+			name: "offset too large",
+			src:  "0\n00\n0\n" + orig,
+			opts: diff.PatchOptions{MaxOffset: 2},
+		},
+		{
+			name:    "unlimited offset",
+			src:     strings.Repeat("x\n", 100) + orig,
+			opts:    diff.PatchOptions{MaxOffset: -1},
+			want:    strings.Repeat("x\n", 100) + "1\n2\n3\nfour\n5\n6\n7\neight\n9\n",
+			applied: "102/100/0 107/100/0",
+		},
+		{
+			name:    "removed line before second hunk",
+			src:     "1\n2\n3\n4\n5\n6\n8\n9\n",
+			opts:    diff.PatchOptions{MaxOffset: 1, Fuzz: 1},
+			want:    "1\n2\n3\nfour\n5\n6\neight\n9\n",
+			applied: "2/0/0 6/-1/1",
+		},
+		{
+			name: "changed context without fuzz",
+			src:  "1\nTWO\n3\n4\n5\n6\n7\n8\nNINE\n",
+			want: "1\nTWO\n3\n4\n5\n6\n7\n8\nNINE\n",
+		},
+		{
+			name:    "changed context with fuzz",
+			src:     "1\nTWO\n3\n4\n5\n6\n7\n8\nNINE\n",
+			opts:    diff.PatchOptions{Fuzz: 1},
+			want:    "1\nTWO\n3\nfour\n5\n6\n7\neight\nNINE\n",
+			applied: "2/0/1 7/0/1",
+		},
+		{
+			name:    "changed deleted line",
+			src:     "1\n2\n3\n4\n5\n6\n7\nEIGHT\n9\n",
+			opts:    diff.PatchOptions{MaxOffset: -1, Fuzz: 2},
+			want:    "1\n2\n3\nfour\n5\n6\n7\nEIGHT\n9\n",
+			applied: "2/0/0",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, res := applyPatch(t, test.src, patch, test.opts)
+			want := test.want
+			if want == "" {
+				want = test.src
+			}
+			if got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+			var applied []string
+			for _, a := range res.Applied {
+				applied = append(applied, fmt.Sprintf("%d/%d/%d", a.Line, a.Offset, a.Fuzz))
+			}
+			if got := strings.Join(applied, " "); got != test.applied {
+				t.Errorf("applied %s, want %s", got, test.applied)
+			}
+			if got, want := len(res.Applied)+len(res.Rejects), 2; got != want {
+				t.Errorf("%d hunks applied or rejected, want %d", got, want)
+			}
+		})
+	}
+}
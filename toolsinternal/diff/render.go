@@ -0,0 +1,432 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// This file defines renderers of diffs other than the unified form:
+// side-by-side text, a standalone HTML report, and JSON.
+
+// SideBySide returns the diff of content and the result of applying
+// edits to it, with contextLines lines of context, as two columns that
+// together fit in width terminal cells: the original lines on the left,
+// and the modified ones on the right. Each deleted line is shown beside
+// the inserted line that Highlight pairs it with. Lines that do not fit
+// are wrapped, allowing for wide characters, and tabs are expanded to
+// multiples of 4 columns. If width is less than 40, 40 is used.
+func SideBySide(oldLabel, newLabel, content string, edits []Edit, contextLines, width int) (string, error) {
+	d, err := HighlightUnified(oldLabel, newLabel, content, edits, contextLines)
+	if err != nil || len(d.Hunks) == 0 {
+		return "", err
+	}
+	width = max(width, 40)
+
+	// Each column has a line number, an op, and text.
+	maxLine := 0
+	for _, h := range d.Hunks {
+		maxLine = max(maxLine, h.OldStart+h.OldLines, h.NewStart+h.NewLines)
+	}
+	const sep = " | "
+	column := (width - len(sep)) / 2
+	numWidth := len(strconv.Itoa(maxLine))
+	textWidth := max(column-numWidth-3, 1)
+
+	var b strings.Builder
+	row := func(left, right string) {
+		b.WriteString(strings.TrimRight(left+pad(column-displayWidth(left))+sep+right, " "))
+		b.WriteByte('\n')
+	}
+	// cell returns the rows of a column for a line.
+	cell := func(l *HighlightedLine, num int) []string {
+		if l == nil {
+			return nil
+		}
+		text := strings.TrimSuffix(l.Content, "\n")
+		chunks := wrap(text, textWidth)
+		if !strings.HasSuffix(l.Content, "\n") {
+			chunks = append(chunks, wrap(`\ No newline at end of file`, textWidth)...)
+		}
+		rows := make([]string, len(chunks))
+		for i, chunk := range chunks {
+			prefix := pad(numWidth + 3)
+			if i == 0 {
+				prefix = fmt.Sprintf("%*d %c ", numWidth, num, l.Op)
+			}
+			rows[i] = prefix + chunk
+		}
+		return rows
+	}
+	pair := func(del, ins *HighlightedLine, oldNum, newNum int) {
+		left, right := cell(del, oldNum), cell(ins, newNum)
+		for i := range max(len(left), len(right)) {
+			var l, r string
+			if i < len(left) {
+				l = left[i]
+			}
+			if i < len(right) {
+				r = right[i]
+			}
+			row(l, r)
+		}
+	}
+
+	row(truncate(d.From, column), truncate(d.To, column))
+	for _, h := range d.Hunks {
+		b.WriteString(truncate(h.header(), width))
+		b.WriteByte('\n')
+		oldNum, newNum := firstLines(h)
+		for i := 0; i < len(h.Lines); {
+			if l := &h.Lines[i]; l.Op == ' ' {
+				pair(l, l, oldNum, newNum)
+				oldNum, newNum, i = oldNum+1, newNum+1, i+1
+				continue
+			}
+			dels := i
+			for i < len(h.Lines) && h.Lines[i].Op == '-' {
+				i++
+			}
+			ins := i
+			for i < len(h.Lines) && h.Lines[i].Op == '+' {
+				i++
+			}
+			for k := 0; dels+k < ins || ins+k < i; k++ {
+				var del, in *HighlightedLine
+				if dels+k < ins {
+					del = &h.Lines[dels+k]
+				}
+				if ins+k < i {
+					in = &h.Lines[ins+k]
+				}
+				pair(del, in, oldNum, newNum)
+				if del != nil {
+					oldNum++
+				}
+				if in != nil {
+					newNum++
+				}
+			}
+		}
+	}
+	return b.String(), nil
+}
+
+// firstLines returns the numbers of the first lines of a hunk in the
+// original and modified files.
+func firstLines(h HighlightedHunk) (old, new int) {
+	old, new = h.OldStart, h.NewStart
+	// An empty range starts at the line before it.
+	if h.OldLines == 0 {
+		old++
+	}
+	if h.NewLines == 0 {
+		new++
+	}
+	return old, new
+}
+
+// wrap expands the tabs of text and divides it into chunks of at most
+// width cells. It returns at least one chunk.
+func wrap(text string, width int) []string {
+	var (
+		chunks []string
+		chunk  strings.Builder
+		col    int // display column within text
+		used   int // cells used in chunk
+	)
+	for _, r := range text {
+		s, w := string(r), runeWidth(r)
+		if r == '\t' {
+			w = 4 - col%4
+			s = pad(w)
+		}
+		col += w
+		for s != "" {
+			if used+w > width && used > 0 {
+				chunks = append(chunks, chunk.String())
+				chunk.Reset()
+				used = 0
+			}
+			if r == '\t' && w > width-used {
+				// Divide the spaces of a tab.
+				n := width - used
+				chunk.WriteString(s[:n])
+				s, w, used = s[n:], w-n, width
+				continue
+			}
+			chunk.WriteString(s)
+			used += w
+			s = ""
+		}
+	}
+	return append(chunks, chunk.String())
+}
+
+// truncate returns the longest prefix of s that fits in width cells.
+func truncate(s string, width int) string {
+	used := 0
+	for i, r := range s {
+		if used += runeWidth(r); used > width {
+			return s[:i]
+		}
+	}
+	return s
+}
+
+func pad(n int) string { return strings.Repeat(" ", max(n, 0)) }
+
+func displayWidth(s string) int {
+	n := 0
+	for _, r := range s {
+		n += runeWidth(r)
+	}
+	return n
+}
+
+// runeWidth returns the number of terminal cells occupied by r: zero for
+// control characters and combining marks, two for East Asian wide and
+// fullwidth characters and emoji, and one otherwise.
+func runeWidth(r rune) int {
+	if r < 0x20 || r == 0x7f || unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) {
+		return 0
+	}
+	for _, rng := range wideRunes {
+		if rng[0] <= r && r <= rng[1] {
+			return 2
+		}
+	}
+	return 1
+}
+
+// wideRunes holds the ranges of runes that occupy two terminal cells.
+var wideRunes = [][2]rune{
+	{0x1100, 0x115f},   // Hangul Jamo
+	{0x2e80, 0x303e},   // CJK radicals ... CJK symbols and punctuation
+	{0x3040, 0xa4cf},   // Hiragana ... Yi
+	{0xac00, 0xd7a3},   // Hangul syllables
+	{0xf900, 0xfaff},   // CJK compatibility ideographs
+	{0xfe30, 0xfe4f},   // CJK compatibility forms
+	{0xff00, 0xff60},   // fullwidth forms
+	{0xffe0, 0xffe6},   // fullwidth signs
+	{0x1f300, 0x1f64f}, // pictographs and emoticons
+	{0x1f900, 0x1f9ff}, // supplemental pictographs
+	{0x20000, 0x3fffd}, // CJK extensions
+}
+
+// HTMLReport returns a standalone HTML document that shows the diff of
+// content and the result of applying edits to it. It lists the whole
+// file in unified form, with the numbers of each line in the original
+// and modified files, and with changed parts of lines enclosed in del
+// and ins elements. Unchanged lines more than contextLines from a change
+// are collapsed into details elements, which the reader may expand.
+func HTMLReport(oldLabel, newLabel, content string, edits []Edit, contextLines int) (string, error) {
+	// Compute a single hunk with the whole file as context.
+	d, err := HighlightUnified(oldLabel, newLabel, content, edits, strings.Count(content, "\n")+1)
+	if err != nil {
+		return "", err
+	}
+	contextLines = max(contextLines, 0)
+
+	var b strings.Builder
+	title := html.EscapeString(oldLabel + " → " + newLabel)
+	fmt.Fprintf(&b, `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<style>
+body { font-family: sans-serif; }
+.diff { font-family: monospace; border: 1px solid #ccc; }
+.line { display: flex; white-space: pre; }
+.num { min-width: 4em; padding: 0 0.5em; text-align: right; color: #888; user-select: none; }
+.text { padding-left: 0.5em; }
+.del { background: #ffebe9; }
+.ins { background: #e6ffec; }
+.del del { background: #ffc1c0; text-decoration: none; }
+.ins ins { background: #abf2bc; text-decoration: none; }
+.nonl { color: #888; }
+summary { background: #f0f4ff; color: #555; padding: 0.2em 0.5em; cursor: pointer; }
+</style>
+</head>
+<body>
+<h1>%s</h1>
+`, title, title)
+	if len(d.Hunks) == 0 {
+		b.WriteString("<p>No differences.</p>\n")
+	} else {
+		b.WriteString("<div class=\"diff\">\n")
+		h := d.Hunks[0]
+		oldNum, newNum := firstLines(h)
+		line := func(l HighlightedLine) {
+			class, open, close := "ctx", "", ""
+			oldCol, newCol := "", ""
+			switch l.Op {
+			case '-':
+				class, open, close = "del", "<del>", "</del>"
+				oldCol = strconv.Itoa(oldNum)
+				oldNum++
+			case '+':
+				class, open, close = "ins", "<ins>", "</ins>"
+				newCol = strconv.Itoa(newNum)
+				newNum++
+			default:
+				oldCol, newCol = strconv.Itoa(oldNum), strconv.Itoa(newNum)
+				oldNum++
+				newNum++
+			}
+			fmt.Fprintf(&b, `<div class="line %s"><span class="num">%s</span><span class="num">%s</span><span class="text">%c`, class, oldCol, newCol, l.Op)
+			writeSpans(&b, l, open, close, html.EscapeString)
+			if !strings.HasSuffix(l.Content, "\n") {
+				b.WriteString(` <span class="nonl">\ No newline at end of file</span>`)
+			}
+			b.WriteString("</span></div>\n")
+		}
+		lines := h.Lines
+		for i := 0; i < len(lines); {
+			if lines[i].Op != ' ' {
+				line(lines[i])
+				i++
+				continue
+			}
+			// Collapse the unchanged lines of a run that are not near
+			// a change.
+			start := i
+			for i < len(lines) && lines[i].Op == ' ' {
+				i++
+			}
+			show, hide := start, i // lines [show, hide) are collapsed
+			if start > 0 {
+				show += contextLines
+			}
+			if i < len(lines) {
+				hide -= contextLines
+			}
+			if hide-show < 2 {
+				show, hide = i, i // not worth collapsing
+			}
+			for _, l := range lines[start:show] {
+				line(l)
+			}
+			if show < hide {
+				fmt.Fprintf(&b, "<details><summary>%d unchanged lines</summary>\n", hide-show)
+				for _, l := range lines[show:hide] {
+					line(l)
+				}
+				b.WriteString("</details>\n")
+			}
+			for _, l := range lines[hide:i] {
+				line(l)
+			}
+		}
+		b.WriteString("</div>\n")
+	}
+	b.WriteString("</body>\n</html>\n")
+	return b.String(), nil
+}
+
+// JSON returns the diff of content and the result of applying edits to
+// it, with contextLines lines of context, in a JSON form suitable for
+// web interfaces. It is an object with these fields:
+//
+//	old, new  the labels of the original and modified files
+//	hunks     an array of hunks
+//
+// Each hunk has the fields oldStart, oldLines, newStart and newLines, as
+// in its header, which is also given, and lines, an array of objects
+// with these fields:
+//
+//	op         " " for context, "-" for a deletion, "+" for an insertion
+//	text       the text of the line, without its newline
+//	oldLine    the 1-based line number in the original file, if any
+//	newLine    the 1-based line number in the modified file, if any
+//	changed    the changed parts of the line, if it is paired with
+//	           another, as [start, end) pairs of UTF-16 offsets in text,
+//	           as used by JavaScript strings
+//	noNewline  true if the line has no newline
+func JSON(oldLabel, newLabel, content string, edits []Edit, contextLines int) ([]byte, error) {
+	d, err := HighlightUnified(oldLabel, newLabel, content, edits, contextLines)
+	if err != nil {
+		return nil, err
+	}
+	type jsonLine struct {
+		Op        string   `json:"op"`
+		Text      string   `json:"text"`
+		OldLine   int      `json:"oldLine,omitempty"`
+		NewLine   int      `json:"newLine,omitempty"`
+		Changed   [][2]int `json:"changed,omitempty"`
+		NoNewline bool     `json:"noNewline,omitempty"`
+	}
+	type jsonHunk struct {
+		Header   string     `json:"header"`
+		OldStart int        `json:"oldStart"`
+		OldLines int        `json:"oldLines"`
+		NewStart int        `json:"newStart"`
+		NewLines int        `json:"newLines"`
+		Lines    []jsonLine `json:"lines"`
+	}
+	type jsonDiff struct {
+		Old   string     `json:"old"`
+		New   string     `json:"new"`
+		Hunks []jsonHunk `json:"hunks"`
+	}
+	res := jsonDiff{Old: d.From, New: d.To, Hunks: []jsonHunk{}}
+	for _, h := range d.Hunks {
+		jh := jsonHunk{
+			Header:   h.header(),
+			OldStart: h.OldStart,
+			OldLines: h.OldLines,
+			NewStart: h.NewStart,
+			NewLines: h.NewLines,
+		}
+		oldNum, newNum := firstLines(h)
+		for _, l := range h.Lines {
+			text := strings.TrimSuffix(l.Content, "\n")
+			jl := jsonLine{
+				Op:        string(l.Op),
+				Text:      text,
+				NoNewline: text == l.Content,
+			}
+			if l.Op != '+' {
+				jl.OldLine = oldNum
+				oldNum++
+			}
+			if l.Op != '-' {
+				jl.NewLine = newNum
+				newNum++
+			}
+			// Lines changed as a whole are not paired.
+			if !(len(l.Changed) == 1 && l.Changed[0] == Span{0, len(text)}) {
+				for _, s := range l.Changed {
+					start := utf16Len(text[:s.Start])
+					jl.Changed = append(jl.Changed, [2]int{start, start + utf16Len(text[s.Start:s.End])})
+				}
+			}
+			jh.Lines = append(jh.Lines, jl)
+		}
+		res.Hunks = append(res.Hunks, jh)
+	}
+	return json.Marshal(res)
+}
+
+// utf16Len returns the length of s in UTF-16 code units.
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		if r == utf8.RuneError {
+			n++
+		} else {
+			n += utf16.RuneLen(r)
+		}
+	}
+	return n
+}
@@ -0,0 +1,155 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diff_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/tenntenn/exp/toolsinternal/diff"
+	"github.com/tenntenn/exp/toolsinternal/diff/difftest"
+)
+
+const (
+	renderBefore = "package p\n\nfunc f() {\n\tx := 1\n\treturn\n}\n" +
+		"// filler\n// filler\n// filler\n// filler\n" +
+		"// 日本語のコメントはとても長いのでちゃんと折り返されるべきです\nvar s = \"<a>\""
+	renderAfter = "package p\n\nfunc f() {\n\tx := 2\n\ty := 3\n\treturn\n}\n" +
+		"// filler\n// filler\n// filler\n// filler\n" +
+		"// 日本語のコメントはとても長いので折り返されるべきです\nvar s = \"<a😀b>\"\n"
+)
+
+func TestSideBySide(t *testing.T) {
+	got, err := diff.SideBySide("a/p.go", "b/p.go", renderBefore, diff.Lines(renderBefore, renderAfter, nil), 1, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `a/p.go                       | b/p.go
+@@ -3,3 +3,4 @@
+ 3   func f() {              |  3   func f() {
+ 4 -     x := 1              |  4 +     x := 2
+                             |  5 +     y := 3
+ 5       return              |  6       return
+@@ -10,3 +11,3 @@
+10   // filler               | 11   // filler
+11 - // 日本語のコメントはと | 12 + // 日本語のコメントはと
+     ても長いのでちゃんと折  |      ても長いので折り返され
+     り返されるべきです      |      るべきです
+12 - var s = "<a>"           | 13 + var s = "<a😀b>"
+     \ No newline at end of  |
+     file                    |
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Every row fits in the width.
+	for _, tc := range difftest.TestCases {
+		for _, width := range []int{0, 41, 80} {
+			got, err := diff.SideBySide(difftest.FileA, difftest.FileB, tc.In, tc.Edits, 3, width)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (got == "") != (tc.In == tc.Out) {
+				t.Errorf("%s: SideBySide returned %q", tc.Name, got)
+			}
+			for _, row := range strings.Split(got, "\n") {
+				if n := len([]rune(row)); n > max(width, 40) {
+					t.Errorf("%s: row %q of %d characters is wider than %d", tc.Name, row, n, width)
+				}
+			}
+		}
+	}
+}
+
+func TestHTMLReport(t *testing.T) {
+	got, err := diff.HTMLReport("a/p.go", "b/p.go", renderBefore, diff.Lines(renderBefore, renderAfter, nil), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"<!DOCTYPE html>",
+		"<title>a/p.go → b/p.go</title>",
+		// "package p" and the blank line are collapsed.
+		"<details><summary>2 unchanged lines</summary>\n" +
+			`<div class="line ctx"><span class="num">1</span><span class="num">1</span><span class="text"> package p</span></div>`,
+		`<div class="line del"><span class="num">4</span><span class="num"></span><span class="text">-	x := <del>1</del></span></div>`,
+		`<div class="line ins"><span class="num"></span><span class="num">5</span><span class="text">+	y := 3</span></div>`,
+		"<details><summary>4 unchanged lines</summary>\n" +
+			`<div class="line ctx"><span class="num">6</span><span class="num">7</span><span class="text"> }</span></div>`,
+		`-var s = &#34;&lt;a&gt;&#34; <span class="nonl">\ No newline at end of file</span>`,
+		`+var s = &#34;&lt;a<ins>😀b</ins>&gt;&#34;`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("report does not contain %q:\n%s", want, got)
+		}
+	}
+	if n := strings.Count(got, "<details>"); n != 2 {
+		t.Errorf("report has %d collapsed sections, want 2", n)
+	}
+
+	got, err = diff.HTMLReport("a", "b", "x\n", nil, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got, "No differences.") {
+		t.Errorf("report of no edits does not say so:\n%s", got)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	data, err := diff.JSON("a/p.go", "b/p.go", renderBefore, diff.Lines(renderBefore, renderAfter, nil), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Old, New string
+		Hunks    []struct {
+			Header                                 string
+			OldStart, OldLines, NewStart, NewLines int
+			Lines                                  []struct {
+				Op, Text         string
+				OldLine, NewLine int
+				Changed          [][2]int
+				NoNewline        bool
+			}
+		}
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	for _, h := range got.Hunks {
+		lines = append(lines, fmt.Sprintf("%s %d,%d %d,%d", h.Header, h.OldStart, h.OldLines, h.NewStart, h.NewLines))
+		for _, l := range h.Lines {
+			lines = append(lines, fmt.Sprintf("%s%q %d %d %v %t", l.Op, l.Text, l.OldLine, l.NewLine, l.Changed, l.NoNewline))
+		}
+	}
+	want := []string{
+		"@@ -4 +4,2 @@ 4,1 4,2",
+		`-"\tx := 1" 4 0 [[6 7]] false`,
+		`+"\tx := 2" 0 4 [[6 7]] false`,
+		`+"\ty := 3" 0 5 [] false`,
+		"@@ -11,2 +12,2 @@ 11,2 12,2",
+		`-"// 日本語のコメントはとても長いのでちゃんと折り返されるべきです" 11 0 [[19 23]] false`,
+		`-"var s = \"<a>\"" 12 0 [] true`,
+		`+"// 日本語のコメントはとても長いので折り返されるべきです" 0 12 [] false`,
+		`+"var s = \"<a😀b>\"" 0 13 [[11 14]] false`, // UTF-16 offsets
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+
+	// No edits give no hunks.
+	data, err = diff.JSON("a", "b", "x\n", nil, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), `{"old":"a","new":"b","hunks":[]}`; got != want {
+		t.Errorf("JSON of no edits = %s, want %s", got, want)
+	}
+}
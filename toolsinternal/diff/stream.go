@@ -0,0 +1,279 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diff
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// StreamOptions holds the optional parameters of Stream.
+type StreamOptions struct {
+	Options // the line diff algorithm
+
+	// Window is the number of lines of each input that are held in
+	// memory at a time. If zero, 10000 is used.
+	Window int
+}
+
+// binaryPeek is the number of leading bytes examined for a NUL byte,
+// which marks a binary file, as in git.
+const binaryPeek = 8000
+
+// Stream writes a unified diff of the contents of before and after to w, with
+// contextLines lines of context around each hunk, and reports whether
+// they differ. A nil opts is equivalent to a pointer to a zero
+// StreamOptions.
+//
+// Unlike ToUnified, Stream reads its inputs incrementally and holds only
+// a window of lines of each in memory. It computes the differences
+// between the windows, writes those that are followed by enough matching
+// lines not to depend on the lines still unread, and then advances the
+// windows. The result is a valid diff, but may be larger than that of
+// Lines where changes span more than a window, and the hunks of such
+// changes are divided so that none is larger than a window.
+//
+// If either input has a NUL byte within its first 8000 bytes, it is
+// considered binary, and if the inputs differ, Stream writes only
+// "Binary files old and new differ", using the labels.
+func Stream(w io.Writer, oldLabel, newLabel string, before, after io.Reader, contextLines int, opts *StreamOptions) (bool, error) {
+	if opts == nil {
+		opts = new(StreamOptions)
+	}
+	window := opts.Window
+	if window <= 0 {
+		window = 10000
+	}
+	a, b := bufio.NewReaderSize(before, binaryPeek), bufio.NewReaderSize(after, binaryPeek)
+	if isBinary(a) || isBinary(b) {
+		differ, err := differBytes(a, b)
+		if differ && err == nil {
+			_, err = fmt.Fprintf(w, "Binary files %s and %s differ\n", oldLabel, newLabel)
+		}
+		return differ, err
+	}
+
+	out := &hunkWriter{
+		w:        w,
+		from:     oldLabel,
+		to:       newLabel,
+		context:  max(contextLines, 0),
+		maxLines: window,
+	}
+	ra, rb := &lineReader{r: a}, &lineReader{r: b}
+	var x, y []string // windows of unprocessed lines
+	for out.err == nil {
+		// Skip the lines the windows have in common.
+		for {
+			if len(x) == 0 {
+				x = ra.fill(x, window)
+			}
+			if len(y) == 0 {
+				y = rb.fill(y, window)
+			}
+			if len(x) == 0 || len(y) == 0 || x[0] != y[0] {
+				break
+			}
+			out.line(opEqual, x[0])
+			x, y = x[1:], y[1:]
+		}
+		x, y = ra.fill(x, window), rb.fill(y, window)
+		if len(x) == 0 && len(y) == 0 {
+			break
+		}
+
+		// Write the differences that are settled: those that end well
+		// before the ends of the windows, unless the inputs are
+		// exhausted. The first is always written, so that the windows
+		// advance.
+		margin := window / 4
+		done := ra.eof && rb.eof
+		nx, ny := 0, 0 // lines of x and y written
+		for i, d := range lineDiffs(x, y, &opts.Options) {
+			if i > 0 && !done && (d.End > len(x)-margin || d.ReplEnd > len(y)-margin) {
+				break
+			}
+			for ; nx < d.Start; nx, ny = nx+1, ny+1 {
+				out.line(opEqual, x[nx])
+			}
+			for ; nx < d.End; nx++ {
+				out.line(opDelete, x[nx])
+			}
+			for ; ny < d.ReplEnd; ny++ {
+				out.line(opInsert, y[ny])
+			}
+		}
+		if done {
+			for ; nx < len(x); nx, ny = nx+1, ny+1 {
+				out.line(opEqual, x[nx])
+			}
+		}
+		x, y = x[nx:], y[ny:]
+	}
+	out.flush()
+	if out.err != nil {
+		return out.differ, out.err
+	}
+	if ra.err != nil {
+		return out.differ, ra.err
+	}
+	return out.differ, rb.err
+}
+
+// isBinary reports whether the start of r contains a NUL byte.
+func isBinary(r *bufio.Reader) bool {
+	data, _ := r.Peek(binaryPeek)
+	return bytes.IndexByte(data, 0) >= 0
+}
+
+// differBytes reports whether two inputs differ.
+func differBytes(a, b io.Reader) (bool, error) {
+	bufA, bufB := make([]byte, 32<<10), make([]byte, 32<<10)
+	for {
+		na, errA := io.ReadFull(a, bufA)
+		nb, errB := io.ReadFull(b, bufB)
+		if !bytes.Equal(bufA[:na], bufB[:nb]) {
+			return true, nil
+		}
+		if errA == io.EOF || errA == io.ErrUnexpectedEOF {
+			return false, nil // both ended
+		}
+		if errA != nil {
+			return false, errA
+		}
+		if errB != nil {
+			return false, errB
+		}
+	}
+}
+
+// A lineReader reads lines, including their newlines, from a reader.
+type lineReader struct {
+	r   *bufio.Reader
+	eof bool
+	err error
+}
+
+// fill appends lines to buf until it has n lines or the input ends.
+func (lr *lineReader) fill(buf []string, n int) []string {
+	for len(buf) < n && !lr.eof {
+		line, err := lr.r.ReadString('\n')
+		if line != "" {
+			buf = append(buf, line)
+		}
+		if err != nil {
+			lr.eof = true
+			if err != io.EOF {
+				lr.err = err
+			}
+		}
+	}
+	return buf
+}
+
+// A hunkWriter writes a unified diff a line at a time.
+type hunkWriter struct {
+	w        io.Writer
+	from, to string
+	context  int // lines of context
+	maxLines int // size at which a hunk is divided
+
+	old, new int      // numbers of lines of each input consumed
+	before   []string // equal lines preceding the current hunk
+	hunk     *hunk    // current hunk, if any
+	trailing int      // equal lines at the end of the hunk
+	differ   bool     // whether a hunk has been written
+	err      error
+}
+
+// line adds a line of the given kind to the diff.
+func (hw *hunkWriter) line(kind opKind, content string) {
+	if kind == opEqual {
+		hw.old++
+		hw.new++
+		if hw.hunk == nil {
+			hw.before = append(hw.before, content)
+			if len(hw.before) > hw.context {
+				hw.before = hw.before[1:]
+			}
+			return
+		}
+		hw.hunk.lines = append(hw.hunk.lines, line{kind, content})
+		hw.trailing++
+		if hw.trailing > 2*hw.context {
+			// The hunk has ended: keep the last lines as the context
+			// of the next.
+			lines := hw.hunk.lines
+			hw.hunk.lines = lines[:len(lines)-hw.trailing+hw.context]
+			for _, l := range lines[len(lines)-hw.context:] {
+				hw.before = append(hw.before, l.content)
+			}
+			hw.trailing = hw.context
+			hw.flush()
+		}
+		return
+	}
+
+	if hw.hunk != nil && len(hw.hunk.lines) >= hw.maxLines && hw.trailing == 0 {
+		hw.flush() // divide a large hunk
+	}
+	if hw.hunk == nil {
+		hw.hunk = &hunk{
+			fromLine: hw.old - len(hw.before) + 1,
+			toLine:   hw.new - len(hw.before) + 1,
+		}
+		for _, l := range hw.before {
+			hw.hunk.lines = append(hw.hunk.lines, line{opEqual, l})
+		}
+		hw.before = nil
+	}
+	hw.hunk.lines = append(hw.hunk.lines, line{kind, content})
+	hw.trailing = 0
+	if kind == opDelete {
+		hw.old++
+	} else {
+		hw.new++
+	}
+}
+
+// flush writes the current hunk, if any, without more than the
+// required context.
+func (hw *hunkWriter) flush() {
+	h := hw.hunk
+	if h == nil || hw.err != nil {
+		return
+	}
+	if extra := hw.trailing - hw.context; extra > 0 {
+		h.lines = h.lines[:len(h.lines)-extra]
+	}
+	hw.hunk, hw.trailing = nil, 0
+
+	var b strings.Builder
+	if !hw.differ {
+		fmt.Fprintf(&b, "--- %s\n+++ %s\n", hw.from, hw.to)
+		hw.differ = true
+	}
+	h.format(&b, h.streamHeader())
+	_, hw.err = io.WriteString(hw.w, b.String())
+}
+
+// streamHeader returns the "@@" line of a hunk of a streamed diff.
+// Unlike hunk.header, it gives the line before an empty range, as GNU
+// diff -u does, since small windows may produce hunks without context
+// whose ranges are empty anywhere in the file.
+func (h *hunk) streamHeader() string {
+	fromStart, toStart := h.fromLine, h.toLine
+	fromCount, toCount := h.counts()
+	if fromCount == 0 {
+		fromStart--
+	}
+	if toCount == 0 {
+		toStart--
+	}
+	return fmt.Sprintf("@@ %s %s @@", hunkRange("-", fromStart, fromCount), hunkRange("+", toStart, toCount))
+}
@@ -0,0 +1,142 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diff_test
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/tenntenn/exp/toolsinternal/diff"
+	"github.com/tenntenn/exp/toolsinternal/diff/difftest"
+)
+
+// checkStream checks that the diff written by Stream transforms before
+// into after.
+func checkStream(t *testing.T, before, after string, contextLines int, opts *diff.StreamOptions) {
+	t.Helper()
+	var buf strings.Builder
+	differ, err := diff.Stream(&buf, difftest.FileA, difftest.FileB, strings.NewReader(before), strings.NewReader(after), contextLines, opts)
+	if err != nil {
+		t.Fatalf("Stream(%q, %q) failed: %v", before, after, err)
+	}
+	patch := buf.String()
+	if differ != (before != after) || differ != (patch != "") {
+		t.Fatalf("Stream(%q, %q) reports %t, with diff:\n%s", before, after, differ, patch)
+	}
+	if !differ {
+		return
+	}
+	got, res := applyPatch(t, before, patch, diff.PatchOptions{})
+	if len(res.Rejects) > 0 {
+		t.Fatalf("%d hunks rejected from diff of %q and %q:\n%s", len(res.Rejects), before, after, patch)
+	}
+	if got != after {
+		t.Fatalf("diff of %q and %q gives %q:\n%s", before, after, got, patch)
+	}
+}
+
+func TestStream(t *testing.T) {
+	for _, tc := range difftest.TestCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			checkStream(t, tc.In, tc.Out, diff.DefaultContextLines, nil)
+			checkStream(t, tc.In, tc.Out, 1, &diff.StreamOptions{Window: 2})
+		})
+	}
+
+	// Small windows divide changes and hunks.
+	rng := rand.New(rand.NewSource(1))
+	for range 1000 {
+		before := randLines(rng, "abc\n", 64)
+		after := randLines(rng, "abd\n", 64)
+		for _, window := range []int{1, 4, 16} {
+			for _, ctx := range []int{0, 1, 3} {
+				checkStream(t, before, after, ctx, &diff.StreamOptions{Window: window})
+			}
+		}
+	}
+}
+
+func TestStreamEmptyRanges(t *testing.T) {
+	// Without context, a hunk that only inserts or deletes lines has an
+	// empty range, which is given by the line before it, as in GNU diff
+	// -u, so that the hunk applies at the right place.
+	for _, test := range []struct {
+		before, after, want string
+	}{
+		{"a\nb\n", "a\nx\nb\n", "@@ -1,0 +2 @@\n+x\n"},
+		{"a\nx\nb\n", "a\nb\n", "@@ -2 +1,0 @@\n-x\n"},
+		{"a\n", "x\na\n", "@@ -0,0 +1 @@\n+x\n"},
+		{"x\na\n", "a\n", "@@ -1 +0,0 @@\n-x\n"},
+	} {
+		var buf strings.Builder
+		if _, err := diff.Stream(&buf, "a", "b", strings.NewReader(test.before), strings.NewReader(test.after), 0, nil); err != nil {
+			t.Fatal(err)
+		}
+		if want := "--- a\n+++ b\n" + test.want; buf.String() != want {
+			t.Errorf("Stream(%q, %q) wrote %q, want %q", test.before, test.after, buf.String(), want)
+		}
+		checkStream(t, test.before, test.after, 0, nil)
+	}
+}
+
+func TestStreamLarge(t *testing.T) {
+	// Changes far apart in a large input give the same diff as
+	// ToUnified.
+	var b strings.Builder
+	for i := range 100000 {
+		b.WriteString(strings.Repeat("x", i%7))
+		b.WriteString("\n")
+	}
+	before := b.String()
+	after := strings.Replace(before, "xxx\nxxxx\n", "xxx\nchanged\n", 1)
+	after = after[:len(after)/2] + "inserted\n" + after[len(after)/2:]
+	after = strings.TrimSuffix(after, "\n")
+	checkStream(t, before, after, 3, &diff.StreamOptions{Window: 100})
+
+	var buf strings.Builder
+	if _, err := diff.Stream(&buf, "a", "b", strings.NewReader(before), strings.NewReader(after), 3, nil); err != nil {
+		t.Fatal(err)
+	}
+	want, err := diff.ToUnified("a", "b", before, diff.Lines(before, after, nil), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != want {
+		t.Errorf("Stream gives:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestStreamBinary(t *testing.T) {
+	for _, test := range []struct {
+		before, after string
+		want          string
+	}{
+		{"a\x00b", "a\x00c", "Binary files a and b differ\n"},
+		{"a\n", "a\n\x00", "Binary files a and b differ\n"},
+		{"a\x00b", "a\x00b", ""},
+		{strings.Repeat("a\n", 10000) + "\x00", strings.Repeat("a\n", 10000) + "b", "--- a\n+++ b\n@@ -10000,2 +10000,2 @@\n a\n-\x00\n\\ No newline at end of file\n+b\n\\ No newline at end of file\n"},
+	} {
+		var buf strings.Builder
+		differ, err := diff.Stream(&buf, "a", "b", strings.NewReader(test.before), strings.NewReader(test.after), 1, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != test.want || differ != (test.want != "") {
+			t.Errorf("Stream(%q, %q) = %t, %q, want %q", test.before, test.after, differ, got, test.want)
+		}
+	}
+}
+
+func TestStreamError(t *testing.T) {
+	errRead := errors.New("read failed")
+	var buf strings.Builder
+	_, err := diff.Stream(&buf, "a", "b", strings.NewReader("a\nb\n"), iotest.ErrReader(errRead), 3, nil)
+	if err != errRead {
+		t.Errorf("Stream with failing reader returned %v, want %v", err, errRead)
+	}
+}
@@ -203,49 +203,77 @@ func (u unified) String() string {
 	fmt.Fprintf(b, "--- %s\n", u.from)
 	fmt.Fprintf(b, "+++ %s\n", u.to)
 	for _, hunk := range u.hunks {
-		fromCount, toCount := 0, 0
-		for _, l := range hunk.lines {
-			switch l.kind {
-			case opDelete:
-				fromCount++
-			case opInsert:
-				toCount++
-			default:
-				fromCount++
-				toCount++
-			}
-		}
-		fmt.Fprint(b, "@@")
-		if fromCount > 1 {
-			fmt.Fprintf(b, " -%d,%d", hunk.fromLine, fromCount)
-		} else if hunk.fromLine == 1 && fromCount == 0 {
-			// Match odd GNU diff -u behavior adding to empty file.
-			fmt.Fprintf(b, " -0,0")
-		} else {
-			fmt.Fprintf(b, " -%d", hunk.fromLine)
+		hunk.format(b, hunk.header())
+	}
+	return b.String()
+}
+
+// format writes the standard textual form of a hunk to b,
+// with the specified "@@" line.
+func (h *hunk) format(b *strings.Builder, header string) {
+	fmt.Fprintf(b, "%s\n", header)
+	for _, l := range h.lines {
+		switch l.kind {
+		case opDelete:
+			fmt.Fprintf(b, "-%s", l.content)
+		case opInsert:
+			fmt.Fprintf(b, "+%s", l.content)
+		default:
+			fmt.Fprintf(b, " %s", l.content)
 		}
-		if toCount > 1 {
-			fmt.Fprintf(b, " +%d,%d", hunk.toLine, toCount)
-		} else if hunk.toLine == 1 && toCount == 0 {
-			// Match odd GNU diff -u behavior adding to empty file.
-			fmt.Fprintf(b, " +0,0")
-		} else {
-			fmt.Fprintf(b, " +%d", hunk.toLine)
+		if !strings.HasSuffix(l.content, "\n") {
+			fmt.Fprintf(b, "\n\\ No newline at end of file\n")
 		}
-		fmt.Fprint(b, " @@\n")
-		for _, l := range hunk.lines {
-			switch l.kind {
-			case opDelete:
-				fmt.Fprintf(b, "-%s", l.content)
-			case opInsert:
-				fmt.Fprintf(b, "+%s", l.content)
-			default:
-				fmt.Fprintf(b, " %s", l.content)
-			}
-			if !strings.HasSuffix(l.content, "\n") {
-				fmt.Fprintf(b, "\n\\ No newline at end of file\n")
-			}
+	}
+}
+
+// counts returns the numbers of lines of the hunk in each file.
+func (h *hunk) counts() (fromCount, toCount int) {
+	for _, l := range h.lines {
+		switch l.kind {
+		case opDelete:
+			fromCount++
+		case opInsert:
+			toCount++
+		default:
+			fromCount++
+			toCount++
 		}
 	}
+	return fromCount, toCount
+}
+
+// header returns the "@@" line of a hunk, without its newline.
+func (h *hunk) header() string {
+	fromCount, toCount := h.counts()
+	b := new(strings.Builder)
+	fmt.Fprint(b, "@@")
+	if fromCount > 1 {
+		fmt.Fprintf(b, " -%d,%d", h.fromLine, fromCount)
+	} else if h.fromLine == 1 && fromCount == 0 {
+		// Match odd GNU diff -u behavior adding to empty file.
+		fmt.Fprintf(b, " -0,0")
+	} else {
+		fmt.Fprintf(b, " -%d", h.fromLine)
+	}
+	if toCount > 1 {
+		fmt.Fprintf(b, " +%d,%d", h.toLine, toCount)
+	} else if h.toLine == 1 && toCount == 0 {
+		// Match odd GNU diff -u behavior adding to empty file.
+		fmt.Fprintf(b, " +0,0")
+	} else {
+		fmt.Fprintf(b, " +%d", h.toLine)
+	}
+	fmt.Fprint(b, " @@")
 	return b.String()
 }
+
+// hunkRange formats a range of lines of a hunk header, such as "-3,2",
+// from its start and number of lines. As in [Hunk], an empty range
+// starts at the line before it.
+func hunkRange(sign string, start, count int) string {
+	if count == 1 {
+		return fmt.Sprintf("%s%d", sign, start)
+	}
+	return fmt.Sprintf("%s%d,%d", sign, start, count)
+}
@@ -6,33 +6,45 @@
 package edit
 
 import (
+	"bytes"
 	"fmt"
+	"slices"
 	"sort"
 )
 
 // A Buffer is a queue of edits to apply to a given byte slice.
 type Buffer struct {
 	old []byte
-	q   edits
+	q   []Edit // sorted by Start, then End, then order of addition
 }
 
-// An edit records a single text modification: change the bytes in [start,end) to new.
-type edit struct {
-	start int
-	end   int
-	new   string
-}
+// An Edit records a single text modification: change the bytes in [Start,End) to New.
+type Edit struct {
+	Start, End int
+	New        string
 
-// An edits is a list of edits that is sortable by start offset, breaking ties by end offset.
-type edits []edit
+	// Source identifies the origin of the edit, such as the name of
+	// the analyzer that suggested it. It is empty for the edits of
+	// Insert, Delete and Replace.
+	Source string
+}
 
-func (x edits) Len() int      { return len(x) }
-func (x edits) Swap(i, j int) { x[i], x[j] = x[j], x[i] }
-func (x edits) Less(i, j int) bool {
-	if x[i].start != x[j].start {
-		return x[i].start < x[j].start
+func (e Edit) String() string {
+	s := fmt.Sprintf("[%d,%d)->%q", e.Start, e.End, e.New)
+	if e.Source != "" {
+		s += " (" + e.Source + ")"
 	}
-	return x[i].end < x[j].end
+	return s
+}
+
+// A ConflictError reports an edit that overlaps one already in a Buffer.
+type ConflictError struct {
+	Edit     Edit // the rejected edit
+	Existing Edit // the edit it overlaps
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("overlapping edits: %v, %v", e.Existing, e.Edit)
 }
 
 // NewBuffer returns a new buffer to accumulate changes to an initial data slice.
@@ -43,47 +55,110 @@ func NewBuffer(old []byte) *Buffer {
 }
 
 // Insert inserts the new string at old[pos:pos].
+// It panics if the insertion falls within text that is deleted or replaced.
 func (b *Buffer) Insert(pos int, new string) {
 	if pos < 0 || pos > len(b.old) {
 		panic("invalid edit position")
 	}
-	b.q = append(b.q, edit{pos, pos, new})
+	b.mustAdd(Edit{Start: pos, End: pos, New: new})
 }
 
 // Delete deletes the text old[start:end].
+// It panics if the text overlaps text that is already deleted or replaced.
 func (b *Buffer) Delete(start, end int) {
 	if end < start || start < 0 || end > len(b.old) {
 		panic("invalid edit position")
 	}
-	b.q = append(b.q, edit{start, end, ""})
+	b.mustAdd(Edit{Start: start, End: end})
 }
 
 // Replace replaces old[start:end] with new.
+// It panics if the text overlaps text that is already deleted or replaced.
 func (b *Buffer) Replace(start, end int, new string) {
 	if end < start || start < 0 || end > len(b.old) {
 		panic("invalid edit position")
 	}
-	b.q = append(b.q, edit{start, end, new})
+	b.mustAdd(Edit{Start: start, End: end, New: new})
+}
+
+func (b *Buffer) mustAdd(e Edit) {
+	if err := b.add(e, false); err != nil {
+		panic(err)
+	}
+}
+
+// Add replaces old[start:end] with new, recording the source of the edit.
+// Insertions at the same position are applied in the order they are
+// added, and before a replacement of the text that starts there.
+//
+// Add is intended for combining the edits of independent sources, such
+// as several analyzers, that may suggest the same change: an edit that
+// is identical to one from a different source is ignored. Otherwise, Add
+// returns a *ConflictError if the edit overlaps a previous one, and an
+// error if the range is not within the data.
+func (b *Buffer) Add(source string, start, end int, new string) error {
+	if end < start || start < 0 || end > len(b.old) {
+		return fmt.Errorf("invalid edit range [%d,%d) for data of length %d", start, end, len(b.old))
+	}
+	return b.add(Edit{Start: start, End: end, New: new, Source: source}, true)
+}
+
+// add adds an edit to the queue, unless it conflicts with another, or
+// dedup is set and it duplicates an edit from another source.
+func (b *Buffer) add(e Edit, dedup bool) error {
+	// Find the position after all edits with the same range.
+	i := sort.Search(len(b.q), func(i int) bool {
+		q := b.q[i]
+		return q.Start > e.Start || q.Start == e.Start && q.End > e.End
+	})
+	if dedup {
+		for j := i - 1; j >= 0 && b.q[j].Start == e.Start && b.q[j].End == e.End; j-- {
+			if b.q[j].New == e.New && b.q[j].Source != e.Source {
+				return nil // duplicate
+			}
+		}
+	}
+	if i > 0 && b.q[i-1].End > e.Start {
+		return &ConflictError{Edit: e, Existing: b.q[i-1]}
+	}
+	if i < len(b.q) && e.End > b.q[i].Start {
+		return &ConflictError{Edit: e, Existing: b.q[i]}
+	}
+	b.q = slices.Insert(b.q, i, e)
+	return nil
+}
+
+// Merge adds the edits of other, a buffer of edits to the same data, as
+// if by Add. If any edit is rejected, Merge returns its error and b is
+// unchanged.
+func (b *Buffer) Merge(other *Buffer) error {
+	if !bytes.Equal(b.old, other.old) {
+		return fmt.Errorf("cannot merge edits of different data")
+	}
+	saved := slices.Clone(b.q)
+	for _, e := range other.q {
+		if err := b.add(e, true); err != nil {
+			b.q = saved
+			return err
+		}
+	}
+	return nil
+}
+
+// Edits returns the queued edits, in the order in which they are applied.
+func (b *Buffer) Edits() []Edit {
+	return slices.Clone(b.q)
 }
 
 // Bytes returns a new byte slice containing the original data
 // with the queued edits applied.
 func (b *Buffer) Bytes() []byte {
-	// Sort edits by starting position and then by ending position.
-	// Breaking ties by ending position allows insertions at point x
-	// to be applied before a replacement of the text at [x, y).
-	sort.Stable(b.q)
-
 	var new []byte
 	offset := 0
-	for i, e := range b.q {
-		if e.start < offset {
-			e0 := b.q[i-1]
-			panic(fmt.Sprintf("overlapping edits: [%d,%d)->%q, [%d,%d)->%q", e0.start, e0.end, e0.new, e.start, e.end, e.new))
-		}
-		new = append(new, b.old[offset:e.start]...)
-		offset = e.end
-		new = append(new, e.new...)
+	for _, e := range b.q {
+		new = append(new, b.old[offset:e.Start]...)
+		offset = e.End
+		new = append(new, e.New...)
 	}
 	new = append(new, b.old[offset:]...)
 	return new
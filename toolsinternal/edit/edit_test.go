@@ -4,7 +4,10 @@
 
 package edit
 
-import "testing"
+import (
+	"go/token"
+	"testing"
+)
 
 func TestEdit(t *testing.T) {
 	b := NewBuffer([]byte("0123456789"))
@@ -26,3 +29,130 @@ func TestEdit(t *testing.T) {
 		t.Errorf("b.Bytes() = %q, want %q", sb, want)
 	}
 }
+
+func TestConflict(t *testing.T) {
+	b := NewBuffer([]byte("0123456789"))
+	b.Replace(2, 5, "x")
+	b.Insert(2, "y") // before the replacement
+	b.Insert(5, "z") // after it
+	b.Delete(5, 7)
+
+	for _, test := range []struct {
+		start, end int
+		existing   Edit
+	}{
+		{3, 3, Edit{Start: 2, End: 5, New: "x"}},
+		{1, 3, Edit{Start: 2, End: 2, New: "y"}},
+		{2, 5, Edit{Start: 2, End: 5, New: "x"}},
+		{6, 8, Edit{Start: 5, End: 7}},
+	} {
+		err := b.Add("test", test.start, test.end, "w")
+		cerr, ok := err.(*ConflictError)
+		if !ok {
+			t.Errorf("Add(%d, %d) returned %v, want conflict", test.start, test.end, err)
+			continue
+		}
+		if cerr.Existing != test.existing {
+			t.Errorf("Add(%d, %d) conflicts with %v, want %v", test.start, test.end, cerr.Existing, test.existing)
+		}
+	}
+	if err := b.Add("test", 9, 11, ""); err == nil {
+		t.Errorf("Add of invalid range succeeded")
+	}
+
+	defer func() {
+		want := `overlapping edits: [5,7)->"", [6,6)->"w"`
+		if r := recover(); r == nil || r.(error).Error() != want {
+			t.Errorf("Insert(6) panicked with %v, want %q", r, want)
+		}
+	}()
+	b.Insert(6, "w")
+}
+
+func TestMerge(t *testing.T) {
+	data := []byte("func f() { return }")
+	b1 := NewBuffer(data)
+	b1.Add("a", 7, 7, "x int")
+	b1.Add("a", 17, 17, " x")
+	b2 := NewBuffer(data)
+	b2.Add("b", 7, 7, "x int") // same as a
+	b2.Add("b", 8, 8, " int")
+	if err := b1.Merge(b2); err != nil {
+		t.Fatal(err)
+	}
+	want := "func f(x int) int { return x }"
+	if got := b1.String(); got != want {
+		t.Errorf("merged edits give %q, want %q", got, want)
+	}
+
+	// A conflicting merge leaves the buffer unchanged.
+	b3 := NewBuffer(data)
+	b3.Add("c", 0, 0, "//")
+	b3.Add("c", 16, 18, "panic()")
+	if err := b1.Merge(b3); err == nil {
+		t.Errorf("conflicting Merge succeeded")
+	}
+	if got := b1.String(); got != want {
+		t.Errorf("after failed Merge, edits give %q, want %q", got, want)
+	}
+}
+
+func TestMapper(t *testing.T) {
+	// old: 0123456789
+	// new: 01ab456xyz9!
+	b := NewBuffer([]byte("0123456789"))
+	b.Replace(2, 4, "ab")
+	b.Insert(7, "x")
+	b.Replace(7, 9, "yz")
+	b.Delete(9, 10)
+	b.Insert(10, "9!")
+	m := b.Mapper()
+	b.Delete(0, 1) // not seen by m
+
+	for _, test := range []struct {
+		old, new int
+		ok       bool
+	}{
+		{0, 0, true},
+		{2, 2, true},
+		{3, 2, false},
+		{4, 4, true},
+		{7, 8, true}, // after insertion
+		{8, 8, false},
+		{9, 10, true},
+		{10, 12, true},
+	} {
+		if got, ok := m.Forward(test.old); got != test.new || ok != test.ok {
+			t.Errorf("Forward(%d) = %d, %t, want %d, %t", test.old, got, ok, test.new, test.ok)
+		}
+	}
+	for _, test := range []struct {
+		new, old int
+		ok       bool
+	}{
+		{0, 0, true},
+		{2, 2, true},
+		{3, 2, false},
+		{4, 4, true},
+		{7, 7, true},
+		{8, 7, true}, // between insertion and replacement
+		{9, 7, false},
+		{10, 10, true}, // after deletion, before insertion
+		{11, 10, false},
+		{12, 10, true},
+	} {
+		if got, ok := m.Backward(test.new); got != test.old || ok != test.ok {
+			t.Errorf("Backward(%d) = %d, %t, want %d, %t", test.new, got, ok, test.old, test.ok)
+		}
+	}
+
+	fset := token.NewFileSet()
+	from := fset.AddFile("old", -1, 10)
+	to := fset.AddFile("new", -1, 12)
+	if pos, ok := m.ForwardPos(from, to, from.Pos(4)); pos != to.Pos(4) || !ok {
+		t.Errorf("ForwardPos(4) = %d, %t, want %d, true", to.Offset(pos), ok, 4)
+	}
+	if pos, ok := m.BackwardPos(from, to, to.Pos(12)); pos != from.Pos(10) || !ok {
+		t.Errorf("BackwardPos(12) = %d, %t, want %d, true", from.Offset(pos), ok, 10)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package edit
+
+import (
+	"go/token"
+	"sort"
+)
+
+// A PositionMapper translates byte offsets between the original data of
+// a Buffer and the result of its edits.
+//
+// An offset maps exactly unless it lies strictly within text that the
+// edits delete or replace, or within text that they insert. In that
+// case it maps to the start of the corresponding text on the other side.
+// An offset at which text is inserted maps forward to the end of the
+// insertion, so that a position just before a token follows any text
+// inserted before the token.
+type PositionMapper struct {
+	edits []Edit
+	delta []int // delta[i] is the change in length due to edits[:i]
+}
+
+// Mapper returns a PositionMapper for the edits currently in the buffer.
+// Later edits do not affect it.
+func (b *Buffer) Mapper() *PositionMapper {
+	m := &PositionMapper{
+		edits: b.Edits(),
+		delta: make([]int, len(b.q)+1),
+	}
+	for i, e := range m.edits {
+		m.delta[i+1] = m.delta[i] + len(e.New) - (e.End - e.Start)
+	}
+	return m
+}
+
+// Forward maps an offset in the original data to the corresponding
+// offset in the result, and reports whether it maps exactly.
+func (m *PositionMapper) Forward(offset int) (int, bool) {
+	// The ends of the edits are in increasing order, as they do not
+	// overlap.
+	i := sort.Search(len(m.edits), func(i int) bool { return m.edits[i].End > offset })
+	if i < len(m.edits) && m.edits[i].Start < offset {
+		return m.edits[i].Start + m.delta[i], false
+	}
+	return offset + m.delta[i], true
+}
+
+// Backward maps an offset in the result to the corresponding offset in
+// the original data, and reports whether it maps exactly.
+func (m *PositionMapper) Backward(offset int) (int, bool) {
+	i := sort.Search(len(m.edits), func(i int) bool {
+		e := m.edits[i]
+		return e.Start+m.delta[i]+len(e.New) > offset
+	})
+	if i < len(m.edits) && m.edits[i].Start+m.delta[i] < offset {
+		return m.edits[i].Start, false
+	}
+	return offset - m.delta[i], true
+}
+
+// ForwardPos maps a position in from, the file of the original data, to
+// the corresponding position in to, the file of the result, as if by
+// Forward.
+func (m *PositionMapper) ForwardPos(from, to *token.File, pos token.Pos) (token.Pos, bool) {
+	offset, ok := m.Forward(from.Offset(pos))
+	return to.Pos(offset), ok
+}
+
+// BackwardPos maps a position in to, the file of the result, to the
+// corresponding position in from, the file of the original data, as if
+// by Backward.
+func (m *PositionMapper) BackwardPos(from, to *token.File, pos token.Pos) (token.Pos, bool) {
+	offset, ok := m.Backward(to.Offset(pos))
+	return from.Pos(offset), ok
+}
@@ -0,0 +1,77 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eventtest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/tenntenn/exp/toolsinternal/event"
+	"github.com/tenntenn/exp/toolsinternal/event/core"
+	"github.com/tenntenn/exp/toolsinternal/event/label"
+)
+
+// Clock is a manually advanced clock for driving time dependent exporters
+// deterministically.
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewClock returns a clock that reads start until it is advanced.
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start}
+}
+
+// Now returns the current time of the clock.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Exporter returns an exporter that stamps each event with the current time
+// of the clock before passing it to output.
+func (c *Clock) Exporter(output event.Exporter) event.Exporter {
+	return func(ctx context.Context, ev core.Event, lm label.Map) context.Context {
+		return output(ctx, core.CloneEvent(ev, c.Now()), lm)
+	}
+}
+
+// Recorder is an exporter that records the events it is given.
+type Recorder struct {
+	mu     sync.Mutex
+	events []core.Event
+}
+
+// ProcessEvent records ev.
+func (r *Recorder) ProcessEvent(ctx context.Context, ev core.Event, lm label.Map) context.Context {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, ev)
+	return ctx
+}
+
+// Events returns the events recorded so far, in order of delivery.
+func (r *Recorder) Events() []core.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]core.Event(nil), r.events...)
+}
+
+// Reset discards the recorded events.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = nil
+}
@@ -0,0 +1,483 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package otlp exports spans and metrics in the OpenTelemetry protocol
+// (OTLP), using its JSON encoding.
+//
+// The exporter relies on other exporters earlier in the chain: spans are
+// only available if events pass through [export.Spans], and metrics only if
+// they pass through a [metric.Config] exporter.
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tenntenn/exp/toolsinternal/event"
+	"github.com/tenntenn/exp/toolsinternal/event/core"
+	"github.com/tenntenn/exp/toolsinternal/event/export"
+	"github.com/tenntenn/exp/toolsinternal/event/export/metric"
+	"github.com/tenntenn/exp/toolsinternal/event/keys"
+	"github.com/tenntenn/exp/toolsinternal/event/label"
+)
+
+// Options configures an Exporter.
+type Options struct {
+	// Service is reported as the "service.name" resource attribute.
+	Service string
+
+	// Scope is reported as the instrumentation scope name.
+	// If empty, the import path of this package is used.
+	Scope string
+
+	// BatchSize is the number of finished spans that triggers an export.
+	// If zero, a default of 512 is used.
+	BatchSize int
+
+	// Interval, if positive, is the maximum time that finished spans are
+	// buffered before being exported. Metrics are also exported at each
+	// interval. If zero, spans are exported only when a batch is full, and
+	// metrics only on Flush.
+	Interval time.Duration
+
+	// OnError, if set, is called with errors from exports that happen in the
+	// background. Errors from Flush and Close are returned directly.
+	OnError func(error)
+}
+
+const defaultBatchSize = 512
+
+// Exporter converts events into OTLP export requests and delivers them to a
+// Sink.
+type Exporter struct {
+	sink  Sink
+	opts  Options
+	start time.Time // start time for cumulative metrics
+
+	mu      sync.Mutex
+	spans   []*Span                // finished spans awaiting export
+	metrics map[string]metric.Data // latest data for each metric, by handle
+
+	batches chan []*Span  // full batches, consumed by the worker
+	stop    chan struct{} // closed by Close
+	done    chan struct{} // closed when the worker exits
+	closeMu sync.Once
+}
+
+// New returns an Exporter that delivers export requests to sink.
+// The exporter must be closed when it is no longer needed.
+func New(sink Sink, opts Options) *Exporter {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	if opts.Scope == "" {
+		opts.Scope = "github.com/tenntenn/exp/toolsinternal/event/export/otlp"
+	}
+	e := &Exporter{
+		sink:    sink,
+		opts:    opts,
+		start:   time.Now(),
+		metrics: make(map[string]metric.Data),
+		batches: make(chan []*Span, 4),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+// run exports full batches as they become available, and everything that is
+// pending at each interval.
+func (e *Exporter) run() {
+	defer close(e.done)
+	var tick <-chan time.Time
+	if e.opts.Interval > 0 {
+		ticker := time.NewTicker(e.opts.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	ctx := context.Background()
+	for {
+		select {
+		case batch := <-e.batches:
+			e.report(e.sendSpans(ctx, batch))
+		case <-tick:
+			e.report(e.Flush(ctx))
+		case <-e.stop:
+			return
+		}
+	}
+}
+
+func (e *Exporter) report(err error) {
+	if err != nil && e.opts.OnError != nil {
+		e.opts.OnError(err)
+	}
+}
+
+// ProcessEvent implements event.Exporter.
+// It records spans when they end, and the latest value of each metric.
+func (e *Exporter) ProcessEvent(ctx context.Context, ev core.Event, lm label.Map) context.Context {
+	switch {
+	case event.IsEnd(ev):
+		span := export.GetSpan(ctx)
+		if span == nil {
+			return ctx
+		}
+		s := convertSpan(span)
+		e.mu.Lock()
+		e.spans = append(e.spans, s)
+		var batch []*Span
+		if len(e.spans) >= e.opts.BatchSize {
+			batch, e.spans = e.spans, nil
+		}
+		e.mu.Unlock()
+		if batch != nil {
+			select {
+			case e.batches <- batch:
+			case <-e.stop:
+			}
+		}
+
+	case event.IsMetric(ev):
+		entries, _ := metric.Entries.Get(lm).([]metric.Data)
+		if len(entries) == 0 {
+			return ctx
+		}
+		e.mu.Lock()
+		for _, data := range entries {
+			e.metrics[data.Handle()] = data
+		}
+		e.mu.Unlock()
+	}
+	return ctx
+}
+
+// Flush synchronously exports all finished spans and the current value of
+// all metrics.
+func (e *Exporter) Flush(ctx context.Context) error {
+	e.mu.Lock()
+	spans := e.spans
+	e.spans = nil
+	metrics := make([]metric.Data, 0, len(e.metrics))
+	for _, data := range e.metrics {
+		metrics = append(metrics, data)
+	}
+	e.mu.Unlock()
+	sort.Slice(metrics, func(i, j int) bool {
+		return metrics[i].Handle() < metrics[j].Handle()
+	})
+	return errors.Join(
+		e.sendSpans(ctx, spans),
+		e.sendMetrics(ctx, metrics),
+	)
+}
+
+// Close stops background exports, and flushes any remaining telemetry.
+func (e *Exporter) Close() error {
+	e.closeMu.Do(func() { close(e.stop) })
+	<-e.done
+	// Drain any batches that were queued but not yet sent.
+	var errs []error
+	for drained := false; !drained; {
+		select {
+		case batch := <-e.batches:
+			errs = append(errs, e.sendSpans(context.Background(), batch))
+		default:
+			drained = true
+		}
+	}
+	errs = append(errs, e.Flush(context.Background()))
+	return errors.Join(errs...)
+}
+
+func (e *Exporter) resource() *Resource {
+	r := &Resource{}
+	if e.opts.Service != "" {
+		r.Attributes = append(r.Attributes, stringAttr("service.name", e.opts.Service))
+	}
+	return r
+}
+
+func (e *Exporter) sendSpans(ctx context.Context, spans []*Span) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	return e.send(ctx, Traces, &TracesData{
+		ResourceSpans: []*ResourceSpans{{
+			Resource: e.resource(),
+			ScopeSpans: []*ScopeSpans{{
+				Scope: &Scope{Name: e.opts.Scope},
+				Spans: spans,
+			}},
+		}},
+	})
+}
+
+func (e *Exporter) sendMetrics(ctx context.Context, data []metric.Data) error {
+	var metrics []*Metric
+	for _, d := range data {
+		if m := e.convertMetric(d); m != nil {
+			metrics = append(metrics, m)
+		}
+	}
+	if len(metrics) == 0 {
+		return nil
+	}
+	return e.send(ctx, Metrics, &MetricsData{
+		ResourceMetrics: []*ResourceMetrics{{
+			Resource: e.resource(),
+			ScopeMetrics: []*ScopeMetrics{{
+				Scope:   &Scope{Name: e.opts.Scope},
+				Metrics: metrics,
+			}},
+		}},
+	})
+}
+
+func (e *Exporter) send(ctx context.Context, signal Signal, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encoding %s: %v", signal, err)
+	}
+	return e.sink.Send(ctx, signal, data)
+}
+
+// convertSpan converts a finished span to its OTLP form.
+//
+// The attributes of the span are the labels of its start event, and of any
+// label events delivered while it was active. Log events become span events,
+// and the span status is an error if any of them was an error.
+func convertSpan(span *export.Span) *Span {
+	start, finish := span.Start(), span.Finish()
+	s := &Span{
+		TraceID:           span.ID.TraceID.String(),
+		SpanID:            span.ID.SpanID.String(),
+		Name:              span.Name,
+		Kind:              SpanKindInternal,
+		StartTimeUnixNano: unixNano(start.At()),
+		EndTimeUnixNano:   unixNano(finish.At()),
+		Attributes:        attributes(start, keys.Start),
+	}
+	if span.ParentID.IsValid() {
+		s.ParentSpanID = span.ParentID.String()
+	}
+	for _, ev := range span.Events() {
+		switch {
+		case event.IsLabel(ev):
+			s.Attributes = append(s.Attributes, attributes(ev, keys.Label)...)
+		case event.IsLog(ev):
+			s.Events = append(s.Events, &SpanEvent{
+				TimeUnixNano: unixNano(ev.At()),
+				Name:         keys.Msg.Get(ev),
+				Attributes:   attributes(ev, keys.Msg),
+			})
+			if err := keys.Err.Get(ev); err != nil {
+				s.Status = &Status{Code: StatusError, Message: err.Error()}
+			}
+		}
+	}
+	return s
+}
+
+// convertMetric converts metric data to its OTLP form, or returns nil if the
+// data is of an unknown type.
+func (e *Exporter) convertMetric(data metric.Data) *Metric {
+	start := unixNano(e.start)
+	switch data := data.(type) {
+	case *metric.Int64Data:
+		m := &Metric{Name: data.Info.Name, Description: data.Info.Description}
+		var points []*NumberDataPoint
+		for i, group := range data.Groups() {
+			v := Int64(data.Rows[i])
+			points = append(points, &NumberDataPoint{
+				Attributes:        groupAttributes(group),
+				StartTimeUnixNano: start,
+				TimeUnixNano:      unixNano(data.EndTime),
+				AsInt:             &v,
+			})
+		}
+		scalarMetric(m, points, data.IsGauge)
+		return m
+
+	case *metric.Float64Data:
+		m := &Metric{Name: data.Info.Name, Description: data.Info.Description}
+		var points []*NumberDataPoint
+		for i, group := range data.Groups() {
+			v := data.Rows[i]
+			points = append(points, &NumberDataPoint{
+				Attributes:        groupAttributes(group),
+				StartTimeUnixNano: start,
+				TimeUnixNano:      unixNano(data.EndTime),
+				AsDouble:          &v,
+			})
+		}
+		scalarMetric(m, points, data.IsGauge)
+		return m
+
+	case *metric.HistogramInt64Data:
+		bounds := make([]float64, len(data.Info.Buckets))
+		for i, b := range data.Info.Buckets {
+			bounds[i] = float64(b)
+		}
+		h := &Histogram{AggregationTemporality: AggregationTemporalityCumulative}
+		for i, group := range data.Groups() {
+			row := data.Rows[i]
+			sum, min, max := float64(row.Sum), float64(row.Min), float64(row.Max)
+			h.DataPoints = append(h.DataPoints, &HistogramDataPoint{
+				Attributes:        groupAttributes(group),
+				StartTimeUnixNano: start,
+				TimeUnixNano:      unixNano(data.EndTime),
+				Count:             Uint64(row.Count),
+				Sum:               &sum,
+				BucketCounts:      bucketCounts(row.Values, row.Count),
+				ExplicitBounds:    bounds,
+				Min:               &min,
+				Max:               &max,
+			})
+		}
+		return &Metric{Name: data.Info.Name, Description: data.Info.Description, Histogram: h}
+
+	case *metric.HistogramFloat64Data:
+		h := &Histogram{AggregationTemporality: AggregationTemporalityCumulative}
+		for i, group := range data.Groups() {
+			row := data.Rows[i]
+			sum, min, max := row.Sum, row.Min, row.Max
+			h.DataPoints = append(h.DataPoints, &HistogramDataPoint{
+				Attributes:        groupAttributes(group),
+				StartTimeUnixNano: start,
+				TimeUnixNano:      unixNano(data.EndTime),
+				Count:             Uint64(row.Count),
+				Sum:               &sum,
+				BucketCounts:      bucketCounts(row.Values, row.Count),
+				ExplicitBounds:    data.Info.Buckets,
+				Min:               &min,
+				Max:               &max,
+			})
+		}
+		return &Metric{Name: data.Info.Name, Description: data.Info.Description, Histogram: h}
+	}
+	return nil
+}
+
+// scalarMetric sets the data of m to a gauge or a monotonic sum.
+func scalarMetric(m *Metric, points []*NumberDataPoint, isGauge bool) {
+	if isGauge {
+		for _, p := range points {
+			p.StartTimeUnixNano = 0
+		}
+		m.Gauge = &Gauge{DataPoints: points}
+		return
+	}
+	m.Sum = &Sum{
+		DataPoints:             points,
+		AggregationTemporality: AggregationTemporalityCumulative,
+		IsMonotonic:            true,
+	}
+}
+
+// bucketCounts converts the cumulative bucket counts of a metric row into
+// the per-bucket counts used by OTLP, including the overflow bucket.
+func bucketCounts(cumulative []int64, count int64) []Uint64 {
+	counts := make([]Uint64, len(cumulative)+1)
+	var prev int64
+	for i, c := range cumulative {
+		counts[i] = Uint64(c - prev)
+		prev = c
+	}
+	counts[len(cumulative)] = Uint64(count - prev)
+	return counts
+}
+
+func unixNano(t time.Time) Uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	return Uint64(t.UnixNano())
+}
+
+// attributes returns the attributes for the labels of a list, ignoring the
+// labels with the given marker key.
+func attributes(list label.List, marker label.Key) []*KeyValue {
+	var attrs []*KeyValue
+	for i := 0; list.Valid(i); i++ {
+		l := list.Label(i)
+		if !l.Valid() || l.Key() == marker || l.Key() == keys.Err {
+			continue
+		}
+		if kv := attribute(l); kv != nil {
+			attrs = append(attrs, kv)
+		}
+	}
+	return attrs
+}
+
+func groupAttributes(group []label.Label) []*KeyValue {
+	var attrs []*KeyValue
+	for _, l := range group {
+		if !l.Valid() {
+			continue
+		}
+		if kv := attribute(l); kv != nil {
+			attrs = append(attrs, kv)
+		}
+	}
+	return attrs
+}
+
+// attribute converts a label to an attribute, using the most specific value
+// type available for its key. It returns nil for labels that carry no value,
+// such as those of tag keys.
+func attribute(l label.Label) *KeyValue {
+	kv := &KeyValue{Key: l.Key().Name()}
+	switch key := l.Key().(type) {
+	case *keys.Tag:
+		return nil
+	case *keys.String:
+		s := key.From(l)
+		kv.Value.StringValue = &s
+	case *keys.Int, *keys.Int8, *keys.Int16, *keys.Int32, *keys.Int64:
+		// All integer keys pack their value into 64 bits, with signed
+		// values sign-extended.
+		i := Int64(l.Unpack64())
+		kv.Value.IntValue = &i
+	case *keys.UInt, *keys.UInt8, *keys.UInt16, *keys.UInt32, *keys.UInt64:
+		// OTLP integers are signed, so unsigned values that do not fit
+		// are sent as decimal strings.
+		if u := l.Unpack64(); u > math.MaxInt64 {
+			s := strconv.FormatUint(u, 10)
+			kv.Value.StringValue = &s
+		} else {
+			i := Int64(u)
+			kv.Value.IntValue = &i
+		}
+	case *keys.Float32:
+		f := float64(key.From(l))
+		kv.Value.DoubleValue = &f
+	case *keys.Float64:
+		f := key.From(l)
+		kv.Value.DoubleValue = &f
+	case *keys.Boolean:
+		b := key.From(l)
+		kv.Value.BoolValue = &b
+	default:
+		var buf bytes.Buffer
+		key.Format(&buf, nil, l)
+		s := buf.String()
+		kv.Value.StringValue = &s
+	}
+	return kv
+}
+
+func stringAttr(key, value string) *KeyValue {
+	return &KeyValue{Key: key, Value: AnyValue{StringValue: &value}}
+}
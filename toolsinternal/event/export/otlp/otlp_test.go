@@ -0,0 +1,280 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package otlp_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/tenntenn/exp/toolsinternal/event"
+	"github.com/tenntenn/exp/toolsinternal/event/export"
+	"github.com/tenntenn/exp/toolsinternal/event/export/metric"
+	"github.com/tenntenn/exp/toolsinternal/event/export/otlp"
+	"github.com/tenntenn/exp/toolsinternal/event/keys"
+	"github.com/tenntenn/exp/toolsinternal/event/label"
+)
+
+// collector is a stand-in for an OTLP/HTTP collector.
+type collector struct {
+	mu      sync.Mutex
+	traces  []*otlp.TracesData
+	metrics []*otlp.MetricsData
+	fail    bool
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+		http.Error(w, "bad content type "+ct, http.StatusUnsupportedMediaType)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fail {
+		http.Error(w, "collector unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	var err error
+	switch r.URL.Path {
+	case "/v1/traces":
+		var data otlp.TracesData
+		err = json.NewDecoder(r.Body).Decode(&data)
+		c.traces = append(c.traces, &data)
+	case "/v1/metrics":
+		var data otlp.MetricsData
+		err = json.NewDecoder(r.Body).Decode(&data)
+		c.metrics = append(c.metrics, &data)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// install sets the global exporter to deliver to e, returning a function that
+// restores the previous (nil) exporter.
+func install(e *otlp.Exporter, config *metric.Config) func() {
+	var output event.Exporter = e.ProcessEvent
+	if config != nil {
+		output = config.Exporter(output)
+	}
+	event.SetExporter(export.Spans(output))
+	return func() { event.SetExporter(nil) }
+}
+
+func TestSpans(t *testing.T) {
+	c := &collector{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	e := otlp.New(otlp.HTTPSink(srv.URL, srv.Client()), otlp.Options{
+		Service:   "test",
+		BatchSize: 2,
+	})
+	defer install(e, nil)()
+
+	method := keys.NewString("method", "")
+	size := keys.NewInt("size", "")
+	ctx := context.Background()
+	ctx, endOuter := event.Start(ctx, "outer", method.Of("get"))
+	ctx = event.Label(ctx, size.Of(3))
+	innerCtx, endInner := event.Start(ctx, "inner")
+	event.Log(innerCtx, "working", size.Of(7))
+	event.Error(innerCtx, "failed", errors.New("oops"))
+	endInner()
+	endOuter()
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.traces) != 1 {
+		t.Fatalf("got %d trace requests, want 1", len(c.traces))
+	}
+	rs := c.traces[0].ResourceSpans[0]
+	if got := *rs.Resource.Attributes[0].Value.StringValue; got != "test" {
+		t.Errorf("service.name = %q, want %q", got, "test")
+	}
+	spans := rs.ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	inner, outer := spans[0], spans[1]
+	if inner.Name != "inner" || outer.Name != "outer" {
+		t.Fatalf("got spans %q, %q; want inner, outer", inner.Name, outer.Name)
+	}
+	if inner.TraceID != outer.TraceID || len(inner.TraceID) != 32 {
+		t.Errorf("trace IDs %q, %q: want equal 32-digit hex", inner.TraceID, outer.TraceID)
+	}
+	if inner.ParentSpanID != outer.SpanID || outer.ParentSpanID != "" {
+		t.Errorf("bad parents: inner %q (want %q), outer %q (want none)", inner.ParentSpanID, outer.SpanID, outer.ParentSpanID)
+	}
+	if outer.StartTimeUnixNano == 0 || outer.EndTimeUnixNano < outer.StartTimeUnixNano {
+		t.Errorf("bad outer span times [%d, %d]", outer.StartTimeUnixNano, outer.EndTimeUnixNano)
+	}
+	if got, want := attrs(outer.Attributes), "method=get size=3"; got != want {
+		t.Errorf("outer attributes = %q, want %q", got, want)
+	}
+	if len(inner.Events) != 2 || inner.Events[0].Name != "working" || inner.Events[1].Name != "failed" {
+		t.Fatalf("bad inner events: %+v", inner.Events)
+	}
+	if got, want := attrs(inner.Events[0].Attributes), "size=7"; got != want {
+		t.Errorf("event attributes = %q, want %q", got, want)
+	}
+	if inner.Status == nil || inner.Status.Code != otlp.StatusError || inner.Status.Message != "oops" {
+		t.Errorf("inner status = %+v, want error oops", inner.Status)
+	}
+	if outer.Status != nil {
+		t.Errorf("outer status = %+v, want unset", outer.Status)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	var buf bytes.Buffer
+	e := otlp.New(otlp.WriterSink(&buf), otlp.Options{})
+
+	var (
+		route   = keys.NewString("route", "")
+		count   = keys.NewInt64("count", "")
+		load    = keys.NewFloat64("load", "")
+		latency = keys.NewInt64("latency", "")
+	)
+	config := &metric.Config{}
+	metric.Scalar{Name: "requests", Keys: []label.Key{route}}.SumInt64(config, count)
+	metric.Scalar{Name: "load", Description: "current load"}.LatestFloat64(config, load)
+	metric.HistogramInt64{Name: "latency", Buckets: []int64{10, 100}}.Record(config, latency)
+	defer install(e, config)()
+
+	ctx := context.Background()
+	event.Metric(ctx, count.Of(2), route.Of("/a"))
+	event.Metric(ctx, count.Of(3), route.Of("/a"))
+	event.Metric(ctx, count.Of(1), route.Of("/b"))
+	event.Metric(ctx, load.Of(0.5))
+	event.Metric(ctx, load.Of(0.25))
+	for _, v := range []int64{5, 50, 60, 500} {
+		event.Metric(ctx, latency.Of(v))
+	}
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var data otlp.MetricsData
+	if err := json.Unmarshal(buf.Bytes(), &data); err != nil {
+		t.Fatalf("decoding %s: %v", buf.Bytes(), err)
+	}
+	metrics := data.ResourceMetrics[0].ScopeMetrics[0].Metrics
+	var names []string
+	for _, m := range metrics {
+		names = append(names, m.Name)
+	}
+	if got, want := strings.Join(names, " "), "latency load requests"; got != want {
+		t.Fatalf("metrics = %s, want %s", got, want)
+	}
+
+	h := metrics[0].Histogram
+	if h == nil || len(h.DataPoints) != 1 {
+		t.Fatalf("latency: bad histogram %+v", h)
+	}
+	p := h.DataPoints[0]
+	if got, want := p.BucketCounts, []otlp.Uint64{1, 2, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("latency buckets = %v, want %v", got, want)
+	}
+	if p.Count != 4 || *p.Sum != 615 || *p.Min != 5 || *p.Max != 500 {
+		t.Errorf("latency: count=%d sum=%v min=%v max=%v", p.Count, *p.Sum, *p.Min, *p.Max)
+	}
+
+	g := metrics[1].Gauge
+	if g == nil || metrics[1].Description != "current load" || *g.DataPoints[0].AsDouble != 0.25 {
+		t.Errorf("load: bad gauge %+v", metrics[1])
+	}
+
+	s := metrics[2].Sum
+	if s == nil || !s.IsMonotonic || s.AggregationTemporality != otlp.AggregationTemporalityCumulative {
+		t.Fatalf("requests: bad sum %+v", metrics[2])
+	}
+	var rows []string
+	for _, p := range s.DataPoints {
+		rows = append(rows, attrs(p.Attributes)+":"+jsonString(p.AsInt))
+	}
+	if got, want := strings.Join(rows, " "), `route=/a:"5" route=/b:"1"`; got != want {
+		t.Errorf("requests = %s, want %s", got, want)
+	}
+}
+
+func TestSinkError(t *testing.T) {
+	c := &collector{fail: true}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	e := otlp.New(otlp.HTTPSink(srv.URL, nil), otlp.Options{})
+	defer install(e, nil)()
+	_, end := event.Start(context.Background(), "span")
+	end()
+	err := e.Close()
+	if err == nil || !strings.Contains(err.Error(), "collector unavailable") {
+		t.Errorf("Close() = %v, want collector error", err)
+	}
+}
+
+func TestUnsignedAttributes(t *testing.T) {
+	c := &collector{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	e := otlp.New(otlp.HTTPSink(srv.URL, srv.Client()), otlp.Options{})
+	defer install(e, nil)()
+	big := keys.NewUInt64("big", "")
+	small := keys.NewUInt64("small", "")
+	_, end := event.Start(context.Background(), "span", big.Of(math.MaxUint64), small.Of(math.MaxInt64))
+	end()
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.traces) != 1 {
+		t.Fatalf("got %d trace requests, want 1", len(c.traces))
+	}
+	// Values beyond the range of the signed OTLP integer are strings.
+	kvs := c.traces[0].ResourceSpans[0].ScopeSpans[0].Spans[0].Attributes
+	if got, want := jsonString(kvs), `[{"key":"big","value":{"stringValue":"18446744073709551615"}},{"key":"small","value":{"intValue":"9223372036854775807"}}]`; got != want {
+		t.Errorf("attributes = %s, want %s", got, want)
+	}
+}
+
+// attrs formats attributes as space-separated key=value pairs.
+func attrs(kvs []*otlp.KeyValue) string {
+	var parts []string
+	for _, kv := range kvs {
+		var v string
+		switch {
+		case kv.Value.StringValue != nil:
+			v = *kv.Value.StringValue
+		case kv.Value.IntValue != nil:
+			v = strings.Trim(jsonString(kv.Value.IntValue), `"`)
+		default:
+			v = jsonString(kv.Value)
+		}
+		parts = append(parts, kv.Key+"="+v)
+	}
+	return strings.Join(parts, " ")
+}
+
+func jsonString(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
@@ -0,0 +1,87 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Signal identifies the kind of telemetry in an export request.
+type Signal string
+
+const (
+	Traces  Signal = "traces"
+	Metrics Signal = "metrics"
+)
+
+// A Sink delivers encoded OTLP/JSON export requests.
+// Implementations must be safe for concurrent use.
+type Sink interface {
+	// Send delivers a single export request payload for the given signal.
+	Send(ctx context.Context, signal Signal, payload []byte) error
+}
+
+// WriterSink returns a Sink that writes each payload to w as a single line.
+// This is the format of the OpenTelemetry collector's file exporter and
+// receiver.
+func WriterSink(w io.Writer) Sink {
+	return &writerSink{w: w}
+}
+
+type writerSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *writerSink) Send(ctx context.Context, signal Signal, payload []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.w.Write(payload); err != nil {
+		return err
+	}
+	_, err := io.WriteString(s.w, "\n")
+	return err
+}
+
+// HTTPSink returns a Sink that posts payloads to an OTLP/HTTP endpoint,
+// such as "http://localhost:4318". Traces are sent to endpoint/v1/traces and
+// metrics to endpoint/v1/metrics.
+// If client is nil, http.DefaultClient is used.
+func HTTPSink(endpoint string, client *http.Client) Sink {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &httpSink{endpoint: strings.TrimSuffix(endpoint, "/"), client: client}
+}
+
+type httpSink struct {
+	endpoint string
+	client   *http.Client
+}
+
+func (s *httpSink) Send(ctx context.Context, signal Signal, payload []byte) error {
+	url := s.endpoint + "/v1/" + string(signal)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("exporting %s to %s: %s: %s", signal, url, resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
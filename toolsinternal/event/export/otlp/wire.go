@@ -0,0 +1,220 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package otlp
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// This file contains the Go forms of the OTLP/JSON wire format.
+// See https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding and
+// the opentelemetry-proto repository for details.
+//
+// Only the subset of the protocol used by the exporter is represented.
+// As required by the protobuf JSON mapping, 64-bit integers are encoded as
+// decimal strings, and trace and span IDs as lowercase hex strings.
+
+// TracesData is the payload of an OTLP/JSON trace export request.
+type TracesData struct {
+	ResourceSpans []*ResourceSpans `json:"resourceSpans"`
+}
+
+// ResourceSpans is a collection of spans from a single resource.
+type ResourceSpans struct {
+	Resource   *Resource     `json:"resource,omitempty"`
+	ScopeSpans []*ScopeSpans `json:"scopeSpans"`
+}
+
+// ScopeSpans is a collection of spans from a single instrumentation scope.
+type ScopeSpans struct {
+	Scope *Scope  `json:"scope,omitempty"`
+	Spans []*Span `json:"spans"`
+}
+
+// Span is a single operation within a trace.
+type Span struct {
+	TraceID           string       `json:"traceId"`
+	SpanID            string       `json:"spanId"`
+	ParentSpanID      string       `json:"parentSpanId,omitempty"`
+	Name              string       `json:"name"`
+	Kind              SpanKind     `json:"kind"`
+	StartTimeUnixNano Uint64       `json:"startTimeUnixNano"`
+	EndTimeUnixNano   Uint64       `json:"endTimeUnixNano"`
+	Attributes        []*KeyValue  `json:"attributes,omitempty"`
+	Events            []*SpanEvent `json:"events,omitempty"`
+	Status            *Status      `json:"status,omitempty"`
+}
+
+// SpanKind is the type of a span.
+type SpanKind int
+
+// SpanKindInternal indicates an internal operation within an application.
+// It is the only kind produced by the exporter.
+const SpanKindInternal SpanKind = 1
+
+// SpanEvent is a time-stamped annotation of a span.
+type SpanEvent struct {
+	TimeUnixNano Uint64      `json:"timeUnixNano"`
+	Name         string      `json:"name"`
+	Attributes   []*KeyValue `json:"attributes,omitempty"`
+}
+
+// Status is the final status of a span.
+type Status struct {
+	Message string     `json:"message,omitempty"`
+	Code    StatusCode `json:"code,omitempty"`
+}
+
+// StatusCode is the status of a completed span.
+type StatusCode int
+
+const (
+	StatusUnset StatusCode = 0
+	StatusOK    StatusCode = 1
+	StatusError StatusCode = 2
+)
+
+// MetricsData is the payload of an OTLP/JSON metrics export request.
+type MetricsData struct {
+	ResourceMetrics []*ResourceMetrics `json:"resourceMetrics"`
+}
+
+// ResourceMetrics is a collection of metrics from a single resource.
+type ResourceMetrics struct {
+	Resource     *Resource       `json:"resource,omitempty"`
+	ScopeMetrics []*ScopeMetrics `json:"scopeMetrics"`
+}
+
+// ScopeMetrics is a collection of metrics from a single instrumentation scope.
+type ScopeMetrics struct {
+	Scope   *Scope    `json:"scope,omitempty"`
+	Metrics []*Metric `json:"metrics"`
+}
+
+// Metric is a single metric. Exactly one of Sum, Gauge and Histogram is set.
+type Metric struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Unit        string     `json:"unit,omitempty"`
+	Sum         *Sum       `json:"sum,omitempty"`
+	Gauge       *Gauge     `json:"gauge,omitempty"`
+	Histogram   *Histogram `json:"histogram,omitempty"`
+}
+
+// AggregationTemporality describes how metric values relate to time.
+type AggregationTemporality int
+
+// AggregationTemporalityCumulative indicates values accumulated since a
+// fixed start time. It is the only temporality produced by the exporter.
+const AggregationTemporalityCumulative AggregationTemporality = 2
+
+// Sum is a scalar metric that is the sum of its measurements.
+type Sum struct {
+	DataPoints             []*NumberDataPoint     `json:"dataPoints"`
+	AggregationTemporality AggregationTemporality `json:"aggregationTemporality"`
+	IsMonotonic            bool                   `json:"isMonotonic"`
+}
+
+// Gauge is a scalar metric that samples a current value.
+type Gauge struct {
+	DataPoints []*NumberDataPoint `json:"dataPoints"`
+}
+
+// NumberDataPoint is a single value of a scalar metric.
+// Exactly one of AsInt and AsDouble is set.
+type NumberDataPoint struct {
+	Attributes        []*KeyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano Uint64      `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      Uint64      `json:"timeUnixNano"`
+	AsInt             *Int64      `json:"asInt,omitempty"`
+	AsDouble          *float64    `json:"asDouble,omitempty"`
+}
+
+// Histogram is a metric that records the distribution of its measurements.
+type Histogram struct {
+	DataPoints             []*HistogramDataPoint  `json:"dataPoints"`
+	AggregationTemporality AggregationTemporality `json:"aggregationTemporality"`
+}
+
+// HistogramDataPoint is a single value of a histogram metric.
+//
+// BucketCounts holds the (non-cumulative) number of measurements in each
+// bucket; it has one more element than ExplicitBounds, the last counting
+// measurements greater than all bounds.
+type HistogramDataPoint struct {
+	Attributes        []*KeyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano Uint64      `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      Uint64      `json:"timeUnixNano"`
+	Count             Uint64      `json:"count"`
+	Sum               *float64    `json:"sum,omitempty"`
+	BucketCounts      []Uint64    `json:"bucketCounts"`
+	ExplicitBounds    []float64   `json:"explicitBounds"`
+	Min               *float64    `json:"min,omitempty"`
+	Max               *float64    `json:"max,omitempty"`
+}
+
+// Resource describes the entity producing telemetry.
+type Resource struct {
+	Attributes []*KeyValue `json:"attributes,omitempty"`
+}
+
+// Scope describes the instrumentation library producing telemetry.
+type Scope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// KeyValue is a single attribute.
+type KeyValue struct {
+	Key   string   `json:"key"`
+	Value AnyValue `json:"value"`
+}
+
+// AnyValue is an attribute value. Exactly one field is set.
+type AnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *Int64   `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+// Int64 is an int64 that is encoded in JSON as a decimal string.
+// It may be decoded from either a string or a number.
+type Int64 int64
+
+func (i Int64) MarshalJSON() ([]byte, error) {
+	return strconv.AppendQuote(nil, strconv.FormatInt(int64(i), 10)), nil
+}
+
+func (i *Int64) UnmarshalJSON(data []byte) error {
+	v, err := strconv.ParseInt(unquote(data), 10, 64)
+	*i = Int64(v)
+	return err
+}
+
+// Uint64 is a uint64 that is encoded in JSON as a decimal string.
+// It may be decoded from either a string or a number.
+type Uint64 uint64
+
+func (u Uint64) MarshalJSON() ([]byte, error) {
+	return strconv.AppendQuote(nil, strconv.FormatUint(uint64(u), 10)), nil
+}
+
+func (u *Uint64) UnmarshalJSON(data []byte) error {
+	v, err := strconv.ParseUint(unquote(data), 10, 64)
+	*u = Uint64(v)
+	return err
+}
+
+// unquote returns the contents of a JSON string, or data itself if it is
+// not a string.
+func unquote(data []byte) string {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		return s
+	}
+	return string(data)
+}
@@ -0,0 +1,338 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package prometheus
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tenntenn/exp/toolsinternal/event/export/metric"
+	"github.com/tenntenn/exp/toolsinternal/event/keys"
+	"github.com/tenntenn/exp/toolsinternal/event/label"
+)
+
+// This file implements the exposition formats.
+//
+// See https://prometheus.io/docs/instrumenting/exposition_formats/ for the
+// text format, and https://github.com/prometheus/OpenMetrics for OpenMetrics.
+// The main differences handled here are that OpenMetrics requires counter
+// samples to have a _total suffix, supports _created samples and exemplars,
+// escapes double quotes in HELP text, and terminates the exposition with
+// "# EOF".
+
+// expositionWriter writes metric families in one of the exposition formats.
+type expositionWriter struct {
+	w           io.Writer
+	openMetrics bool
+}
+
+func (ew *expositionWriter) printf(format string, args ...any) {
+	fmt.Fprintf(ew.w, format, args...)
+}
+
+// header writes the HELP and TYPE lines of a metric family.
+func (ew *expositionWriter) header(name, description, kind string) {
+	ew.printf("# HELP %s %s\n", name, ew.escapeHelp(description))
+	ew.printf("# TYPE %s %s\n", name, kind)
+}
+
+// sample writes a single sample line. The extra label, if non-empty, is
+// appended after the group labels and must already be in name="value" form.
+func (ew *expositionWriter) sample(name string, group []label.Label, extra string, value float64, ex *exemplar) {
+	var buf bytes.Buffer
+	buf.WriteString(name)
+	writeLabels(&buf, group, extra)
+	buf.WriteByte(' ')
+	buf.WriteString(formatValue(value))
+	if ex != nil && ew.openMetrics {
+		fmt.Fprintf(&buf, ` # {trace_id="%s",span_id="%s"} %s %s`,
+			ex.span.TraceID, ex.span.SpanID, formatValue(ex.value), formatTimestamp(ex.at))
+	}
+	buf.WriteByte('\n')
+	ew.w.Write(buf.Bytes())
+}
+
+// created writes the _created sample of a series, in OpenMetrics only.
+func (ew *expositionWriter) created(name string, group []label.Label, at time.Time) {
+	if ew.openMetrics && !at.IsZero() {
+		var buf bytes.Buffer
+		buf.WriteString(name + "_created")
+		writeLabels(&buf, group, "")
+		buf.WriteString(" " + formatTimestamp(at) + "\n")
+		ew.w.Write(buf.Bytes())
+	}
+}
+
+// escapeHelp escapes the text of a HELP line.
+func (ew *expositionWriter) escapeHelp(s string) string {
+	if ew.openMetrics {
+		return escapeLabelValue(s)
+	}
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+// writeMetric writes the metric family for data.
+func (e *Exporter) writeMetric(ew *expositionWriter, data metric.Data) {
+	created := e.created[data.Handle()]
+	createdAt := func(group []label.Label) time.Time { return created[seriesKey(group)] }
+
+	switch data := data.(type) {
+	case *metric.Int64Data:
+		rows := make([]float64, len(data.Rows))
+		for i, v := range data.Rows {
+			rows[i] = float64(v)
+		}
+		ew.scalar(data.Info, data.IsGauge, data.Groups(), rows, createdAt)
+
+	case *metric.Float64Data:
+		ew.scalar(data.Info, data.IsGauge, data.Groups(), data.Rows, createdAt)
+
+	case *metric.HistogramInt64Data:
+		name := metricName(data.Info.Name)
+		buckets := make([]float64, len(data.Info.Buckets))
+		for i, b := range data.Info.Buckets {
+			buckets[i] = float64(b)
+		}
+		ew.header(name, data.Info.Description, "histogram")
+		for i, group := range data.Groups() {
+			row := data.Rows[i]
+			ew.histogram(name, group, buckets, row.Values, row.Count, float64(row.Sum), e.exemplars[data.Handle()])
+			ew.created(name, group, createdAt(group))
+		}
+
+	case *metric.HistogramFloat64Data:
+		name := metricName(data.Info.Name)
+		if len(e.Quantiles) > 0 {
+			ew.header(name, data.Info.Description, "summary")
+			for i, group := range data.Groups() {
+				row := data.Rows[i]
+				for _, q := range sortedQuantiles(e.Quantiles) {
+					v := quantile(q, data.Info.Buckets, row)
+					ew.sample(name, group, `quantile="`+formatValue(q)+`"`, v, nil)
+				}
+				ew.sample(name+"_sum", group, "", row.Sum, nil)
+				ew.sample(name+"_count", group, "", float64(row.Count), nil)
+				ew.created(name, group, createdAt(group))
+			}
+			return
+		}
+		ew.header(name, data.Info.Description, "histogram")
+		for i, group := range data.Groups() {
+			row := data.Rows[i]
+			ew.histogram(name, group, data.Info.Buckets, row.Values, row.Count, row.Sum, e.exemplars[data.Handle()])
+			ew.created(name, group, createdAt(group))
+		}
+	}
+}
+
+// scalar writes a counter or gauge family.
+func (ew *expositionWriter) scalar(info *metric.Scalar, isGauge bool, groups [][]label.Label, rows []float64, createdAt func([]label.Label) time.Time) {
+	name := metricName(info.Name)
+	if isGauge {
+		ew.header(name, info.Description, "gauge")
+		for i, group := range groups {
+			ew.sample(name, group, "", rows[i], nil)
+		}
+		return
+	}
+	if !ew.openMetrics {
+		ew.header(name, info.Description, "counter")
+		for i, group := range groups {
+			ew.sample(name, group, "", rows[i], nil)
+		}
+		return
+	}
+	// In OpenMetrics, the family name of a counter excludes the _total suffix
+	// that its samples must have.
+	family := strings.TrimSuffix(name, "_total")
+	ew.header(family, info.Description, "counter")
+	for i, group := range groups {
+		ew.sample(family+"_total", group, "", rows[i], nil)
+		ew.created(family, group, createdAt(group))
+	}
+}
+
+// histogram writes the samples of a single histogram series. The values are
+// the cumulative counts for each bucket.
+func (ew *expositionWriter) histogram(name string, group []label.Label, buckets []float64, values []int64, count int64, sum float64, exemplars map[string]exemplar) {
+	exemplarFor := func(bucket int) *exemplar {
+		if ex, ok := exemplars[bucketKey(group, bucket)]; ok {
+			return &ex
+		}
+		return nil
+	}
+	for j, b := range buckets {
+		ew.sample(name+"_bucket", group, `le="`+formatValue(b)+`"`, float64(values[j]), exemplarFor(j))
+	}
+	ew.sample(name+"_bucket", group, `le="+Inf"`, float64(count), exemplarFor(len(buckets)))
+	ew.sample(name+"_count", group, "", float64(count), nil)
+	ew.sample(name+"_sum", group, "", sum, nil)
+}
+
+// quantile estimates the q-quantile of the values recorded in row, by
+// linear interpolation within the bucket containing it. The bounds of that
+// bucket are clamped to the observed minimum and maximum.
+func quantile(q float64, buckets []float64, row *metric.HistogramFloat64Row) float64 {
+	if row.Count == 0 || q < 0 || q > 1 {
+		return math.NaN()
+	}
+	rank := q * float64(row.Count)
+	interpolate := func(lower, upper, below, count float64) float64 {
+		lower, upper = max(lower, row.Min), min(upper, row.Max)
+		if upper <= lower || count <= below {
+			return upper
+		}
+		return lower + (upper-lower)*(rank-below)/(count-below)
+	}
+	lower, below := row.Min, 0.0 // lower bound of the bucket, and the count below it
+	for i, b := range buckets {
+		count := float64(row.Values[i])
+		if count >= rank {
+			return interpolate(lower, b, below, count)
+		}
+		lower, below = b, count
+	}
+	return interpolate(lower, row.Max, below, float64(row.Count))
+}
+
+// writeLabels writes the label set of a sample, if it is non-empty.
+// Labels missing from the group are omitted.
+func writeLabels(buf *bytes.Buffer, group []label.Label, extra string) {
+	first := true
+	sep := func() {
+		if first {
+			buf.WriteByte('{')
+			first = false
+		} else {
+			buf.WriteByte(',')
+		}
+	}
+	for _, l := range group {
+		if !l.Valid() {
+			continue
+		}
+		sep()
+		buf.WriteString(labelName(l.Key().Name()))
+		buf.WriteString(`="`)
+		buf.WriteString(escapeLabelValue(labelValue(l)))
+		buf.WriteByte('"')
+	}
+	if extra != "" {
+		sep()
+		buf.WriteString(extra)
+	}
+	if !first {
+		buf.WriteByte('}')
+	}
+}
+
+// labelValue returns the unquoted value of a label.
+func labelValue(l label.Label) string {
+	switch key := l.Key().(type) {
+	case *keys.String:
+		return key.From(l)
+	case *keys.Error:
+		if err := key.From(l); err != nil {
+			return err.Error()
+		}
+		return ""
+	}
+	var buf bytes.Buffer
+	l.Key().Format(&buf, nil, l)
+	return buf.String()
+}
+
+// escapeLabelValue escapes backslash, double quote and newline, as required
+// for label values in both formats.
+func escapeLabelValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// metricName returns name with any characters that are not valid in metric
+// names replaced by underscores.
+func metricName(name string) string {
+	return sanitizeName(name, true)
+}
+
+// labelName returns name with any characters that are not valid in label
+// names replaced by underscores.
+func labelName(name string) string {
+	return sanitizeName(name, false)
+}
+
+func sanitizeName(name string, allowColon bool) string {
+	if name == "" {
+		return "_"
+	}
+	b := []byte(name)
+	for i, c := range b {
+		ok := c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' ||
+			allowColon && c == ':' || i > 0 && '0' <= c && c <= '9'
+		if !ok {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+// formatValue formats a sample value. Integral values are formatted without
+// an exponent where they are exact, so that bucket bounds read naturally.
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, +1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	case v == math.Trunc(v) && math.Abs(v) <= 1<<53:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// formatTimestamp formats a time as seconds since the Unix epoch.
+func formatTimestamp(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixNano())/1e9, 'f', -1, 64)
+}
+
+// prefersOpenMetrics reports whether an Accept header prefers OpenMetrics to
+// the text format, taking quality values into account.
+func prefersOpenMetrics(accept string) bool {
+	var om, text float64
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if s, ok := params["q"]; ok {
+			if v, err := strconv.ParseFloat(s, 64); err == nil {
+				q = v
+			}
+		}
+		switch mediaType {
+		case "application/openmetrics-text":
+			om = max(om, q)
+		case "text/plain", "text/*", "*/*":
+			text = max(text, q)
+		}
+	}
+	return om > 0 && om > text
+}
+
+// sortedQuantiles returns a sorted copy of qs.
+func sortedQuantiles(qs []float64) []float64 {
+	qs = append([]float64(nil), qs...)
+	sort.Float64s(qs)
+	return qs
+}
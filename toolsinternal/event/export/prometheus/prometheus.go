@@ -2,18 +2,21 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
+// Package prometheus exposes metrics in the Prometheus text exposition
+// format and in the OpenMetrics format.
 package prometheus
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"net/http"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/tenntenn/exp/toolsinternal/event"
 	"github.com/tenntenn/exp/toolsinternal/event/core"
+	"github.com/tenntenn/exp/toolsinternal/event/export"
 	"github.com/tenntenn/exp/toolsinternal/event/export/metric"
 	"github.com/tenntenn/exp/toolsinternal/event/label"
 )
@@ -23,8 +26,28 @@ func New() *Exporter {
 }
 
 type Exporter struct {
+	// Quantiles, if non-empty, causes HistogramFloat64 metrics to be exposed
+	// as summaries reporting the given quantiles (each in [0, 1]), estimated
+	// from the bucket counts, instead of as histograms.
+	// It must be set before the exporter is used.
+	Quantiles []float64
+
 	mu      sync.Mutex
 	metrics []metric.Data
+	// created records the time each series was first observed, by metric
+	// handle and then series key.
+	created map[string]map[string]time.Time
+	// exemplars records the most recent exemplar for each histogram bucket,
+	// by metric handle and then bucket key.
+	exemplars map[string]map[string]exemplar
+}
+
+// An exemplar is a sample observed within a span, linking a histogram bucket
+// to a trace.
+type exemplar struct {
+	span  export.SpanContext
+	value float64
+	at    time.Time
 }
 
 func (e *Exporter) ProcessEvent(ctx context.Context, ev core.Event, lm label.Map) context.Context {
@@ -42,88 +65,151 @@ func (e *Exporter) ProcessEvent(ctx context.Context, ev core.Event, lm label.Map
 		index := sort.Search(len(e.metrics), func(i int) bool {
 			return e.metrics[i].Handle() >= name
 		})
+		var old metric.Data
 		if index >= len(e.metrics) || e.metrics[index].Handle() != name {
 			// we have a new metric, so we need to make a space for it
-			old := e.metrics
-			e.metrics = make([]metric.Data, len(old)+1)
-			copy(e.metrics, old[:index])
-			copy(e.metrics[index+1:], old[index:])
+			prev := e.metrics
+			e.metrics = make([]metric.Data, len(prev)+1)
+			copy(e.metrics, prev[:index])
+			copy(e.metrics[index+1:], prev[index:])
+		} else {
+			old = e.metrics[index]
 		}
 		e.metrics[index] = data
+		e.recordCreated(name, data.Groups(), ev.At())
+		if span := export.GetSpan(ctx); span != nil {
+			e.recordExemplar(old, data, span.ID, ev.At())
+		}
 	}
 	return ctx
 }
 
-func (e *Exporter) header(w http.ResponseWriter, name, description string, isGauge, isHistogram bool) {
-	kind := "counter"
-	if isGauge {
-		kind = "gauge"
+// recordCreated records the creation time of any new series of a metric.
+func (e *Exporter) recordCreated(name string, groups [][]label.Label, at time.Time) {
+	if e.created == nil {
+		e.created = make(map[string]map[string]time.Time)
 	}
-	if isHistogram {
-		kind = "histogram"
+	series := e.created[name]
+	if series == nil {
+		series = make(map[string]time.Time)
+		e.created[name] = series
+	}
+	for _, group := range groups {
+		key := seriesKey(group)
+		if _, ok := series[key]; !ok {
+			series[key] = at
+		}
 	}
-	fmt.Fprintf(w, "# HELP %s %s\n", name, description)
-	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
 }
 
-func (e *Exporter) row(w http.ResponseWriter, name string, group []label.Label, extra string, value any) {
-	fmt.Fprint(w, name)
-	buf := &bytes.Buffer{}
-	fmt.Fprint(buf, group)
-	if extra != "" {
-		if buf.Len() > 0 {
-			fmt.Fprint(buf, ",")
+// recordExemplar records an exemplar for the histogram bucket that received
+// the value recorded by the transition from old to data, if any.
+//
+// Histogram data does not expose the recorded value directly, but since each
+// event records exactly one value in one row, it is the change in that row's
+// sum.
+func (e *Exporter) recordExemplar(old, data metric.Data, span export.SpanContext, at time.Time) {
+	var (
+		value   float64
+		buckets []float64
+		row     []label.Label
+		found   bool
+	)
+	switch data := data.(type) {
+	case *metric.HistogramInt64Data:
+		prev := make(map[string]*metric.HistogramInt64Row)
+		if old, ok := old.(*metric.HistogramInt64Data); ok {
+			for i, group := range old.Groups() {
+				prev[seriesKey(group)] = old.Rows[i]
+			}
+		}
+		for i, group := range data.Groups() {
+			cur, before := data.Rows[i], prev[seriesKey(group)]
+			if before == nil {
+				before = &metric.HistogramInt64Row{}
+			}
+			if cur.Count == before.Count+1 {
+				value, row, found = float64(cur.Sum-before.Sum), group, true
+				break
+			}
+		}
+		for _, b := range data.Info.Buckets {
+			buckets = append(buckets, float64(b))
+		}
+	case *metric.HistogramFloat64Data:
+		prev := make(map[string]*metric.HistogramFloat64Row)
+		if old, ok := old.(*metric.HistogramFloat64Data); ok {
+			for i, group := range old.Groups() {
+				prev[seriesKey(group)] = old.Rows[i]
+			}
+		}
+		for i, group := range data.Groups() {
+			cur, before := data.Rows[i], prev[seriesKey(group)]
+			if before == nil {
+				before = &metric.HistogramFloat64Row{}
+			}
+			if cur.Count == before.Count+1 {
+				value, row, found = cur.Sum-before.Sum, group, true
+				break
+			}
+		}
+		buckets = data.Info.Buckets
+	}
+	if !found {
+		return
+	}
+	bucket := len(buckets) // +Inf
+	for i, b := range buckets {
+		if value <= b {
+			bucket = i
+			break
 		}
-		fmt.Fprint(buf, extra)
 	}
-	if buf.Len() > 0 {
-		fmt.Fprint(w, "{")
-		buf.WriteTo(w)
-		fmt.Fprint(w, "}")
+	if e.exemplars == nil {
+		e.exemplars = make(map[string]map[string]exemplar)
+	}
+	byBucket := e.exemplars[data.Handle()]
+	if byBucket == nil {
+		byBucket = make(map[string]exemplar)
+		e.exemplars[data.Handle()] = byBucket
 	}
-	fmt.Fprintf(w, " %v\n", value)
+	byBucket[bucketKey(row, bucket)] = exemplar{span: span, value: value, at: at}
 }
 
+// seriesKey returns a key identifying the series of a metric with the given
+// group labels.
+func seriesKey(group []label.Label) string {
+	return fmt.Sprint(group)
+}
+
+// bucketKey returns a key identifying a histogram bucket within a series.
+func bucketKey(group []label.Label, bucket int) string {
+	return fmt.Sprintf("%s\x00%d", seriesKey(group), bucket)
+}
+
+// Content types of the supported exposition formats.
+const (
+	textContentType        = "text/plain; version=0.0.4; charset=utf-8"
+	openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// Serve writes the current metrics in the format negotiated from the
+// request's Accept header: OpenMetrics if the client prefers it, and the
+// Prometheus text format otherwise.
 func (e *Exporter) Serve(w http.ResponseWriter, r *http.Request) {
+	openMetrics := prefersOpenMetrics(r.Header.Get("Accept"))
+	if openMetrics {
+		w.Header().Set("Content-Type", openMetricsContentType)
+	} else {
+		w.Header().Set("Content-Type", textContentType)
+	}
 	e.mu.Lock()
 	defer e.mu.Unlock()
+	ew := &expositionWriter{w: w, openMetrics: openMetrics}
 	for _, data := range e.metrics {
-		switch data := data.(type) {
-		case *metric.Int64Data:
-			e.header(w, data.Info.Name, data.Info.Description, data.IsGauge, false)
-			for i, group := range data.Groups() {
-				e.row(w, data.Info.Name, group, "", data.Rows[i])
-			}
-
-		case *metric.Float64Data:
-			e.header(w, data.Info.Name, data.Info.Description, data.IsGauge, false)
-			for i, group := range data.Groups() {
-				e.row(w, data.Info.Name, group, "", data.Rows[i])
-			}
-
-		case *metric.HistogramInt64Data:
-			e.header(w, data.Info.Name, data.Info.Description, false, true)
-			for i, group := range data.Groups() {
-				row := data.Rows[i]
-				for j, b := range data.Info.Buckets {
-					e.row(w, data.Info.Name+"_bucket", group, fmt.Sprintf(`le="%v"`, b), row.Values[j])
-				}
-				e.row(w, data.Info.Name+"_bucket", group, `le="+Inf"`, row.Count)
-				e.row(w, data.Info.Name+"_count", group, "", row.Count)
-				e.row(w, data.Info.Name+"_sum", group, "", row.Sum)
-			}
-
-		case *metric.HistogramFloat64Data:
-			e.header(w, data.Info.Name, data.Info.Description, false, true)
-			for i, group := range data.Groups() {
-				row := data.Rows[i]
-				for j, b := range data.Info.Buckets {
-					e.row(w, data.Info.Name+"_bucket", group, fmt.Sprintf(`le="%v"`, b), row.Values[j])
-				}
-				e.row(w, data.Info.Name+"_bucket", group, `le="+Inf"`, row.Count)
-				e.row(w, data.Info.Name+"_count", group, "", row.Count)
-				e.row(w, data.Info.Name+"_sum", group, "", row.Sum)
-			}
-		}
+		e.writeMetric(ew, data)
+	}
+	if openMetrics {
+		ew.printf("# EOF\n")
 	}
 }
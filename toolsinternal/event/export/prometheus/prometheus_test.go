@@ -0,0 +1,632 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package prometheus_test
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/tenntenn/exp/toolsinternal/event"
+	"github.com/tenntenn/exp/toolsinternal/event/export"
+	"github.com/tenntenn/exp/toolsinternal/event/export/metric"
+	"github.com/tenntenn/exp/toolsinternal/event/export/prometheus"
+	"github.com/tenntenn/exp/toolsinternal/event/keys"
+	"github.com/tenntenn/exp/toolsinternal/event/label"
+)
+
+var (
+	method  = keys.NewString("method", "")
+	code    = keys.NewInt("code", "")
+	count   = keys.NewInt64("count", "")
+	load    = keys.NewFloat64("load", "")
+	latency = keys.NewInt64("latency", "")
+	size    = keys.NewFloat64("size", "")
+)
+
+// trickyValue exercises all escapes required in label values.
+const trickyValue = "a \"quoted\" \\ back\nslash"
+
+// setup installs an exporter chain delivering to e, records a fixed set of
+// metrics, and returns a function to uninstall the chain.
+func setup(t *testing.T, e *prometheus.Exporter) {
+	config := &metric.Config{}
+	metric.Scalar{
+		Name:        "http.requests_total",
+		Description: "Requests \"served\",\nby method\\code.",
+		Keys:        []label.Key{method, code},
+	}.SumInt64(config, count)
+	metric.Scalar{Name: "load", Description: "current load"}.LatestFloat64(config, load)
+	metric.HistogramInt64{
+		Name:        "latency_ms",
+		Description: "request latency",
+		Keys:        []label.Key{method},
+		Buckets:     []int64{10, 100, 1000},
+	}.Record(config, latency)
+	metric.HistogramFloat64{
+		Name:        "size_kb",
+		Description: "response size",
+		Buckets:     []float64{1, 2, 4, 8},
+	}.Record(config, size)
+	event.SetExporter(export.Spans(config.Exporter(e.ProcessEvent)))
+	t.Cleanup(func() { event.SetExporter(nil) })
+
+	ctx := context.Background()
+	event.Metric(ctx, count.Of(1), method.Of("GET"), code.Of(200))
+	event.Metric(ctx, count.Of(2), method.Of(trickyValue), code.Of(500))
+	event.Metric(ctx, count.Of(3), method.Of("GET"), code.Of(200))
+	event.Metric(ctx, load.Of(math.Inf(1)))
+	event.Metric(ctx, load.Of(0.75))
+	spanCtx, end := event.Start(ctx, "request")
+	for _, v := range []int64{5, 50, 70, 2000} {
+		event.Metric(spanCtx, latency.Of(v), method.Of("GET"))
+	}
+	end()
+	event.Metric(ctx, latency.Of(500))
+	for _, v := range []float64{0.5, 1.5, 1.5, 3, 3, 3, 6, 7} {
+		event.Metric(ctx, size.Of(v))
+	}
+}
+
+// scrape serves the exporter's metrics with the given Accept header.
+func scrape(e *prometheus.Exporter, accept string) (contentType, body string) {
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	e.Serve(w, req)
+	return w.Header().Get("Content-Type"), w.Body.String()
+}
+
+func TestConformance(t *testing.T) {
+	e := prometheus.New()
+	setup(t, e)
+
+	for _, openMetrics := range []bool{false, true} {
+		t.Run(fmt.Sprintf("openMetrics=%t", openMetrics), func(t *testing.T) {
+			accept := "text/plain"
+			if openMetrics {
+				accept = "application/openmetrics-text"
+			}
+			_, body := scrape(e, accept)
+			families, err := parse(body, openMetrics)
+			if err != nil {
+				t.Fatalf("invalid exposition: %v\n%s", err, body)
+			}
+
+			requests := "http_requests_total"
+			if openMetrics {
+				requests = "http_requests"
+			}
+			var names []string
+			for _, f := range families {
+				names = append(names, f.name+":"+f.typ)
+			}
+			want := requests + ":counter latency_ms:histogram load:gauge size_kb:histogram"
+			if got := strings.Join(names, " "); got != want {
+				t.Errorf("families = %s, want %s", got, want)
+			}
+
+			reqs := families[0]
+			if want := "Requests \"served\",\nby method\\code."; reqs.help != want {
+				t.Errorf("help = %q, want %q", reqs.help, want)
+			}
+			var methods []string
+			for _, s := range reqs.samples {
+				if !strings.HasSuffix(s.name, "_created") {
+					methods = append(methods, s.labels["method"]+"="+formatFloat(s.value))
+				}
+			}
+			sort.Strings(methods)
+			if got, want := methods, []string{"GET=4", trickyValue + "=2"}; fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("requests by method = %q, want %q", got, want)
+			}
+
+			created, exemplars := 0, 0
+			for _, f := range families {
+				for _, s := range f.samples {
+					if strings.HasSuffix(s.name, "_created") {
+						created++
+					}
+					if s.exemplar != "" {
+						exemplars++
+					}
+				}
+			}
+			if openMetrics {
+				// 2 request series, 2 latency series, 1 size series.
+				if created != 5 {
+					t.Errorf("got %d _created samples, want 5", created)
+				}
+				// The latency buckets that received values within the span.
+				if exemplars != 3 {
+					t.Errorf("got %d exemplars, want 3", exemplars)
+				}
+			} else if created != 0 || exemplars != 0 {
+				t.Errorf("text format has %d _created samples and %d exemplars, want none", created, exemplars)
+			}
+		})
+	}
+}
+
+func TestNegotiation(t *testing.T) {
+	e := prometheus.New()
+	for _, test := range []struct {
+		accept      string
+		openMetrics bool
+	}{
+		{"", false},
+		{"*/*", false},
+		{"text/plain", false},
+		{"application/openmetrics-text", true},
+		{"application/openmetrics-text; version=1.0.0", true},
+		// The header sent by Prometheus servers.
+		{"application/openmetrics-text;version=1.0.0;q=0.5,text/plain;version=0.0.4;q=0.4,*/*;q=0.1", true},
+		{"application/openmetrics-text;q=0.3,text/plain;q=0.7", false},
+		{"application/openmetrics-text;q=0", false},
+	} {
+		contentType, body := scrape(e, test.accept)
+		gotOM := strings.HasPrefix(contentType, "application/openmetrics-text")
+		if gotOM != test.openMetrics {
+			t.Errorf("Accept: %q: got Content-Type %q, want OpenMetrics=%t", test.accept, contentType, test.openMetrics)
+		}
+		if gotOM != strings.HasSuffix(body, "# EOF\n") {
+			t.Errorf("Accept: %q: body %q inconsistent with Content-Type %q", test.accept, body, contentType)
+		}
+	}
+}
+
+func TestQuantiles(t *testing.T) {
+	e := prometheus.New()
+	e.Quantiles = []float64{0.99, 0.5, 0}
+	setup(t, e)
+
+	for _, openMetrics := range []bool{false, true} {
+		accept := "text/plain"
+		if openMetrics {
+			accept = "application/openmetrics-text"
+		}
+		_, body := scrape(e, accept)
+		families, err := parse(body, openMetrics)
+		if err != nil {
+			t.Fatalf("invalid exposition: %v\n%s", err, body)
+		}
+		var size *family
+		for _, f := range families {
+			if f.name == "size_kb" {
+				size = f
+			}
+		}
+		if size == nil || size.typ != "summary" {
+			t.Fatalf("size_kb: got %+v, want summary", size)
+		}
+		var got []string
+		for _, s := range size.samples {
+			if q, ok := s.labels["quantile"]; ok {
+				got = append(got, q+"="+formatFloat(s.value))
+			}
+		}
+		// Values: 0.5, 1.5, 1.5, 3, 3, 3, 6, 7 in buckets (min=0.5,1], (1,2],
+		// (2,4], (4,8=max=7]. The median (rank 4) lies one third of the way
+		// through the (2,4] bucket, which holds ranks 3 to 6; the 99th
+		// percentile (rank 7.92) lies 96% of the way through (4,7], which holds
+		// ranks 6 to 8.
+		want := []string{"0=0.5", "0.5=2.6666666666666665", "0.99=6.88"}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("quantiles = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestValueFormat(t *testing.T) {
+	config := &metric.Config{}
+	metric.Scalar{Name: "big", Description: "a large gauge"}.LatestFloat64(config, load)
+	metric.HistogramFloat64{
+		Name:        "size_kb",
+		Description: "response size",
+		Buckets:     []float64{0.5, 1e6, 1e21},
+	}.Record(config, size)
+	e := prometheus.New()
+	event.SetExporter(config.Exporter(e.ProcessEvent))
+	t.Cleanup(func() { event.SetExporter(nil) })
+
+	ctx := context.Background()
+	event.Metric(ctx, load.Of(1e6))
+	event.Metric(ctx, size.Of(2e6))
+
+	// Integral values are formatted without an exponent, unless they
+	// are too large to be exact.
+	_, body := scrape(e, "text/plain")
+	for _, want := range []string{
+		"big 1000000\n",
+		`size_kb_bucket{le="0.5"} 0`,
+		`size_kb_bucket{le="1000000"} 0`,
+		`size_kb_bucket{le="1e+21"} 1`,
+		"size_kb_sum 2000000\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("exposition does not contain %q:\n%s", want, body)
+		}
+	}
+}
+
+// A family is a parsed metric family.
+type family struct {
+	name, help, typ string
+	samples         []*sample
+}
+
+// A sample is a parsed sample line.
+type sample struct {
+	name     string
+	labels   map[string]string
+	value    float64
+	exemplar string // raw exemplar text, OpenMetrics only
+}
+
+// parse parses and validates an exposition in the Prometheus text format or
+// in OpenMetrics. It checks the grammar of each line, that each family is
+// declared once before its samples, that sample names match their family
+// type, and that histogram buckets are cumulative and consistent with their
+// count.
+func parse(text string, openMetrics bool) ([]*family, error) {
+	var (
+		families []*family
+		cur      *family
+		seen     = make(map[string]bool)
+		eof      bool
+	)
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] != "" {
+		return nil, fmt.Errorf("exposition does not end with a newline")
+	}
+	lines = lines[:len(lines)-1]
+	for i, line := range lines {
+		line = strings.TrimSuffix(line, "\n")
+		errorf := func(format string, args ...any) error {
+			return fmt.Errorf("line %d: %q: %s", i+1, line, fmt.Sprintf(format, args...))
+		}
+		if eof {
+			return nil, errorf("content after # EOF")
+		}
+		if line == "# EOF" && openMetrics {
+			eof = true
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			fields := strings.SplitN(line, " ", 4)
+			if len(fields) < 3 {
+				return nil, errorf("malformed comment")
+			}
+			name := fields[2]
+			if !validName(name, true) {
+				return nil, errorf("invalid metric name %q", name)
+			}
+			if cur == nil || cur.name != name {
+				if seen[name] {
+					return nil, errorf("family %s is not contiguous", name)
+				}
+				seen[name] = true
+				cur = &family{name: name}
+				families = append(families, cur)
+			}
+			switch fields[1] {
+			case "HELP":
+				help := ""
+				if len(fields) == 4 {
+					var err error
+					if help, err = unescape(fields[3], openMetrics); err != nil {
+						return nil, errorf("%v", err)
+					}
+				}
+				cur.help = help
+			case "TYPE":
+				if len(fields) != 4 {
+					return nil, errorf("missing type")
+				}
+				switch fields[3] {
+				case "counter", "gauge", "histogram", "summary":
+				default:
+					return nil, errorf("unknown type %q", fields[3])
+				}
+				if cur.typ != "" || len(cur.samples) > 0 {
+					return nil, errorf("TYPE must come once, before samples")
+				}
+				cur.typ = fields[3]
+			default:
+				if openMetrics {
+					return nil, errorf("unknown comment")
+				}
+			}
+			continue
+		}
+		s, err := parseSample(line, openMetrics)
+		if err != nil {
+			return nil, errorf("%v", err)
+		}
+		if cur == nil || cur.typ == "" {
+			return nil, errorf("sample without TYPE")
+		}
+		suffix, ok := strings.CutPrefix(s.name, cur.name)
+		if !ok || !allowedSuffix(cur.typ, suffix, openMetrics) {
+			return nil, errorf("sample %s does not belong to %s family %s", s.name, cur.typ, cur.name)
+		}
+		if s.exemplar != "" && suffix != "_bucket" && suffix != "_total" {
+			return nil, errorf("exemplar on %s sample", suffix)
+		}
+		if (suffix == "_bucket") != (s.labels["le"] != "") {
+			return nil, errorf("le label must appear exactly on buckets")
+		}
+		cur.samples = append(cur.samples, s)
+	}
+	if openMetrics && !eof {
+		return nil, fmt.Errorf("missing # EOF")
+	}
+	for _, f := range families {
+		if f.typ == "histogram" {
+			if err := checkHistogram(f); err != nil {
+				return nil, fmt.Errorf("%s: %v", f.name, err)
+			}
+		}
+	}
+	return families, nil
+}
+
+func allowedSuffix(typ, suffix string, openMetrics bool) bool {
+	if openMetrics && suffix == "_created" && typ != "gauge" {
+		return true
+	}
+	switch typ {
+	case "counter":
+		if openMetrics {
+			return suffix == "_total"
+		}
+		return suffix == ""
+	case "gauge":
+		return suffix == ""
+	case "histogram":
+		return suffix == "_bucket" || suffix == "_count" || suffix == "_sum"
+	case "summary":
+		return suffix == "" || suffix == "_count" || suffix == "_sum"
+	}
+	return false
+}
+
+// checkHistogram checks that the buckets of each series are cumulative, and
+// that the +Inf bucket matches the count.
+func checkHistogram(f *family) error {
+	type series struct {
+		le    []float64
+		count []float64
+		total float64
+	}
+	bySeries := make(map[string]*series)
+	get := func(s *sample) *series {
+		var key []string
+		for k, v := range s.labels {
+			if k != "le" {
+				key = append(key, k+"="+v)
+			}
+		}
+		sort.Strings(key)
+		k := strings.Join(key, ",")
+		if bySeries[k] == nil {
+			bySeries[k] = &series{total: -1}
+		}
+		return bySeries[k]
+	}
+	for _, s := range f.samples {
+		switch {
+		case strings.HasSuffix(s.name, "_bucket"):
+			le, err := parseFloat(s.labels["le"])
+			if err != nil {
+				return err
+			}
+			ser := get(s)
+			ser.le = append(ser.le, le)
+			ser.count = append(ser.count, s.value)
+		case strings.HasSuffix(s.name, "_count"):
+			get(s).total = s.value
+		}
+	}
+	for key, ser := range bySeries {
+		for i := 1; i < len(ser.le); i++ {
+			if ser.le[i] <= ser.le[i-1] || ser.count[i] < ser.count[i-1] {
+				return fmt.Errorf("series {%s}: buckets are not cumulative", key)
+			}
+		}
+		if n := len(ser.le); n == 0 || !math.IsInf(ser.le[n-1], 1) || ser.count[n-1] != ser.total {
+			return fmt.Errorf("series {%s}: +Inf bucket missing or inconsistent with _count", key)
+		}
+	}
+	return nil
+}
+
+// parseSample parses a sample line.
+func parseSample(line string, openMetrics bool) (*sample, error) {
+	s := &sample{labels: make(map[string]string)}
+	i := strings.IndexAny(line, "{ ")
+	if i < 0 {
+		return nil, fmt.Errorf("missing value")
+	}
+	s.name, line = line[:i], line[i:]
+	if !validName(s.name, true) {
+		return nil, fmt.Errorf("invalid metric name %q", s.name)
+	}
+	if strings.HasPrefix(line, "{") {
+		var err error
+		if line, err = parseLabels(line[1:], s.labels); err != nil {
+			return nil, err
+		}
+	}
+	if !strings.HasPrefix(line, " ") {
+		return nil, fmt.Errorf("missing space before value")
+	}
+	line = line[1:]
+	if openMetrics {
+		if before, after, ok := strings.Cut(line, " # "); ok {
+			line, s.exemplar = before, after
+			labels := make(map[string]string)
+			if !strings.HasPrefix(after, "{") {
+				return nil, fmt.Errorf("malformed exemplar %q", after)
+			}
+			rest, err := parseLabels(after[1:], labels)
+			if err != nil {
+				return nil, fmt.Errorf("exemplar: %v", err)
+			}
+			fields := strings.Fields(rest)
+			if len(fields) < 1 || len(fields) > 2 {
+				return nil, fmt.Errorf("malformed exemplar %q", after)
+			}
+			for _, f := range fields {
+				if _, err := parseFloat(f); err != nil {
+					return nil, fmt.Errorf("exemplar: %v", err)
+				}
+			}
+		}
+	}
+	fields := strings.Split(line, " ")
+	if len(fields) > 2 {
+		return nil, fmt.Errorf("trailing content")
+	}
+	v, err := parseFloat(fields[0])
+	if err != nil {
+		return nil, err
+	}
+	s.value = v
+	if len(fields) == 2 {
+		if _, err := strconv.ParseFloat(fields[1], 64); err != nil {
+			return nil, fmt.Errorf("bad timestamp %q", fields[1])
+		}
+	}
+	return s, nil
+}
+
+// parseLabels parses a label set following the opening brace, storing the
+// labels in m, and returns the remainder of the line after the closing
+// brace.
+func parseLabels(s string, m map[string]string) (string, error) {
+	for {
+		if rest, ok := strings.CutPrefix(s, "}"); ok {
+			return rest, nil
+		}
+		eq := strings.Index(s, `="`)
+		if eq < 0 {
+			return "", fmt.Errorf("malformed labels")
+		}
+		name := s[:eq]
+		if !validName(name, false) {
+			return "", fmt.Errorf("invalid label name %q", name)
+		}
+		if _, dup := m[name]; dup {
+			return "", fmt.Errorf("duplicate label %q", name)
+		}
+		s = s[eq+2:]
+		var value strings.Builder
+		for {
+			if s == "" {
+				return "", fmt.Errorf("unterminated label value")
+			}
+			c := s[0]
+			s = s[1:]
+			if c == '"' {
+				break
+			}
+			if c == '\n' {
+				return "", fmt.Errorf("raw newline in label value")
+			}
+			if c == '\\' {
+				if s == "" {
+					return "", fmt.Errorf("unterminated escape")
+				}
+				switch s[0] {
+				case '\\':
+					value.WriteByte('\\')
+				case '"':
+					value.WriteByte('"')
+				case 'n':
+					value.WriteByte('\n')
+				default:
+					return "", fmt.Errorf("invalid escape \\%c", s[0])
+				}
+				s = s[1:]
+				continue
+			}
+			value.WriteByte(c)
+		}
+		m[name] = value.String()
+		s, _ = strings.CutPrefix(s, ",")
+	}
+}
+
+// unescape unescapes HELP text. Double quotes are escaped only in
+// OpenMetrics.
+func unescape(s string, openMetrics bool) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if openMetrics && c == '"' {
+			return "", fmt.Errorf("unescaped quote in HELP")
+		}
+		if c != '\\' {
+			b.WriteByte(c)
+			continue
+		}
+		if i++; i == len(s) {
+			return "", fmt.Errorf("unterminated escape")
+		}
+		switch c := s[i]; {
+		case c == '\\':
+			b.WriteByte('\\')
+		case c == 'n':
+			b.WriteByte('\n')
+		case c == '"' && openMetrics:
+			b.WriteByte('"')
+		default:
+			return "", fmt.Errorf("invalid escape \\%c", c)
+		}
+	}
+	return b.String(), nil
+}
+
+func validName(name string, allowColon bool) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		ok := c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' ||
+			allowColon && c == ':' || i > 0 && '0' <= c && c <= '9'
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func parseFloat(s string) (float64, error) {
+	switch s {
+	case "+Inf":
+		return math.Inf(1), nil
+	case "-Inf":
+		return math.Inf(-1), nil
+	case "NaN":
+		return math.NaN(), nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || strings.ContainsAny(s, "xXpP_") || strings.EqualFold(s, "inf") {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	return v, nil
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
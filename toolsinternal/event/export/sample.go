@@ -0,0 +1,184 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package export
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/tenntenn/exp/toolsinternal/event"
+	"github.com/tenntenn/exp/toolsinternal/event/core"
+	"github.com/tenntenn/exp/toolsinternal/event/keys"
+	"github.com/tenntenn/exp/toolsinternal/event/label"
+)
+
+// This file contains exporters that reduce the volume of events passed on to
+// another exporter. The span based ones must be wrapped by Spans, so that the
+// span of each event is available in its context, for example:
+//
+//	event.SetExporter(export.Spans(export.SampleTraces(0.01, output)))
+//
+// All of them measure time using the time of the events, not the wall clock.
+
+// SampleTraces returns an exporter that passes on the events of a fraction of
+// all traces, and drops the rest.
+// The decision is a function of the trace ID alone, so every span of a trace
+// is either kept or dropped, and exporters in different processes sampling
+// the same fraction agree on it.
+// Events that are not in a span, and metric events, are always passed on.
+func SampleTraces(fraction float64, output event.Exporter) event.Exporter {
+	return func(ctx context.Context, ev core.Event, lm label.Map) context.Context {
+		if !event.IsMetric(ev) {
+			if span := GetSpan(ctx); span != nil && !sampled(span.ID.TraceID, fraction) {
+				return ctx
+			}
+		}
+		return output(ctx, ev, lm)
+	}
+}
+
+// sampled reports whether a trace is in the sampled fraction of all traces.
+// It uses the last eight bytes of the trace ID, which are the random part of
+// W3C trace IDs.
+func sampled(id TraceID, fraction float64) bool {
+	switch {
+	case fraction >= 1:
+		return true
+	case fraction <= 0 || math.IsNaN(fraction):
+		return false
+	}
+	return binary.BigEndian.Uint64(id[8:]) < uint64(fraction*(1<<64))
+}
+
+// RateLimit returns an exporter that limits the rate of log events with the
+// same message. Each message may be logged up to burst times in quick
+// succession, after which it is allowed once for every period that passes.
+// Log events over the limit are dropped, and all other events are passed on.
+//
+// The limiter keeps state for each distinct message, so it should only be
+// used where messages are constant strings.
+func RateLimit(every time.Duration, burst int, output event.Exporter) event.Exporter {
+	rl := &rateLimiter{every: every, burst: float64(burst), buckets: make(map[string]*tokenBucket)}
+	return func(ctx context.Context, ev core.Event, lm label.Map) context.Context {
+		if event.IsLog(ev) && !rl.allow(keys.Msg.Get(ev), ev.At()) {
+			return ctx
+		}
+		return output(ctx, ev, lm)
+	}
+}
+
+type rateLimiter struct {
+	every time.Duration
+	burst float64
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+// tokenBucket holds the allowance of a single message.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// allow reports whether a message logged at the given time is within the limit,
+// and if so consumes its allowance.
+func (rl *rateLimiter) allow(message string, at time.Time) bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	b, ok := rl.buckets[message]
+	if !ok {
+		b = &tokenBucket{tokens: rl.burst, last: at}
+		rl.buckets[message] = b
+	}
+	if elapsed := at.Sub(b.last); elapsed > 0 {
+		if rl.every > 0 {
+			b.tokens = min(rl.burst, b.tokens+float64(elapsed)/float64(rl.every))
+		}
+		b.last = at
+	}
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// TailSpans returns an exporter that holds back the events of each span until
+// the span ends, and then passes them on only if the span took at least slow
+// to complete, contained an error event, or has a descendant span that was
+// kept. Keeping the ancestors of kept spans means the retained part of a
+// trace is always connected.
+//
+// As the start event of a span is passed on late, the context returned by
+// output for it is discarded. Events that are not in a span, and metric
+// events, are passed on immediately.
+func TailSpans(slow time.Duration, output event.Exporter) event.Exporter {
+	t := &tailSampler{slow: slow, output: output, spans: make(map[SpanID]*tailSpan)}
+	return t.processEvent
+}
+
+type tailSampler struct {
+	slow   time.Duration
+	output event.Exporter
+
+	mu    sync.Mutex
+	spans map[SpanID]*tailSpan // spans that have started but not ended
+}
+
+// tailSpan holds the events of a span that has not yet ended.
+type tailSpan struct {
+	events []heldEvent
+	keep   bool
+}
+
+type heldEvent struct {
+	ctx context.Context
+	ev  core.Event
+	lm  label.Map
+}
+
+func (t *tailSampler) processEvent(ctx context.Context, ev core.Event, lm label.Map) context.Context {
+	span := GetSpan(ctx)
+	if span == nil || event.IsMetric(ev) {
+		return t.output(ctx, ev, lm)
+	}
+	t.mu.Lock()
+	if event.IsStart(ev) {
+		t.spans[span.ID.SpanID] = &tailSpan{}
+	}
+	held, ok := t.spans[span.ID.SpanID]
+	if !ok {
+		// The span started before this exporter saw it.
+		t.mu.Unlock()
+		return t.output(ctx, ev, lm)
+	}
+	held.events = append(held.events, heldEvent{ctx, ev, lm})
+	if event.IsError(ev) {
+		held.keep = true
+	}
+	if !event.IsEnd(ev) {
+		t.mu.Unlock()
+		return ctx
+	}
+	delete(t.spans, span.ID.SpanID)
+	if ev.At().Sub(span.Start().At()) >= t.slow {
+		held.keep = true
+	}
+	if parent, ok := t.spans[span.ParentID]; ok && held.keep {
+		parent.keep = true // and so on up, as each ancestor ends
+	}
+	t.mu.Unlock()
+
+	if held.keep {
+		for _, h := range held.events {
+			t.output(h.ctx, h.ev, h.lm)
+		}
+	}
+	return ctx
+}
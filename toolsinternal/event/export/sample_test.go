@@ -0,0 +1,183 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package export_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tenntenn/exp/toolsinternal/event"
+	"github.com/tenntenn/exp/toolsinternal/event/core"
+	"github.com/tenntenn/exp/toolsinternal/event/export"
+	"github.com/tenntenn/exp/toolsinternal/event/export/eventtest"
+	"github.com/tenntenn/exp/toolsinternal/event/keys"
+	"github.com/tenntenn/exp/toolsinternal/event/label"
+)
+
+// install makes exporter the global exporter for the duration of the test,
+// with event times taken from the returned clock.
+func install(t *testing.T, exporter func(output event.Exporter) event.Exporter) (*eventtest.Clock, *eventtest.Recorder) {
+	clock := eventtest.NewClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	rec := &eventtest.Recorder{}
+	event.SetExporter(clock.Exporter(export.Spans(exporter(rec.ProcessEvent))))
+	t.Cleanup(func() { event.SetExporter(nil) })
+	return clock, rec
+}
+
+// summarize returns a short description of each event, such as "start a",
+// "log hello" or "end".
+func summarize(events []core.Event) string {
+	var parts []string
+	for _, ev := range events {
+		switch {
+		case event.IsStart(ev):
+			parts = append(parts, "start "+keys.Start.Get(ev))
+		case event.IsEnd(ev):
+			parts = append(parts, "end")
+		case event.IsLog(ev):
+			parts = append(parts, "log "+keys.Msg.Get(ev))
+		case event.IsMetric(ev):
+			parts = append(parts, "metric")
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func TestSampleTraces(t *testing.T) {
+	var traces map[export.TraceID]bool
+	_, rec := install(t, func(output event.Exporter) event.Exporter {
+		return export.SampleTraces(0.25, func(ctx context.Context, ev core.Event, lm label.Map) context.Context {
+			if span := export.GetSpan(ctx); span != nil {
+				traces[span.ID.TraceID] = true
+			}
+			return output(ctx, ev, lm)
+		})
+	})
+
+	const n = 1000
+	traces = make(map[export.TraceID]bool)
+	ctx := context.Background()
+	for range n {
+		ctx, end := event.Start(ctx, "root")
+		ctx, endChild := event.Start(ctx, "child")
+		event.Log(ctx, "inside")
+		endChild()
+		end()
+	}
+	event.Log(ctx, "outside")
+	counter := keys.NewInt64("counter", "")
+	event.Metric(ctx, counter.Of(1))
+
+	// Sampled traces must be complete, unsampled ones absent.
+	events := rec.Events()
+	if got, want := len(events), 5*len(traces)+2; got != want {
+		t.Errorf("got %d events for %d traces, want %d", got, len(traces), want)
+	}
+	if got := summarize(events[len(events)-2:]); got != "log outside, metric" {
+		t.Errorf("events outside spans: got %q", got)
+	}
+	// The IDs are random, so allow a generous margin.
+	if len(traces) < n/8 || len(traces) > n/2 {
+		t.Errorf("sampled %d of %d traces, want about %d", len(traces), n, n/4)
+	}
+
+	// The decision depends only on the trace ID.
+	for _, fraction := range []float64{0, 1} {
+		rec := &eventtest.Recorder{}
+		event.SetExporter(export.Spans(export.SampleTraces(fraction, rec.ProcessEvent)))
+		_, end := event.Start(ctx, "span")
+		end()
+		if got, want := len(rec.Events()), int(fraction)*2; got != want {
+			t.Errorf("SampleTraces(%v) passed %d events, want %d", fraction, got, want)
+		}
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	clock, rec := install(t, func(output event.Exporter) event.Exporter {
+		return export.RateLimit(time.Second, 2, output)
+	})
+	ctx := context.Background()
+	logAll := func() {
+		event.Log(ctx, "hot")
+		event.Error(ctx, "cold", errors.New("e"))
+	}
+	for range 4 {
+		logAll()
+	}
+	clock.Advance(500 * time.Millisecond)
+	logAll() // half a token: still limited
+	clock.Advance(500 * time.Millisecond)
+	logAll() // one token
+	logAll()
+	clock.Advance(time.Hour)
+	for range 3 {
+		logAll() // burst is capped
+	}
+	_, end := event.Start(ctx, "span") // not limited
+	end()
+
+	var counts []string
+	n := map[string]int{}
+	for _, ev := range rec.Events() {
+		n[summarize([]core.Event{ev})]++
+	}
+	for _, s := range []string{"log hot", "log cold", "start span", "end"} {
+		counts = append(counts, fmt.Sprintf("%s=%d", s, n[s]))
+	}
+	if got, want := strings.Join(counts, " "), "log hot=5 log cold=5 start span=1 end=1"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestTailSpans(t *testing.T) {
+	clock, rec := install(t, func(output event.Exporter) event.Exporter {
+		return export.TailSpans(100*time.Millisecond, output)
+	})
+	ctx := context.Background()
+
+	// A fast span is dropped.
+	sctx, end := event.Start(ctx, "fast")
+	event.Log(sctx, "fast work")
+	clock.Advance(10 * time.Millisecond)
+	end()
+	if got := summarize(rec.Events()); got != "" {
+		t.Errorf("fast span: got %q, want nothing", got)
+	}
+
+	// A slow span is kept, along with its events.
+	sctx, end = event.Start(ctx, "slow")
+	event.Log(sctx, "slow work")
+	if got := summarize(rec.Events()); got != "" {
+		t.Errorf("before end: got %q, want events held back", got)
+	}
+	clock.Advance(100 * time.Millisecond)
+	end()
+	if got, want := summarize(rec.Events()), "start slow, log slow work, end"; got != want {
+		t.Errorf("slow span: got %q, want %q", got, want)
+	}
+	rec.Reset()
+
+	// A fast errored child is kept, as is its fast parent, but not its fast
+	// sibling.
+	pctx, endParent := event.Start(ctx, "parent")
+	cctx, endChild := event.Start(pctx, "sibling")
+	endChild()
+	cctx, endChild = event.Start(pctx, "child")
+	event.Error(cctx, "failed", errors.New("boom"))
+	endChild()
+	event.Log(ctx, "unrelated")
+	counter := keys.NewInt64("counter", "")
+	event.Metric(pctx, counter.Of(1))
+	endParent()
+	want := "start child, log failed, end, log unrelated, metric, start parent, end"
+	if got := summarize(rec.Events()); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
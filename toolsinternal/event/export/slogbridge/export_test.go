@@ -0,0 +1,17 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package slogbridge
+
+import "log/slog"
+
+const MaxCachedKeys = maxCachedKeys
+
+// CachedKeys returns the number of label keys cached by h.
+func CachedKeys(h slog.Handler) int {
+	c := h.(*handler).keys
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.keys)
+}
@@ -0,0 +1,175 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package slogbridge
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/tenntenn/exp/toolsinternal/event/core"
+	"github.com/tenntenn/exp/toolsinternal/event/keys"
+	"github.com/tenntenn/exp/toolsinternal/event/label"
+)
+
+// LevelKey is the label key that records the level of a slog record.
+var LevelKey = keys.NewString("level", "the slog level of a log record")
+
+// HandlerOptions configures a Handler.
+type HandlerOptions struct {
+	// Level is the minimum level of records that are delivered.
+	// If nil, slog.LevelInfo is used.
+	Level slog.Leveler
+}
+
+// A handler is a slog.Handler that delivers records as log events.
+type handler struct {
+	level  slog.Leveler
+	attrs  []label.Label // labels from WithAttrs, already qualified
+	prefix string        // group prefix for subsequent attributes
+	keys   *keyCache     // shared with the handlers derived from this one
+}
+
+// NewHandler returns a slog.Handler that delivers each record to the global
+// event exporter as a log event, so that it satisfies event.IsLog.
+//
+// The record message becomes the keys.Msg label. The first attribute whose
+// value is an error becomes the keys.Err label, so that records carrying an
+// error satisfy event.IsError. The level is recorded with [LevelKey], and all
+// other attributes become labels whose key type matches the attribute kind.
+// Attributes in groups are named by joining the group names and the
+// attribute name with dots. Attributes of the same name and kind share a
+// label key, up to a bound on the number of keys held by the handler and
+// those derived from it; beyond it, each attribute has a key of its own.
+//
+// The record's context is passed to the exporter, so span-aware exporters
+// (see export.Spans) see the span active where the record was logged. The
+// event time is the time of delivery, not the record time.
+func NewHandler(opts *HandlerOptions) slog.Handler {
+	h := &handler{level: slog.LevelInfo, keys: new(keyCache)}
+	if opts != nil && opts.Level != nil {
+		h.level = opts.Level
+	}
+	return h
+}
+
+func (h *handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	labels := make([]label.Label, 0, len(h.attrs)+r.NumAttrs()+1)
+	labels = append(labels, LevelKey.Of(r.Level.String()))
+	labels = append(labels, h.attrs...)
+	var errLabel label.Label
+	r.Attrs(func(a slog.Attr) bool {
+		labels = h.appendAttr(labels, h.prefix, a, &errLabel)
+		return true
+	})
+	core.Export(ctx, core.MakeEvent([3]label.Label{
+		keys.Msg.Of(r.Message),
+		errLabel,
+	}, labels))
+	return nil
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.attrs = append([]label.Label(nil), h.attrs...)
+	for _, a := range attrs {
+		h2.attrs = h.appendAttr(h2.attrs, h.prefix, a, nil)
+	}
+	return &h2
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
+
+// appendAttr appends the labels for an attribute to labels.
+// If errLabel is non-nil and has not yet been set, the first error-valued
+// attribute is stored there instead of being appended.
+func (h *handler) appendAttr(labels []label.Label, prefix string, a slog.Attr, errLabel *label.Label) []label.Label {
+	v := a.Value.Resolve()
+	if a.Key == "" && v.Kind() != slog.KindGroup {
+		return labels // ignored, per the slog.Handler contract
+	}
+	name := prefix + a.Key
+	switch v.Kind() {
+	case slog.KindGroup:
+		group := v.Group()
+		if len(group) == 0 {
+			return labels
+		}
+		if a.Key != "" {
+			prefix = name + "."
+		}
+		for _, ga := range group {
+			labels = h.appendAttr(labels, prefix, ga, errLabel)
+		}
+		return labels
+	case slog.KindString:
+		return append(labels, keyFor[*keys.String](h.keys, name, keys.NewString).Of(v.String()))
+	case slog.KindInt64:
+		return append(labels, keyFor[*keys.Int64](h.keys, name, keys.NewInt64).Of(v.Int64()))
+	case slog.KindUint64:
+		return append(labels, keyFor[*keys.UInt64](h.keys, name, keys.NewUInt64).Of(v.Uint64()))
+	case slog.KindFloat64:
+		return append(labels, keyFor[*keys.Float64](h.keys, name, keys.NewFloat64).Of(v.Float64()))
+	case slog.KindBool:
+		return append(labels, keyFor[*keys.Boolean](h.keys, name, keys.NewBoolean).Of(v.Bool()))
+	}
+	if err, ok := v.Any().(error); ok {
+		if errLabel != nil && !errLabel.Valid() {
+			*errLabel = keys.Err.Of(err)
+			return labels
+		}
+		return append(labels, keyFor[*keys.Error](h.keys, name, keys.NewError).Of(err))
+	}
+	// Durations, times and arbitrary values.
+	return append(labels, keyFor[*keys.Value](h.keys, name, keys.New).Of(v.Any()))
+}
+
+// maxCachedKeys bounds the number of label keys held by a keyCache, since
+// attribute names may be built at run time.
+const maxCachedKeys = 1024
+
+// A keyCache holds the label keys created for attributes.
+// Label keys are compared by identity, so each distinct attribute name and
+// key type should map to a single key.
+type keyCache struct {
+	mu   sync.Mutex
+	keys map[keyID]label.Key
+}
+
+type keyID struct {
+	name string
+	typ  any // a nil pointer of the key type
+}
+
+// keyFor returns the key of type K for the named attribute, creating it
+// with newKey if necessary. The key is unique unless the cache is full.
+func keyFor[K label.Key](c *keyCache, name string, newKey func(name, description string) K) K {
+	var zero K
+	id := keyID{name, zero}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if k, ok := c.keys[id]; ok {
+		return k.(K)
+	}
+	k := newKey(name, "")
+	if len(c.keys) < maxCachedKeys {
+		if c.keys == nil {
+			c.keys = make(map[keyID]label.Key)
+		}
+		c.keys[id] = k
+	}
+	return k
+}
@@ -0,0 +1,117 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package slogbridge connects the event package to log/slog.
+//
+// [Exporter] forwards log events to a [slog.Handler], and [NewHandler]
+// returns a slog.Handler that delivers records as log events to the global
+// event exporter. The two must not be connected to each other, or records
+// would be forwarded in a loop.
+package slogbridge
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+
+	"github.com/tenntenn/exp/toolsinternal/event"
+	"github.com/tenntenn/exp/toolsinternal/event/core"
+	"github.com/tenntenn/exp/toolsinternal/event/export"
+	"github.com/tenntenn/exp/toolsinternal/event/keys"
+	"github.com/tenntenn/exp/toolsinternal/event/label"
+)
+
+// Attribute keys used for span context, in both directions.
+const (
+	TraceIDKey = "trace_id"
+	SpanIDKey  = "span_id"
+)
+
+// Exporter returns an event.Exporter that forwards log events to h.
+// Events built by event.Error are logged at slog.LevelError, and all other
+// log events at slog.LevelInfo. Other events are ignored.
+//
+// Labels become attributes of the same name, with the slog kind that best
+// matches the label key type. If the context carries a span (see
+// export.Spans), its trace and span IDs are added as the attributes
+// [TraceIDKey] and [SpanIDKey].
+func Exporter(h slog.Handler) event.Exporter {
+	return func(ctx context.Context, ev core.Event, lm label.Map) context.Context {
+		if !event.IsLog(ev) {
+			return ctx
+		}
+		level := slog.LevelInfo
+		if event.IsError(ev) {
+			level = slog.LevelError
+		}
+		if !h.Enabled(ctx, level) {
+			return ctx
+		}
+		r := slog.NewRecord(ev.At(), level, keys.Msg.Get(ev), 0)
+		for i := 0; ev.Valid(i); i++ {
+			l := ev.Label(i)
+			if !l.Valid() || l.Key() == keys.Msg {
+				continue
+			}
+			if attr, ok := Attr(l); ok {
+				r.AddAttrs(attr)
+			}
+		}
+		if span := export.GetSpan(ctx); span != nil {
+			r.AddAttrs(
+				slog.String(TraceIDKey, span.ID.TraceID.String()),
+				slog.String(SpanIDKey, span.ID.SpanID.String()),
+			)
+		}
+		h.Handle(ctx, r) // errors have nowhere to go
+		return ctx
+	}
+}
+
+// Attr converts a label to a slog.Attr, choosing the value kind from the
+// type of the label's key. It reports false for labels that carry no value,
+// such as those of tag keys.
+func Attr(l label.Label) (slog.Attr, bool) {
+	name := l.Key().Name()
+	switch key := l.Key().(type) {
+	case *keys.Tag:
+		return slog.Attr{}, false
+	case *keys.String:
+		return slog.String(name, key.From(l)), true
+	case *keys.Int:
+		return slog.Int(name, key.From(l)), true
+	case *keys.Int8:
+		return slog.Int64(name, int64(key.From(l))), true
+	case *keys.Int16:
+		return slog.Int64(name, int64(key.From(l))), true
+	case *keys.Int32:
+		return slog.Int64(name, int64(key.From(l))), true
+	case *keys.Int64:
+		return slog.Int64(name, key.From(l)), true
+	case *keys.UInt:
+		return slog.Uint64(name, uint64(key.From(l))), true
+	case *keys.UInt8:
+		return slog.Uint64(name, uint64(key.From(l))), true
+	case *keys.UInt16:
+		return slog.Uint64(name, uint64(key.From(l))), true
+	case *keys.UInt32:
+		return slog.Uint64(name, uint64(key.From(l))), true
+	case *keys.UInt64:
+		return slog.Uint64(name, key.From(l)), true
+	case *keys.Float32:
+		return slog.Float64(name, float64(key.From(l))), true
+	case *keys.Float64:
+		return slog.Float64(name, key.From(l)), true
+	case *keys.Boolean:
+		return slog.Bool(name, key.From(l)), true
+	case *keys.Error:
+		return slog.Any(name, key.From(l)), true
+	case *keys.Value:
+		return slog.Any(name, key.From(l)), true
+	}
+	// An unknown key type: use its own formatting.
+	var buf bytes.Buffer
+	l.Key().Format(&buf, nil, l)
+	return slog.String(name, buf.String()), true
+}
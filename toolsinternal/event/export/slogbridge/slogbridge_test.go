@@ -0,0 +1,211 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package slogbridge_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tenntenn/exp/toolsinternal/event"
+	"github.com/tenntenn/exp/toolsinternal/event/core"
+	"github.com/tenntenn/exp/toolsinternal/event/export"
+	"github.com/tenntenn/exp/toolsinternal/event/export/slogbridge"
+	"github.com/tenntenn/exp/toolsinternal/event/keys"
+	"github.com/tenntenn/exp/toolsinternal/event/label"
+)
+
+func TestExporter(t *testing.T) {
+	var buf bytes.Buffer
+	h := slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	event.SetExporter(export.Spans(slogbridge.Exporter(h)))
+	defer event.SetExporter(nil)
+
+	var (
+		name  = keys.NewString("name", "")
+		n     = keys.NewInt("n", "")
+		u     = keys.NewUInt8("u", "")
+		ratio = keys.NewFloat64("ratio", "")
+		ok    = keys.NewBoolean("ok", "")
+		delay = keys.New("delay", "")
+	)
+	ctx := context.Background()
+	event.Log(ctx, "plain", name.Of("x"), n.Of(-3), u.Of(200), ratio.Of(0.5), ok.Of(true), delay.Of(time.Second))
+	spanCtx, end := event.Start(ctx, "op")
+	event.Error(spanCtx, "failed", errors.New("boom"))
+	end()
+	event.Metric(ctx, n.Of(1)) // ignored
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d records, want 2:\n%s", len(lines), buf.String())
+	}
+	want := `{"level":"INFO","msg":"plain","name":"x","n":-3,"u":200,"ratio":0.5,"ok":true,"delay":1000000000}`
+	if lines[0] != want {
+		t.Errorf("got  %s\nwant %s", lines[0], want)
+	}
+
+	var rec map[string]any
+	if err := json.Unmarshal([]byte(lines[1]), &rec); err != nil {
+		t.Fatal(err)
+	}
+	span := ""
+	if rec["level"] != "ERROR" || rec["msg"] != "failed" || rec["error"] != "boom" {
+		t.Errorf("bad error record: %s", lines[1])
+	}
+	for _, key := range []string{slogbridge.TraceIDKey, slogbridge.SpanIDKey} {
+		if s, _ := rec[key].(string); s == "" {
+			t.Errorf("error record is missing %s: %s", key, lines[1])
+		} else {
+			span += s
+		}
+	}
+	if len(span) != 32+16 {
+		t.Errorf("bad span context in %s", lines[1])
+	}
+}
+
+func TestExporterEnabled(t *testing.T) {
+	var buf bytes.Buffer
+	h := slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelError})
+	event.SetExporter(slogbridge.Exporter(h))
+	defer event.SetExporter(nil)
+
+	ctx := context.Background()
+	event.Log(ctx, "dropped")
+	event.Error(ctx, "kept", errors.New("e"))
+	if got := buf.String(); strings.Contains(got, "dropped") || !strings.Contains(got, "kept") {
+		t.Errorf("got %q, want only the error", got)
+	}
+}
+
+// captured is a log event observed by the test exporter.
+type captured struct {
+	ctx     context.Context
+	isError bool
+	labels  []string
+}
+
+func capture(t *testing.T) *[]captured {
+	var events []captured
+	event.SetExporter(func(ctx context.Context, ev core.Event, lm label.Map) context.Context {
+		if event.IsLog(ev) {
+			c := captured{ctx: ctx, isError: event.IsError(ev)}
+			for i := 0; ev.Valid(i); i++ {
+				if l := ev.Label(i); l.Valid() {
+					c.labels = append(c.labels, fmt.Sprintf("%s:%T=%v", l.Key().Name(), l.Key(), l))
+				}
+			}
+			events = append(events, c)
+		}
+		return ctx
+	})
+	t.Cleanup(func() { event.SetExporter(nil) })
+	return &events
+}
+
+func TestHandler(t *testing.T) {
+	events := capture(t)
+
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "marker")
+	logger := slog.New(slogbridge.NewHandler(nil)).With("service", "api").WithGroup("req")
+	logger.DebugContext(ctx, "dropped")
+	logger.InfoContext(ctx, "hello",
+		"id", 42,
+		"size", uint64(7),
+		"ratio", 0.25,
+		"ok", true,
+		slog.Group("user", "name", "gopher"),
+		"elapsed", 2*time.Second,
+	)
+	logger.Error("bad", "err", errors.New("boom"), "other", errors.New("second"))
+
+	if len(*events) != 2 {
+		t.Fatalf("got %d events, want 2", len(*events))
+	}
+	info, bad := (*events)[0], (*events)[1]
+	if info.ctx.Value(ctxKey{}) != "marker" {
+		t.Errorf("record context was not passed to the exporter")
+	}
+	if info.isError || !bad.isError {
+		t.Errorf("IsError: got %t, %t; want false, true", info.isError, bad.isError)
+	}
+	sort.Strings(info.labels)
+	want := []string{
+		`level:*keys.String=level="INFO"`,
+		`message:*keys.String=message="hello"`,
+		`req.elapsed:*keys.Value=req.elapsed=2s`,
+		`req.id:*keys.Int64=req.id=42`,
+		`req.ok:*keys.Boolean=req.ok=true`,
+		`req.ratio:*keys.Float64=req.ratio=2.5E-01`,
+		`req.size:*keys.UInt64=req.size=7`,
+		`req.user.name:*keys.String=req.user.name="gopher"`,
+		`service:*keys.String=service="api"`,
+	}
+	if strings.Join(info.labels, "\n") != strings.Join(want, "\n") {
+		t.Errorf("labels:\n%s\nwant:\n%s", strings.Join(info.labels, "\n"), strings.Join(want, "\n"))
+	}
+	if got := strings.Join(bad.labels, " "); !strings.Contains(got, "error:*keys.Error=error=boom") ||
+		!strings.Contains(got, "req.other:*keys.Error=req.other=second") {
+		t.Errorf("error labels: %s", got)
+	}
+}
+
+func TestHandlerKeyIdentity(t *testing.T) {
+	// Attributes with the same name and kind must share a label key, so that
+	// they can be found in label maps.
+	logger := slog.New(slogbridge.NewHandler(nil))
+	logger.Info("a", "id", 1)
+
+	var found []int64
+	event.SetExporter(func(ctx context.Context, ev core.Event, lm label.Map) context.Context {
+		for i := 0; ev.Valid(i); i++ {
+			l := ev.Label(i)
+			if k, ok := l.Key().(*keys.Int64); ok && k.Name() == "id" {
+				found = append(found, k.Get(lm))
+			}
+		}
+		return ctx
+	})
+	defer event.SetExporter(nil)
+	logger.Info("c", "id", 3)
+	logger.Info("d", "id", 4)
+	if fmt.Sprint(found) != "[3 4]" {
+		t.Errorf("found %v, want [3 4]", found)
+	}
+}
+
+func TestHandlerKeyCache(t *testing.T) {
+	capture(t)
+
+	// Each handler has its own cache, shared with the handlers derived
+	// from it, and bounded in size.
+	h1, h2 := slogbridge.NewHandler(nil), slogbridge.NewHandler(nil)
+	logger := slog.New(h1).With("service", "api").WithGroup("req")
+	for i := range slogbridge.MaxCachedKeys + 10 {
+		logger.Info("msg", fmt.Sprint("id", i), i)
+	}
+	if got, want := slogbridge.CachedKeys(h1), slogbridge.MaxCachedKeys; got != want {
+		t.Errorf("CachedKeys(h1) = %d, want %d", got, want)
+	}
+	if got := slogbridge.CachedKeys(h2); got != 0 {
+		t.Errorf("CachedKeys(h2) = %d, want 0", got)
+	}
+}
@@ -0,0 +1,537 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gofix
+
+// This file defines Apply, which computes all the inlinings of the
+// analyzer across a set of packages.
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/checker"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/types/typeutil"
+	"github.com/tenntenn/exp/toolsinternal/diff"
+	"github.com/tenntenn/exp/toolsinternal/gocommand"
+	"github.com/tenntenn/exp/toolsinternal/imports"
+)
+
+// ApplyOptions configures a call to Apply.
+type ApplyOptions struct {
+	// Config configures the loading of packages. Apply sets its Mode
+	// and its Overlay, which must be empty: Apply uses it to present
+	// the files changed by each round to the next.
+	Config packages.Config
+
+	// MaxRounds bounds the number of rounds of analysis.
+	// If zero, DefaultMaxRounds is used.
+	MaxRounds int
+
+	// Imports configures the tidying of the imports of each file
+	// changed by a round, which removes those left unused by its
+	// inlinings. If nil, a default configuration is used.
+	Imports *imports.Options
+}
+
+// DefaultMaxRounds is the default bound on the number of rounds of
+// analysis of Apply.
+const DefaultMaxRounds = 10
+
+// An ApplyResult describes the changes computed by Apply.
+type ApplyResult struct {
+	Packages  int               // number of packages analyzed in the last round
+	Rounds    int               // number of rounds of analysis
+	Converged bool              // whether the last round found nothing more to inline
+	Original  map[string][]byte // original content of each changed file
+	Files     map[string][]byte // new content of each changed file
+	Fixes     []Finding         // fixes applied, in order
+	Conflicts []Finding         // fixes of the last round not applied because their edits conflicted with others
+	Unfixed   []Finding         // diagnostics without fixes of the last round
+	Cycles    []Cycle           // cycles among the declarations marked for inlining
+}
+
+// A Finding is a diagnostic of the analyzer in some round. Its
+// position refers to the content of the file in that round.
+type Finding struct {
+	Round   int
+	Posn    token.Position
+	Message string
+}
+
+func (f Finding) String() string { return fmt.Sprintf("%v: %s", f.Posn, f.Message) }
+
+// A Cycle is a set of declarations marked for inlining that refer to
+// one another, directly or indirectly, such as a pair of mutually
+// recursive functions. Inlining them would never reach a fixed point,
+// so Apply does not inline their uses. The declarations are sorted by
+// name.
+type Cycle []CycleDecl
+
+// A CycleDecl is a declaration in a Cycle.
+type CycleDecl struct {
+	Name string // for example "p.F", "(*p.T).M", or "field p.F", with package paths
+	Posn token.Position
+}
+
+func (c Cycle) String() string {
+	var names []string
+	for _, decl := range c {
+		names = append(names, decl.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// Edits returns the edits that transform the original content of the
+// named file into its new content.
+func (r *ApplyResult) Edits(filename string) []diff.Edit {
+	return diff.Bytes(r.Original[filename], r.Files[filename])
+}
+
+// Apply computes all the inlinings of the Analyzer across the packages
+// denoted by patterns. It does not write the changed files: see
+// [ApplyResult.Write].
+//
+// Apply proceeds in rounds. Each round loads and analyzes the packages,
+// as changed in memory by the previous rounds, orders the fixes of the
+// analyzer by position, outermost first, and merges their edits, deferring to
+// the next round each fix that conflicts with an earlier one. Apply
+// stops when a round finds nothing to inline, or after the maximum
+// number of rounds; each round may expose new inlinings, such as calls
+// within the body of an inlined function that is itself marked for
+// inlining.
+//
+// Uses of declarations that belong to a Cycle are not inlined.
+//
+// The analyzer's flags, such as -allow_binding_decl, apply.
+//
+// The packages must be free of errors. Apply checks that they remain
+// so after the last round, so that a faulty inlining is reported as an
+// error, rather than written.
+func Apply(opts *ApplyOptions, patterns ...string) (*ApplyResult, error) {
+	if len(opts.Config.Overlay) > 0 {
+		return nil, errors.New("Config.Overlay is not supported")
+	}
+	maxRounds := opts.MaxRounds
+	if maxRounds == 0 {
+		maxRounds = DefaultMaxRounds
+	}
+	importsOpts := opts.Imports
+	if importsOpts == nil {
+		importsOpts = &imports.Options{
+			Env: &imports.ProcessEnv{
+				GocmdRunner: new(gocommand.Runner),
+				WorkingDir:  opts.Config.Dir,
+			},
+			Comments:  true,
+			TabIndent: true,
+			TabWidth:  8,
+		}
+	}
+
+	p := &planner{
+		opts: opts,
+		result: &ApplyResult{
+			Original: make(map[string][]byte),
+			Files:    make(map[string][]byte),
+		},
+		cycles: make(map[string]bool),
+	}
+	p.analyzer = overlayAnalyzer(p.result.Files)
+	for p.result.Rounds < maxRounds {
+		p.result.Rounds++
+		n, err := p.round(patterns, importsOpts)
+		if err != nil {
+			return nil, fmt.Errorf("round %d: %v", p.result.Rounds, err)
+		}
+		if n == 0 {
+			p.result.Converged = true
+			break
+		}
+	}
+	if !p.result.Converged {
+		// Check the changes of the last round,
+		// which no later round has loaded.
+		if _, err := p.load(patterns); err != nil {
+			return nil, err
+		}
+	}
+	return p.result, nil
+}
+
+// Write writes the new content of each changed file.
+func (r *ApplyResult) Write() error {
+	for _, filename := range slices.Sorted(maps.Keys(r.Files)) {
+		if err := os.WriteFile(filename, r.Files[filename], 0666); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// planner holds the state of a call to Apply.
+type planner struct {
+	opts     *ApplyOptions
+	analyzer *analysis.Analyzer // Analyzer, reading result.Files in place of the files on disk
+	result   *ApplyResult
+	cycles   map[string]bool // cycles already reported, by Cycle.String
+}
+
+// A candidate is a fix of the analyzer, as edits to file content.
+type candidate struct {
+	Finding
+	start, end int                    // extent of the diagnostic in its file
+	edits      map[string][]diff.Edit // sorted edits of each file
+}
+
+// load loads the packages in their current state, and reports an
+// error if they contain errors.
+func (p *planner) load(patterns []string) ([]*packages.Package, error) {
+	cfg := p.opts.Config
+	cfg.Mode = packages.LoadAllSyntax
+	cfg.Overlay = p.result.Files
+	pkgs, err := packages.Load(&cfg, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	var errs []string
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		for _, err := range pkg.Errors {
+			errs = append(errs, err.Error())
+		}
+	})
+	if len(errs) > 0 {
+		if len(p.result.Files) > 0 {
+			return nil, fmt.Errorf("packages contain errors after inlining:\n%s", strings.Join(errs, "\n"))
+		}
+		return nil, fmt.Errorf("packages contain errors:\n%s", strings.Join(errs, "\n"))
+	}
+	return pkgs, nil
+}
+
+// round loads and analyzes the packages in their current state, and
+// applies the fixes that do not conflict to the content of the files.
+// It returns the number of fixes applied.
+func (p *planner) round(patterns []string, importsOpts *imports.Options) (int, error) {
+	round := p.result.Rounds
+	pkgs, err := p.load(patterns)
+	if err != nil {
+		return 0, err
+	}
+	p.result.Packages = len(pkgs)
+	graph, err := checker.Analyze([]*analysis.Analyzer{p.analyzer}, pkgs, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	cyclic := p.findCycles(graph)
+
+	// Gather the fixes. A file may belong to several packages (for
+	// example, p and its test variant), which report the same
+	// diagnostics, so they are identified by position.
+	var (
+		candidates []*candidate
+		seen       = make(map[string]bool)
+	)
+	p.result.Conflicts = nil
+	p.result.Unfixed = nil
+	for _, act := range graph.Roots {
+		if act.Err != nil {
+			return 0, act.Err
+		}
+		fset := act.Package.Fset
+		for _, diag := range act.Diagnostics {
+			posn := fset.PositionFor(diag.Pos, false) // ignore //line directives
+			key := posn.String()
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			finding := Finding{Round: round, Posn: posn, Message: diag.Message}
+			if len(diag.SuggestedFixes) == 0 {
+				p.result.Unfixed = append(p.result.Unfixed, finding)
+				continue
+			}
+			if obj := diagnosedObject(act.Package, diag); obj != nil && cyclic[obj] {
+				continue
+			}
+			cand, err := p.candidate(fset, finding, diag)
+			if err != nil {
+				return 0, err
+			}
+			candidates = append(candidates, cand)
+		}
+	}
+
+	// Merge the edits of the fixes, outermost first.
+	slices.SortFunc(candidates, func(x, y *candidate) int {
+		if c := strings.Compare(x.Posn.Filename, y.Posn.Filename); c != 0 {
+			return c
+		}
+		if x.start != y.start {
+			return x.start - y.start
+		}
+		return y.end - x.end
+	})
+	merged := make(map[string][]diff.Edit)
+	n := 0
+nextCandidate:
+	for _, cand := range candidates {
+		updates := make(map[string][]diff.Edit)
+		for filename, edits := range cand.edits {
+			m, ok := diff.Merge(merged[filename], edits)
+			if !ok {
+				p.result.Conflicts = append(p.result.Conflicts, cand.Finding)
+				continue nextCandidate
+			}
+			updates[filename] = m
+		}
+		maps.Copy(merged, updates)
+		p.result.Fixes = append(p.result.Fixes, cand.Finding)
+		n++
+	}
+
+	// Apply the edits and tidy the imports.
+	for filename, edits := range merged {
+		content, err := p.content(filename)
+		if err != nil {
+			return 0, err
+		}
+		if _, ok := p.result.Original[filename]; !ok {
+			p.result.Original[filename] = content
+		}
+		content, err = diff.ApplyBytes(content, edits)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %v", filename, err)
+		}
+		content, err = imports.Process(filename, content, importsOpts)
+		if err != nil {
+			return 0, fmt.Errorf("fixes in %s produced invalid code: %v", filename, err)
+		}
+		p.result.Files[filename] = content
+	}
+	return n, nil
+}
+
+// candidate returns the candidate for the first fix of diag.
+func (p *planner) candidate(fset *token.FileSet, finding Finding, diag analysis.Diagnostic) (*candidate, error) {
+	file := fset.File(diag.Pos)
+	end := diag.End
+	if !end.IsValid() {
+		end = diag.Pos
+	}
+	cand := &candidate{
+		Finding: finding,
+		start:   file.Offset(diag.Pos),
+		end:     file.Offset(end),
+		edits:   make(map[string][]diff.Edit),
+	}
+	for _, e := range diag.SuggestedFixes[0].TextEdits {
+		file := fset.File(e.Pos)
+		end := e.End
+		if !end.IsValid() {
+			end = e.Pos
+		}
+		cand.edits[file.Name()] = append(cand.edits[file.Name()], diff.Edit{
+			Start: file.Offset(e.Pos),
+			End:   file.Offset(end),
+			New:   string(e.NewText),
+		})
+	}
+	for filename, edits := range cand.edits {
+		diff.SortEdits(edits)
+		content, err := p.content(filename)
+		if err != nil {
+			return nil, err
+		}
+		// Reject invalid (for example, overlapping) edits now,
+		// rather than when all the edits of the file are applied.
+		if _, err := diff.ApplyBytes(content, edits); err != nil {
+			return nil, fmt.Errorf("%v: invalid fix: %v", finding.Posn, err)
+		}
+	}
+	return cand, nil
+}
+
+// content returns the current content of the named file.
+func (p *planner) content(filename string) ([]byte, error) {
+	if content, ok := p.result.Files[filename]; ok {
+		return content, nil
+	}
+	return os.ReadFile(filename)
+}
+
+// diagnosedObject returns the function or field whose use diag
+// proposes to inline, or nil if it cannot be determined.
+func diagnosedObject(pkg *packages.Package, diag analysis.Diagnostic) types.Object {
+	for _, file := range pkg.Syntax {
+		if !(file.FileStart <= diag.Pos && diag.Pos < file.FileEnd) {
+			continue
+		}
+		path, _ := astutil.PathEnclosingInterval(file, diag.Pos, diag.End)
+		switch n := path[0].(type) {
+		case *ast.CallExpr:
+			if fn := typeutil.StaticCallee(pkg.TypesInfo, n); fn != nil {
+				return fn.Origin()
+			}
+		case *ast.SelectorExpr:
+			return origin(pkg.TypesInfo.Uses[n.Sel])
+		case *ast.Ident:
+			return origin(pkg.TypesInfo.Uses[n])
+		}
+		return nil
+	}
+	return nil
+}
+
+// origin returns the generic object of an instantiated function or field.
+func origin(obj types.Object) types.Object {
+	switch obj := obj.(type) {
+	case *types.Func:
+		return obj.Origin()
+	case *types.Var:
+		return obj.Origin()
+	}
+	return obj
+}
+
+// findCycles records, in p.result, the cycles among the functions and
+// fields marked for inlining in the analyzed packages and their
+// dependencies, and returns the set of their declarations.
+//
+// A function refers to each function marked for inlining that it
+// calls, and a field to the field that replaces it.
+func (p *planner) findCycles(graph *checker.Graph) map[types.Object]bool {
+	var (
+		fset  *token.FileSet
+		edges = make(map[types.Object][]types.Object)
+		nodes []types.Object // in order of discovery
+	)
+	addEdge := func(from, to types.Object) {
+		if _, ok := edges[from]; !ok {
+			nodes = append(nodes, from)
+		}
+		if !slices.Contains(edges[from], to) {
+			edges[from] = append(edges[from], to)
+		}
+	}
+	for act := range graph.All() {
+		if act.Analyzer != p.analyzer || act.Err != nil {
+			continue
+		}
+		fset = act.Package.Fset
+		info := act.Package.TypesInfo
+		for _, file := range act.Package.Syntax {
+			ast.Inspect(file, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.FuncDecl:
+					fn, _ := info.Defs[n.Name].(*types.Func)
+					if fn == nil || n.Body == nil || !act.ObjectFact(fn, new(goFixInlineFuncFact)) {
+						break
+					}
+					ast.Inspect(n.Body, func(n ast.Node) bool {
+						if call, ok := n.(*ast.CallExpr); ok {
+							if callee := typeutil.StaticCallee(info, call); callee != nil &&
+								act.ObjectFact(callee.Origin(), new(goFixInlineFuncFact)) {
+								addEdge(fn, callee.Origin())
+							}
+						}
+						return true
+					})
+				case *ast.StructType:
+					st, ok := info.TypeOf(n).(*types.Struct)
+					if !ok {
+						break
+					}
+					for field := range st.Fields() {
+						var fact goFixInlineFieldFact
+						if !act.ObjectFact(field, &fact) {
+							continue
+						}
+						for rhs := range st.Fields() {
+							if rhs.Name() == fact.RHSName {
+								addEdge(field, rhs)
+							}
+						}
+					}
+				}
+				return true
+			})
+		}
+	}
+
+	// Find the strongly connected components (Tarjan's algorithm).
+	var (
+		index   = make(map[types.Object]int)
+		lowlink = make(map[types.Object]int)
+		onStack = make(map[types.Object]bool)
+		stack   []types.Object
+		cyclic  = make(map[types.Object]bool)
+		visit   func(obj types.Object)
+	)
+	visit = func(obj types.Object) {
+		index[obj] = len(index)
+		lowlink[obj] = index[obj]
+		stack = append(stack, obj)
+		onStack[obj] = true
+		for _, succ := range edges[obj] {
+			if _, ok := index[succ]; !ok {
+				visit(succ)
+				lowlink[obj] = min(lowlink[obj], lowlink[succ])
+			} else if onStack[succ] {
+				lowlink[obj] = min(lowlink[obj], index[succ])
+			}
+		}
+		if lowlink[obj] != index[obj] {
+			return
+		}
+		// obj is the root of a component.
+		i := slices.Index(stack, obj)
+		scc := slices.Clone(stack[i:])
+		stack = stack[:i]
+		for _, obj := range scc {
+			onStack[obj] = false
+		}
+		if len(scc) == 1 && !slices.Contains(edges[obj], obj) {
+			return // not recursive
+		}
+		var cycle Cycle
+		for _, obj := range scc {
+			cyclic[obj] = true
+			cycle = append(cycle, CycleDecl{
+				Name: declName(obj),
+				Posn: fset.PositionFor(obj.Pos(), false),
+			})
+		}
+		slices.SortFunc(cycle, func(x, y CycleDecl) int { return strings.Compare(x.Name, y.Name) })
+		if key := cycle.String(); !p.cycles[key] {
+			p.cycles[key] = true
+			p.result.Cycles = append(p.result.Cycles, cycle)
+		}
+	}
+	for _, obj := range nodes {
+		if _, ok := index[obj]; !ok {
+			visit(obj)
+		}
+	}
+	return cyclic
+}
+
+// declName returns the name of a function or field for a Cycle.
+func declName(obj types.Object) string {
+	if fn, ok := obj.(*types.Func); ok {
+		return fn.FullName()
+	}
+	return "field " + obj.Pkg().Path() + "." + obj.Name()
+}
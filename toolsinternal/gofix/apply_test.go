@@ -0,0 +1,77 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gofix_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	gocmp "github.com/google/go-cmp/cmp"
+	"golang.org/x/tools/txtar"
+	"github.com/tenntenn/exp/toolsinternal/gofix"
+	"github.com/tenntenn/exp/toolsinternal/testenv"
+	"github.com/tenntenn/exp/toolsinternal/testfiles"
+)
+
+func TestApply(t *testing.T) {
+	testenv.NeedsGoPackages(t)
+
+	ar, err := txtar.ParseFile("testdata/apply/apply.txtar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs, err := txtar.FS(ar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := testfiles.CopyToTmp(t, fs)
+
+	opts := new(gofix.ApplyOptions)
+	opts.Config.Dir = dir
+	opts.Config.Env = append(os.Environ(), "GOWORK=off", "GOPROXY=off")
+	res, err := gofix.Apply(opts, "./...")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Converged || res.Rounds != 3 {
+		t.Errorf("Apply: converged=%t after %d rounds, want true after 3", res.Converged, res.Rounds)
+	}
+	var cycles []string
+	for _, cycle := range res.Cycles {
+		cycles = append(cycles, cycle.String())
+	}
+	if diff := gocmp.Diff([]string{"example.com/a.Even, example.com/a.Odd", "field example.com/a.A, field example.com/a.B"}, cycles); diff != "" {
+		t.Errorf("cycles mismatch (-want +got):\n%s", diff)
+	}
+
+	filename := filepath.Join(dir, "b/b.go")
+	want, err := os.ReadFile(filename + ".golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := gocmp.Diff(string(want), string(res.Files[filename])); diff != "" {
+		t.Errorf("%s: mismatch (-want +got):\n%s", filename, diff)
+	}
+
+	// Apply leaves the files unchanged on disk, until Write.
+	got, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := gocmp.Diff(string(res.Original[filename]), string(got)); diff != "" {
+		t.Errorf("%s changed on disk (-original +got):\n%s", filename, diff)
+	}
+	if err := res.Write(); err != nil {
+		t.Fatal(err)
+	}
+	got, err = os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := gocmp.Diff(string(want), string(got)); diff != "" {
+		t.Errorf("%s: mismatch after Write (-want +got):\n%s", filename, diff)
+	}
+}
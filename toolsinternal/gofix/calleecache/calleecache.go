@@ -0,0 +1,189 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package calleecache defines an on-disk cache of the results of
+// [inline.AnalyzeCallee], so that repeated runs of the gofix analyzer
+// over a large, mostly unchanged code base need not analyze each
+// function marked for inlining again.
+//
+// Entries are keyed by a [Key], which combines a fingerprint of the
+// package that declares the function with the function's name. The
+// fingerprint must change whenever the package or any of its
+// dependencies changes in a way that may affect the analysis, as does
+// the fingerprint of export data (see pkgbits.PkgDecoder.Fingerprint),
+// or a hash of the package's source and of its dependencies'
+// fingerprints. A cache is therefore never invalidated explicitly:
+// a changed package simply has a new fingerprint, and [Cache.Trim]
+// removes the entries that are no longer used.
+//
+// The key does not cover the analysis itself, so each entry records
+// the [Version] of the analysis and of its encoding; entries of other
+// versions, and corrupt ones, are treated as misses.
+package calleecache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/tenntenn/exp/toolsinternal/refactor/inline"
+)
+
+// Version is the version of cache entries. It must be incremented
+// whenever the encoding of [inline.Callee] changes, and whenever a
+// change to [inline.AnalyzeCallee] may change its result for some
+// function, since neither is covered by the keys of the entries.
+const Version = 1
+
+// magic begins each cache entry, followed by the Version.
+const magic = "gofix callee cache\n"
+
+// mtimeInterval is the interval at which Get updates the modification
+// time of the entries it uses, which Trim consults.
+const mtimeInterval = 1 * time.Hour
+
+// A Key identifies a cache entry.
+type Key [sha256.Size]byte
+
+// NewKey returns the key of the function of the given name (as
+// reported by types.Func.FullName) in the package with the given
+// fingerprint.
+func NewKey(fingerprint []byte, name string) Key {
+	h := sha256.New()
+	fmt.Fprintf(h, "callee v%d\n", Version)
+	binary.Write(h, binary.LittleEndian, uint64(len(fingerprint)))
+	h.Write(fingerprint)
+	h.Write([]byte(name))
+	var key Key
+	h.Sum(key[:0])
+	return key
+}
+
+func (key Key) String() string { return hex.EncodeToString(key[:]) }
+
+// A Cache is an on-disk cache of Callees, safe for concurrent use by
+// several goroutines and processes.
+type Cache struct {
+	dir string
+}
+
+// Open returns the cache in the named directory, creating it if
+// necessary.
+func Open(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, err
+	}
+	return &Cache{dir: dir}, nil
+}
+
+// Dir returns the directory of the cache.
+func (c *Cache) Dir() string { return c.dir }
+
+// filename returns the name of the file of the entry for key.
+// Entries are spread over 256 subdirectories.
+func (c *Cache) filename(key Key) string {
+	s := key.String()
+	return filepath.Join(c.dir, s[:2], s)
+}
+
+// Get returns the Callee of the entry for key, if any.
+func (c *Cache) Get(key Key) (*inline.Callee, bool) {
+	filename := c.filename(key)
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, false
+	}
+	callee, err := decode(data)
+	if err != nil {
+		os.Remove(filename) // stale or corrupt
+		return nil, false
+	}
+	if info, err := os.Stat(filename); err == nil && time.Since(info.ModTime()) > mtimeInterval {
+		now := time.Now()
+		os.Chtimes(filename, now, now)
+	}
+	return callee, true
+}
+
+// Put records callee as the entry for key.
+func (c *Cache) Put(key Key, callee *inline.Callee) error {
+	data, err := encode(callee)
+	if err != nil {
+		return err
+	}
+	filename := c.filename(key)
+	if err := os.MkdirAll(filepath.Dir(filename), 0777); err != nil {
+		return err
+	}
+	// Write to a temporary file, then rename it, so that
+	// concurrent readers never see a partial entry.
+	f, err := os.CreateTemp(filepath.Dir(filename), "tmp-")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err2 := f.Close(); err == nil {
+		err = err2
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filename)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// Trim removes the entries that have not been used for longer than
+// maxAge, including leftover temporary files.
+func (c *Cache) Trim(maxAge time.Duration) error {
+	cutoff := time.Now().Add(-maxAge)
+	return filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if info, err := d.Info(); err == nil && info.ModTime().Before(cutoff) {
+			if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// encode returns the encoding of a cache entry for callee.
+func encode(callee *inline.Callee) ([]byte, error) {
+	data, err := callee.GobEncode()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.WriteString(magic)
+	binary.Write(&buf, binary.LittleEndian, uint32(Version))
+	buf.Write(data)
+	return buf.Bytes(), nil
+}
+
+// decode decodes a cache entry.
+func decode(data []byte) (*inline.Callee, error) {
+	data, ok := bytes.CutPrefix(data, []byte(magic))
+	if !ok || len(data) < 4 {
+		return nil, errors.New("not a cache entry")
+	}
+	if v := binary.LittleEndian.Uint32(data); v != Version {
+		return nil, fmt.Errorf("cache entry has version %d, want %d", v, Version)
+	}
+	callee := new(inline.Callee)
+	if err := callee.GobDecode(data[4:]); err != nil {
+		return nil, err
+	}
+	return callee, nil
+}
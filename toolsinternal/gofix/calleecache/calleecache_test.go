@@ -0,0 +1,165 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package calleecache_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"go/ast"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/tools/go/packages"
+	"github.com/tenntenn/exp/toolsinternal/gofix/calleecache"
+	"github.com/tenntenn/exp/toolsinternal/refactor/inline"
+	"github.com/tenntenn/exp/toolsinternal/testenv"
+)
+
+func TestCache(t *testing.T) {
+	callee := analyze(t, "F")
+
+	cache, err := calleecache.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := calleecache.NewKey([]byte("fingerprint"), "p.F")
+	if _, ok := cache.Get(key); ok {
+		t.Fatalf("Get before Put: got hit")
+	}
+	if err := cache.Put(key, callee); err != nil {
+		t.Fatal(err)
+	}
+	got, ok := cache.Get(key)
+	if !ok {
+		t.Fatalf("Get after Put: got miss")
+	}
+	if got.String() != callee.String() {
+		t.Errorf("Get returned callee %v, want %v", got, callee)
+	}
+
+	// Other fingerprints and names have other keys.
+	for _, other := range []calleecache.Key{
+		calleecache.NewKey([]byte("fingerprint2"), "p.F"),
+		calleecache.NewKey([]byte("fingerprint"), "p.G"),
+	} {
+		if other == key {
+			t.Errorf("NewKey returned the same key for different inputs")
+		}
+		if _, ok := cache.Get(other); ok {
+			t.Errorf("Get(%v): got hit for key never Put", other)
+		}
+	}
+}
+
+func TestCacheInvalidEntries(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := calleecache.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := calleecache.NewKey([]byte("fingerprint"), "p.F")
+	if err := cache.Put(key, analyze(t, "G")); err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, key.String()[:2], key.String())
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherVersion := bytes.Clone(data)
+	binary.LittleEndian.PutUint32(otherVersion[len("gofix callee cache\n"):], calleecache.Version+1)
+
+	for _, test := range []struct {
+		name string
+		data []byte
+	}{
+		{"truncated", data[:len(data)/2]},
+		{"other version", otherVersion},
+		{"garbage", []byte("not a cache entry")},
+	} {
+		if err := os.WriteFile(filename, test.data, 0666); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := cache.Get(key); ok {
+			t.Errorf("%s: Get returned a hit", test.name)
+		}
+		if _, err := os.Stat(filename); err == nil {
+			t.Errorf("%s: invalid entry was not removed", test.name)
+		}
+	}
+}
+
+func TestTrim(t *testing.T) {
+	cache, err := calleecache.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	callee := analyze(t, "G")
+	old := calleecache.NewKey([]byte("old"), "p.F")
+	recent := calleecache.NewKey([]byte("recent"), "p.F")
+	for _, key := range []calleecache.Key{old, recent} {
+		if err := cache.Put(key, callee); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cache.Trim(time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache.Get(old); !ok {
+		t.Fatalf("Trim removed a recent entry")
+	}
+
+	// Age the old entry.
+	filename := filepath.Join(cache.Dir(), old.String()[:2], old.String())
+	then := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(filename, then, then); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Trim(time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache.Get(old); ok {
+		t.Errorf("Trim did not remove an old entry")
+	}
+	if _, ok := cache.Get(recent); !ok {
+		t.Errorf("Trim removed a recent entry")
+	}
+}
+
+// analyze returns the Callee for the named function of package p in
+// testdata.
+func analyze(t *testing.T, name string) *inline.Callee {
+	t.Helper()
+	testenv.NeedsGoPackages(t)
+	cfg := &packages.Config{Mode: packages.LoadSyntax}
+	pkgs, err := packages.Load(cfg, "./testdata/p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if packages.PrintErrors(pkgs) > 0 {
+		t.Fatal("there were errors loading the package")
+	}
+	pkg := pkgs[0]
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			if decl, ok := decl.(*ast.FuncDecl); ok && decl.Name.Name == name {
+				content, err := os.ReadFile(pkg.Fset.File(file.FileStart).Name())
+				if err != nil {
+					t.Fatal(err)
+				}
+				callee, err := inline.AnalyzeCallee(t.Logf, pkg.Fset, pkg.Types, pkg.TypesInfo, decl, content)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return callee
+			}
+		}
+	}
+	t.Fatalf("no function %s in %s", name, pkg.PkgPath)
+	return nil
+}
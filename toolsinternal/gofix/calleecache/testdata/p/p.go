@@ -0,0 +1,5 @@
+package p
+
+func F(x int) int { return x + 1 }
+
+func G() {}
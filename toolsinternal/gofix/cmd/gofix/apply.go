@@ -0,0 +1,141 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+// This file defines the "apply" subcommand.
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"time"
+
+	"golang.org/x/tools/go/packages"
+	"github.com/tenntenn/exp/toolsinternal/diff"
+	"github.com/tenntenn/exp/toolsinternal/gofix"
+	"github.com/tenntenn/exp/toolsinternal/gofix/calleecache"
+)
+
+const applyUsage = `usage: gofix apply [flags] [packages]
+
+Apply inlines, across the specified packages (default ./...), the calls
+and references marked by "//go:fix inline" directives, and tidies the
+imports of each file it changes. It repeats the analysis until nothing
+more can be inlined, since each round of inlining may expose calls to
+other functions marked for inlining, and prints a summary of the
+changes, including the cycles among the declarations marked for
+inlining, whose uses it does not inline.
+
+Flags:
+`
+
+// apply implements the "apply" subcommand.
+func apply(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("apply", flag.ExitOnError)
+	allowBindingDecl := fs.Bool("allow_binding_decl", false, "permit inlinings that require a 'var params = args' declaration")
+	dir := fs.String("C", "", "run in `dir` instead of the current directory")
+	tests := fs.Bool("test", true, "also apply the fixes to tests")
+	printDiff := fs.Bool("diff", false, "print the changes as a unified diff instead of writing them")
+	maxRounds := fs.Int("rounds", gofix.DefaultMaxRounds, "maximum number of rounds of analysis")
+	cacheDir := fs.String("callee_cache", "", "cache the analysis of functions marked for inlining in `dir`")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), applyUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	patterns := fs.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+	if *allowBindingDecl {
+		gofix.Analyzer.Flags.Set("allow_binding_decl", "true")
+	}
+	if *cacheDir != "" {
+		gofix.Analyzer.Flags.Set("callee_cache", *cacheDir)
+	}
+
+	res, err := gofix.Apply(&gofix.ApplyOptions{
+		Config: packages.Config{
+			Context: ctx,
+			Dir:     *dir,
+			Tests:   *tests,
+		},
+		MaxRounds: *maxRounds,
+	}, patterns...)
+	if err != nil {
+		return err
+	}
+	if *cacheDir != "" {
+		if err := trimCache(*cacheDir); err != nil {
+			return err
+		}
+	}
+	if *printDiff {
+		for _, filename := range slices.Sorted(maps.Keys(res.Files)) {
+			unified, err := diff.ToUnified(filename, filename, string(res.Original[filename]), res.Edits(filename), diff.DefaultContextLines)
+			if err != nil {
+				return err
+			}
+			fmt.Print(unified)
+		}
+		printResult(os.Stderr, res)
+		return nil
+	}
+	if err := res.Write(); err != nil {
+		return err
+	}
+	printResult(os.Stdout, res)
+	return nil
+}
+
+// cacheMaxAge is the age beyond which unused entries of the callee
+// cache are removed.
+const cacheMaxAge = 5 * 24 * time.Hour
+
+// trimCache removes the old entries of the callee cache in dir.
+func trimCache(dir string) error {
+	cache, err := calleecache.Open(dir)
+	if err != nil {
+		return err
+	}
+	return cache.Trim(cacheMaxAge)
+}
+
+// printResult prints a summary of the changes made by gofix.Apply.
+func printResult(w io.Writer, res *gofix.ApplyResult) {
+	fixes := make(map[string]int) // number of fixes applied to each file
+	for _, fix := range res.Fixes {
+		fixes[fix.Posn.Filename]++
+	}
+	fmt.Fprintf(w, "applied %d fixes to %d files in %d packages (%d rounds)\n", len(res.Fixes), len(res.Files), res.Packages, res.Rounds)
+	for _, filename := range slices.Sorted(maps.Keys(fixes)) {
+		fmt.Fprintf(w, "\t%s: %d\n", filename, fixes[filename])
+	}
+	if !res.Converged {
+		fmt.Fprintf(w, "stopped after %d rounds before reaching a fixed point\n", res.Rounds)
+	}
+	if len(res.Cycles) > 0 {
+		fmt.Fprintf(w, "%d cycles among declarations marked for inlining, whose uses were not inlined:\n", len(res.Cycles))
+		for _, cycle := range res.Cycles {
+			fmt.Fprintf(w, "\t%v: %v\n", cycle[0].Posn, cycle)
+		}
+	}
+	if len(res.Conflicts) > 0 {
+		fmt.Fprintf(w, "skipped %d conflicting fixes:\n", len(res.Conflicts))
+		for _, fix := range res.Conflicts {
+			fmt.Fprintf(w, "\t%v\n", fix.Posn)
+		}
+	}
+	if len(res.Unfixed) > 0 {
+		fmt.Fprintf(w, "%d diagnostics without fixes:\n", len(res.Unfixed))
+		for _, diag := range res.Unfixed {
+			fmt.Fprintf(w, "\t%v\n", diag)
+		}
+	}
+}
@@ -15,14 +15,52 @@
 //
 //	$ go run ./internal/gofix/cmd/gofix -fix -test packages...
 //
+// The apply subcommand applies all the fixes, repeating the analysis
+// until nothing more can be inlined, since inlining a call may expose
+// calls to other functions marked for inlining. It tidies the imports
+// of the changed files, and reports the cycles among the functions
+// marked for inlining, whose calls it leaves alone:
+//
+//	$ go run ./internal/gofix/cmd/gofix apply ./...
+//
+// The migrate subcommand adds or upgrades a module requirement in
+// go.mod, then applies all the fixes across the workspace, tidies the
+// imports of the changed files, and prints a summary. For example,
+// to move the callers of the deprecated API of example.com/pkg, marked
+// for inlining by calls to its successor example.com/pkg/v2:
+//
+//	$ go run ./internal/gofix/cmd/gofix migrate example.com/pkg/v2@latest ./...
+//
 // This internal command is not officially supported. In the long
 // term, we plan to migrate this functionality into "go fix"; see Go
 // issues https//go.dev/issue/32816, 71859, 73605.
 package main
 
 import (
+	"context"
+	"log"
+	"os"
+
 	"golang.org/x/tools/go/analysis/singlechecker"
 	"github.com/tenntenn/exp/toolsinternal/gofix"
 )
 
-func main() { singlechecker.Main(gofix.Analyzer) }
+// subcommands maps the name of each subcommand to its implementation.
+var subcommands = map[string]func(ctx context.Context, args []string) error{
+	"apply":   apply,
+	"migrate": migrate,
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := subcommands[os.Args[1]]; ok {
+			log.SetFlags(0)
+			log.SetPrefix("gofix " + os.Args[1] + ": ")
+			if err := cmd(context.Background(), os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+	}
+	singlechecker.Main(gofix.Analyzer)
+}
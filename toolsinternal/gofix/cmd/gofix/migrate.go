@@ -0,0 +1,220 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+// This file defines the "migrate" subcommand.
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+	"github.com/tenntenn/exp/toolsinternal/gocommand"
+	"github.com/tenntenn/exp/toolsinternal/gofix"
+	"github.com/tenntenn/exp/toolsinternal/imports"
+)
+
+const migrateUsage = `usage: gofix migrate [flags] module@version [packages]
+
+Migrate adds or upgrades the requirement on module in go.mod, as if by
+"go get module@version", then inlines, across the specified packages
+(default ./...) and their tests, the calls and references marked by
+"//go:fix inline" directives, and tidies the imports of each file it
+changes. It prints a summary of the changes. If the fixes cannot be
+computed, for example because the packages do not build with the new
+version, it restores go.mod and go.sum, and changes no other file.
+
+The migrated code may no longer need the module that provided the
+"//go:fix inline" directives; the -tidy flag removes such requirements
+by running "go mod tidy" after the fixes are applied.
+
+Flags:
+`
+
+// A migrationReport summarizes the changes made by a migration.
+type migrationReport struct {
+	module, oldVersion, newVersion string
+	*gofix.ApplyResult
+}
+
+// migrate implements the "migrate" subcommand.
+func migrate(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	allowBindingDecl := fs.Bool("allow_binding_decl", false, "permit inlinings that require a 'var params = args' declaration")
+	dir := fs.String("C", "", "run in `dir` instead of the current directory")
+	tidy := fs.Bool("tidy", false, "run 'go mod tidy' after applying the fixes")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), migrateUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	module, version, ok := strings.Cut(fs.Arg(0), "@")
+	if !ok || module == "" || version == "" {
+		return fmt.Errorf("invalid module query %q, want module@version", fs.Arg(0))
+	}
+	patterns := fs.Args()[1:]
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+	if *allowBindingDecl {
+		gofix.Analyzer.Flags.Set("allow_binding_decl", "true")
+	}
+
+	report, err := runMigrate(ctx, *dir, module, version, patterns, *tidy)
+	if err != nil {
+		return err
+	}
+	report.print(os.Stdout)
+	return nil
+}
+
+// runMigrate performs a migration, in the directory dir, of the
+// packages denoted by patterns to the specified version of module.
+// If it fails before the fixes are written, it restores go.mod and
+// go.sum.
+func runMigrate(ctx context.Context, dir, module, version string, patterns []string, tidy bool) (_ *migrationReport, err error) {
+	runner := new(gocommand.Runner)
+	report := &migrationReport{module: module}
+
+	// Save go.mod and go.sum, which "go get" updates before the
+	// fixes are computed.
+	restore, err := saveModFiles(ctx, runner, dir)
+	if err != nil {
+		return nil, err
+	}
+	written := false
+	defer func() {
+		if err != nil && !written {
+			if err2 := restore(); err2 != nil {
+				err = fmt.Errorf("%v (restoring go.mod: %v)", err, err2)
+			}
+		}
+	}()
+
+	// Update the requirement.
+	if report.oldVersion, err = moduleVersion(ctx, runner, dir, module); err != nil {
+		return nil, err
+	}
+	if _, err := runner.Run(ctx, gocommand.Invocation{
+		Verb:       "get",
+		Args:       []string{module + "@" + version},
+		WorkingDir: dir,
+	}); err != nil {
+		return nil, err
+	}
+	if report.newVersion, err = moduleVersion(ctx, runner, dir, module); err != nil {
+		return nil, err
+	}
+
+	// Apply the fixes.
+	opts := &imports.Options{
+		Env: &imports.ProcessEnv{
+			GocmdRunner: runner,
+			WorkingDir:  dir,
+		},
+		Comments:  true,
+		TabIndent: true,
+		TabWidth:  8,
+	}
+	report.ApplyResult, err = gofix.Apply(&gofix.ApplyOptions{
+		Config: packages.Config{
+			Context: ctx,
+			Dir:     dir,
+			Tests:   true,
+		},
+		Imports: opts,
+	}, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	written = true
+	if err := report.Write(); err != nil {
+		return nil, err
+	}
+
+	if tidy {
+		if _, err := runner.Run(ctx, gocommand.Invocation{
+			Verb:       "mod",
+			Args:       []string{"tidy"},
+			WorkingDir: dir,
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// saveModFiles reads the go.mod and go.sum files of the main module
+// in dir, and returns a function that restores them.
+func saveModFiles(ctx context.Context, runner *gocommand.Runner, dir string) (restore func() error, _ error) {
+	stdout, err := runner.Run(ctx, gocommand.Invocation{
+		Verb:       "env",
+		Args:       []string{"GOMOD"},
+		WorkingDir: dir,
+	})
+	if err != nil {
+		return nil, err
+	}
+	gomod := strings.TrimSpace(stdout.String())
+	if gomod == "" || gomod == os.DevNull {
+		return nil, fmt.Errorf("no go.mod file in %s", dir)
+	}
+	gosum := strings.TrimSuffix(gomod, ".mod") + ".sum"
+	modData, err := os.ReadFile(gomod)
+	if err != nil {
+		return nil, err
+	}
+	sumData, err := os.ReadFile(gosum)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	hasSum := err == nil
+	return func() error {
+		if err := os.WriteFile(gomod, modData, 0666); err != nil {
+			return err
+		}
+		if !hasSum {
+			if err := os.Remove(gosum); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			return nil
+		}
+		return os.WriteFile(gosum, sumData, 0666)
+	}, nil
+}
+
+// moduleVersion returns the selected version of the module, or "" if
+// the main module does not depend on it.
+func moduleVersion(ctx context.Context, runner *gocommand.Runner, dir, module string) (string, error) {
+	stdout, err := runner.Run(ctx, gocommand.Invocation{
+		Verb:       "list",
+		Args:       []string{"-m", "-e", "-f", "{{if not .Error}}{{.Version}}{{end}}", module},
+		WorkingDir: dir,
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+func (r *migrationReport) print(w io.Writer) {
+	switch {
+	case r.oldVersion == "":
+		fmt.Fprintf(w, "added %s %s\n", r.module, r.newVersion)
+	case r.oldVersion != r.newVersion:
+		fmt.Fprintf(w, "upgraded %s %s => %s\n", r.module, r.oldVersion, r.newVersion)
+	default:
+		fmt.Fprintf(w, "%s %s already required\n", r.module, r.newVersion)
+	}
+	printResult(w, r.ApplyResult)
+}
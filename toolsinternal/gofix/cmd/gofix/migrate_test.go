@@ -0,0 +1,99 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	gocmp "github.com/google/go-cmp/cmp"
+	"golang.org/x/tools/txtar"
+	"github.com/tenntenn/exp/toolsinternal/testenv"
+	"github.com/tenntenn/exp/toolsinternal/testfiles"
+)
+
+// setupMigrate copies testdata/migrate.txtar to a temporary directory,
+// and returns the directory of its app module.
+func setupMigrate(t *testing.T) string {
+	testenv.NeedsGoPackages(t)
+	t.Setenv("GOWORK", "off")
+	t.Setenv("GOPROXY", "off")
+
+	ar, err := txtar.ParseFile("testdata/migrate.txtar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs, err := txtar.FS(ar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(testfiles.CopyToTmp(t, fs), "app")
+}
+
+func TestMigrate(t *testing.T) {
+	dir := setupMigrate(t)
+
+	report, err := runMigrate(context.Background(), dir, "example.com/lib", "v1.1.0", []string{"./..."}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.oldVersion != "v1.0.0" || report.newVersion != "v1.1.0" {
+		t.Errorf("migrated from %q to %q, want v1.0.0 to v1.1.0", report.oldVersion, report.newVersion)
+	}
+	if len(report.Fixes) != 1 {
+		t.Errorf("got %d fixes, want 1", len(report.Fixes))
+	}
+
+	filename := filepath.Join(dir, "app.go")
+	got, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(filename + ".golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := gocmp.Diff(string(want), string(got)); diff != "" {
+		t.Errorf("%s: mismatch (-want +got):\n%s", filename, diff)
+	}
+	gomod, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req := "require example.com/lib v1.1.0"; !strings.Contains(string(gomod), req) {
+		t.Errorf("go.mod does not contain %q:\n%s", req, gomod)
+	}
+}
+
+func TestMigrateRestoresGoMod(t *testing.T) {
+	dir := setupMigrate(t)
+
+	// The packages do not build, so the fixes cannot be computed.
+	if err := os.WriteFile(filepath.Join(dir, "bad.go"), []byte("package app\n\nvar _ int = \"\"\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	gomod := filepath.Join(dir, "go.mod")
+	before, err := os.ReadFile(gomod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = runMigrate(context.Background(), dir, "example.com/lib", "v1.1.0", []string{"./..."}, false)
+	if err == nil || !strings.Contains(err.Error(), "packages contain errors") {
+		t.Fatalf("migration of packages with errors returned %v", err)
+	}
+	after, err := os.ReadFile(gomod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := gocmp.Diff(string(before), string(after)); diff != "" {
+		t.Errorf("go.mod not restored (-before +after):\n%s", diff)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "go.sum")); !os.IsNotExist(err) {
+		t.Errorf("go.sum created: %v", err)
+	}
+}
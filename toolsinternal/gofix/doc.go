@@ -4,14 +4,15 @@
 
 /*
 Package gofix defines an Analyzer that inlines calls to functions
-and uses of constants
+and methods, and uses of constants, variables, type aliases and fields
 marked with a "//go:fix inline" directive.
 
 # Analyzer gofix
 
 gofix: apply fixes based on go:fix comment directives
 
-The gofix analyzer inlines functions and constants that are marked for inlining.
+The gofix analyzer inlines functions, methods, constants, variables,
+type aliases and struct fields that are marked for inlining.
 
 ## Functions
 
@@ -62,6 +63,32 @@ inliner machinery is capable of replacing f by a function literal,
 func(){...}(). However, the gofix analyzer discards all such
 "literalizations" unconditionally, again on grounds of style.)
 
+Authors of packages that use "//go:fix inline" directives may wish to
+know why some calls were not inlined. The -gofix.explain flag causes
+the analyzer to report, for each call that it did not inline because
+the inlining would require a literalization or a disallowed binding
+declaration, the reasons why the call could not be reduced, such as
+the order of effects of the arguments, shadowing of names, references
+to unexported declarations, or the use of defer or return statements
+in the function body.
+
+## Methods
+
+A method may be marked for inlining like a function. Calls to the
+method are inlined, but the method itself remains, so it continues to
+satisfy any interfaces that require it.
+
+A method whose body merely forwards its receiver and parameters to
+another method of the same type, like this one:
+
+	// Deprecated: use Close.
+	//go:fix inline
+	func (f *File) Shutdown() error { return f.Close() }
+
+may also be used as a method value or method expression, as in
+f.Shutdown or (*File).Shutdown. This analyzer will recommend that such
+uses select the other method instead, as in f.Close.
+
 ## Constants
 
 Given a constant that is marked for inlining, like this one:
@@ -94,12 +121,62 @@ or before a group, applying to every constant in the group:
 	    Val = Value
 	)
 
+## Variables
+
+Given a package-level variable that is marked for inlining, like this one:
+
+	//go:fix inline
+	var ErrClosed = fs.ErrClosed
+
+this analyzer will recommend that uses of ErrClosed should be replaced
+with fs.ErrClosed. The variable must be initialized with the name of
+another package-level variable of the same type. As the variables are
+distinct, only the uses that read ErrClosed are replaced: assignments
+to it, and expressions that take its address, are left unchanged. The
+directive may be placed before a var declaration or a group, as for
+constants.
+
+## Fields
+
+A struct field may be marked for inlining by naming the field that
+replaces it, which must have the same type and tag:
+
+	type Options struct {
+		Timeout time.Duration
+
+		// Deprecated: use Timeout.
+		//go:fix inline Timeout
+		Deadline time.Duration
+	}
+
+This analyzer will recommend that selections x.Deadline that read the
+field be replaced by x.Timeout, where doing so does not change which
+field is selected. As the fields are distinct variables, updates of
+Deadline, including keys of Options literals, are left unchanged.
+
+## Caching
+
+Each run of the analyzer analyzes every function marked for inlining
+afresh. In large code bases, such as in continuous integration, the
+-gofix.callee_cache=dir flag causes the analyzer to record the results
+of these analyses in the named directory, keyed by a fingerprint of the
+package and its dependencies, and to reuse them in later runs for
+packages that have not changed.
+
 The proposal https://go.dev/issue/32816 introduces the "//go:fix" directives.
 
 You can use this (officially unsupported) command to apply gofix fixes en masse:
 
 	$ go run golang.org/x/tools/internal/gofix/cmd/gofix@latest -test ./...
 
+Inlining a call may expose calls to other functions marked for
+inlining, so this command may need to be run repeatedly. Its apply
+subcommand instead repeats the analysis until nothing more can be
+inlined, and reports any cycles among the functions marked for
+inlining, whose calls it leaves alone:
+
+	$ go run golang.org/x/tools/internal/gofix/cmd/gofix@latest apply ./...
+
 (Do not use "go get -tool" to add gopls as a dependency of your
 module; gopls commands must be built from their release branch.)
 
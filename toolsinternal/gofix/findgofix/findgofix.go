@@ -17,30 +17,35 @@ import (
 	"go/ast"
 	"go/token"
 	"go/types"
+	"strings"
 
 	"golang.org/x/tools/go/analysis"
 	"golang.org/x/tools/go/ast/inspector"
 	internalastutil "github.com/tenntenn/exp/toolsinternal/astutil"
+	"github.com/tenntenn/exp/toolsinternal/typesinternal"
 )
 
 // A Handler handles language entities with go:fix directives.
 type Handler interface {
-	HandleFunc(*ast.FuncDecl)
+	HandleFunc(*ast.FuncDecl) // functions and methods
 	HandleAlias(*ast.TypeSpec)
 	HandleConst(name, rhs *ast.Ident)
+	HandleVar(name, rhs *ast.Ident)
+	HandleField(name *ast.Ident, rhs *types.Var)
 }
 
-// Find finds functions and constants annotated with an appropriate "//go:fix"
-// comment (the syntax proposed by #32816), and calls handler methods for each one.
+// Find finds functions, methods, constants, variables, type aliases and
+// struct fields annotated with an appropriate "//go:fix" comment (the
+// syntax proposed by #32816), and calls handler methods for each one.
 // h may be nil.
 func Find(pass *analysis.Pass, root inspector.Cursor, h Handler) {
-	for cur := range root.Preorder((*ast.FuncDecl)(nil), (*ast.GenDecl)(nil)) {
+	for cur := range root.Preorder((*ast.FuncDecl)(nil), (*ast.GenDecl)(nil), (*ast.StructType)(nil)) {
 		switch decl := cur.Node().(type) {
 		case *ast.FuncDecl:
 			findFunc(decl, h)
 
 		case *ast.GenDecl:
-			if decl.Tok != token.CONST && decl.Tok != token.TYPE {
+			if decl.Tok == token.IMPORT {
 				continue
 			}
 			declInline := hasFixInline(decl.Doc)
@@ -50,10 +55,19 @@ func Find(pass *analysis.Pass, root inspector.Cursor, h Handler) {
 				case *ast.TypeSpec: // Tok == TYPE
 					findAlias(pass, spec, declInline, h)
 
-				case *ast.ValueSpec: // Tok == CONST
-					findConst(pass, spec, declInline, h)
+				case *ast.ValueSpec: // Tok == CONST or VAR
+					if decl.Tok == token.CONST {
+						findConst(pass, spec, declInline, h)
+					} else {
+						findVar(pass, spec, declInline, h)
+					}
 				}
 			}
+
+		case *ast.StructType:
+			for _, field := range decl.Fields.List {
+				findField(pass, decl, field, h)
+			}
 		}
 	}
 }
@@ -129,15 +143,124 @@ func findConst(pass *analysis.Pass, spec *ast.ValueSpec, declInline bool, h Hand
 	}
 }
 
+func findVar(pass *analysis.Pass, spec *ast.ValueSpec, declInline bool, h Handler) {
+	if !declInline && !hasFixInline(spec.Doc) {
+		return
+	}
+	for i, nameIdent := range spec.Names {
+		lhs, ok := pass.TypesInfo.Defs[nameIdent].(*types.Var)
+		if !ok || nameIdent.Name == "_" {
+			continue
+		}
+		if !typesinternal.IsPackageLevel(lhs) {
+			pass.Reportf(nameIdent.Pos(), "invalid //go:fix inline directive: not a package-level variable")
+			return
+		}
+		if i >= len(spec.Values) {
+			pass.Reportf(nameIdent.Pos(), "invalid //go:fix inline directive: var has no value")
+			return
+		}
+		var rhsIdent *ast.Ident
+		switch val := spec.Values[i].(type) {
+		case *ast.Ident:
+			rhsIdent = val
+		case *ast.SelectorExpr:
+			rhsIdent = val.Sel
+		}
+		rhs, ok := pass.TypesInfo.Uses[rhsIdent].(*types.Var)
+		if rhsIdent == nil || !ok || !typesinternal.IsPackageLevel(rhs) {
+			pass.Reportf(spec.Values[i].Pos(), "invalid //go:fix inline directive: var value is not the name of another package-level variable")
+			return
+		}
+		if !types.Identical(lhs.Type(), rhs.Type()) {
+			pass.Reportf(spec.Values[i].Pos(), "invalid //go:fix inline directive: var type %s differs from that of %s", lhs.Type(), rhs.Name())
+			return
+		}
+		if h != nil {
+			h.HandleVar(nameIdent, rhsIdent)
+		}
+	}
+}
+
+// findField finds a field of a struct type marked with a
+// "//go:fix inline F" directive, where F is another field of the
+// struct, of the same type and tag, that replaces it.
+func findField(pass *analysis.Pass, st *ast.StructType, field *ast.Field, h Handler) {
+	args, ok := fixInlineArgs(field.Doc)
+	if !ok {
+		return
+	}
+	if args == "" {
+		pass.Reportf(field.Pos(), "invalid //go:fix inline directive: field directive must name the replacement field")
+		return
+	}
+	if len(field.Names) == 0 {
+		pass.Reportf(field.Pos(), "invalid //go:fix inline directive: embedded field")
+		return
+	}
+	styp, ok := pass.TypesInfo.TypeOf(st).(*types.Struct)
+	if !ok {
+		return // ill-typed
+	}
+	var (
+		rhs  *types.Var
+		tags = make(map[*types.Var]string)
+	)
+	for i := range styp.NumFields() {
+		f := styp.Field(i)
+		tags[f] = styp.Tag(i)
+		if f.Name() == args {
+			rhs = f
+		}
+	}
+	if rhs == nil {
+		pass.Reportf(field.Pos(), "invalid //go:fix inline directive: no field %s in struct", args)
+		return
+	}
+	for _, nameIdent := range field.Names {
+		lhs, ok := pass.TypesInfo.Defs[nameIdent].(*types.Var)
+		if !ok || nameIdent.Name == "_" {
+			continue
+		}
+		if lhs == rhs {
+			pass.Reportf(nameIdent.Pos(), "invalid //go:fix inline directive: field %s refers to itself", lhs.Name())
+			return
+		}
+		if !types.Identical(lhs.Type(), rhs.Type()) {
+			pass.Reportf(nameIdent.Pos(), "invalid //go:fix inline directive: type of field %s differs from that of %s", lhs.Name(), rhs.Name())
+			return
+		}
+		// The tags are visible to reflection, for example in the
+		// encoding of the struct.
+		if tags[lhs] != tags[rhs] {
+			pass.Reportf(nameIdent.Pos(), "invalid //go:fix inline directive: tag of field %s differs from that of %s", lhs.Name(), rhs.Name())
+			return
+		}
+		if h != nil {
+			h.HandleField(nameIdent, rhs)
+		}
+	}
+}
+
 // hasFixInline reports the presence of a "//go:fix inline" directive
 // in the comments.
 func hasFixInline(cg *ast.CommentGroup) bool {
+	args, ok := fixInlineArgs(cg)
+	return ok && args == ""
+}
+
+// fixInlineArgs returns the arguments of the first "//go:fix inline"
+// directive in the comments, such as "F" in "//go:fix inline F", and
+// reports whether there is one.
+func fixInlineArgs(cg *ast.CommentGroup) (string, bool) {
 	for _, d := range internalastutil.Directives(cg) {
-		if d.Tool == "go" && d.Name == "fix" && d.Args == "inline" {
-			return true
+		if d.Tool == "go" && d.Name == "fix" {
+			if args, ok := strings.CutPrefix(d.Args, "inline"); ok && (args == "" || args[0] == ' ' || args[0] == '\t') {
+				return strings.TrimSpace(args), true
+			}
 		}
 	}
-	return false
+	return "", false
 }
 
 var builtinIota = types.Universe.Lookup("iota")
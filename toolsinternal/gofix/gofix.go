@@ -5,11 +5,13 @@
 package gofix
 
 import (
+	"crypto/sha256"
 	"fmt"
 	"go/ast"
 	"go/token"
 	"go/types"
 	"iter"
+	"path/filepath"
 	"slices"
 	"strings"
 
@@ -21,7 +23,7 @@ import (
 	"golang.org/x/tools/go/ast/inspector"
 	"golang.org/x/tools/go/types/typeutil"
 	"github.com/tenntenn/exp/toolsinternal/analysisinternal"
-	"github.com/tenntenn/exp/toolsinternal/diff"
+	"github.com/tenntenn/exp/toolsinternal/gofix/calleecache"
 	"github.com/tenntenn/exp/toolsinternal/gofix/findgofix"
 	"github.com/tenntenn/exp/toolsinternal/refactor/inline"
 	"github.com/tenntenn/exp/toolsinternal/typesinternal"
@@ -38,44 +40,122 @@ var Analyzer = &analysis.Analyzer{
 	FactTypes: []analysis.Fact{
 		(*goFixInlineFuncFact)(nil),
 		(*goFixInlineConstFact)(nil),
+		(*goFixInlineVarFact)(nil),
+		(*goFixInlineFieldFact)(nil),
 		(*goFixInlineAliasFact)(nil),
+		(*goFixPackageFact)(nil),
 	},
 	Requires: []*analysis.Analyzer{inspect.Analyzer},
 }
 
-var allowBindingDecl bool
+var (
+	allowBindingDecl bool
+	explain          bool
+	calleeCacheDir   string
+)
 
 func init() {
 	Analyzer.Flags.BoolVar(&allowBindingDecl, "allow_binding_decl", false,
 		"permit inlinings that require a 'var params = args' declaration")
+	Analyzer.Flags.BoolVar(&explain, "explain", false,
+		"report why calls that require a literalization or a disallowed binding declaration were not inlined")
+	Analyzer.Flags.StringVar(&calleeCacheDir, "callee_cache", "",
+		"cache the analysis of functions marked for inlining in `dir`, for use by later runs")
 }
 
 // analyzer holds the state for this analysis.
 type analyzer struct {
 	pass *analysis.Pass
 	root inspector.Cursor
+	// content of files that replaces that on disk (see Apply)
+	overlay map[string][]byte
 	// memoization of repeated calls for same file.
 	fileContent map[string][]byte
 	// memoization of fact imports (nil => no fact)
-	inlinableFuncs   map[*types.Func]*inline.Callee
+	inlinableFuncs   map[*types.Func]*goFixInlineFuncFact
 	inlinableConsts  map[*types.Const]*goFixInlineConstFact
+	inlinableVars    map[*types.Var]*goFixInlineVarFact
+	inlinableFields  map[*types.Var]*goFixInlineFieldFact
 	inlinableAliases map[*types.TypeName]*goFixInlineAliasFact
+	// functions to which a fix adds a label
+	labeled map[ast.Node]bool
+	// cache of callee analyses, and fingerprint of the package (nil => no cache)
+	cache       *calleecache.Cache
+	fingerprint []byte
 }
 
 func run(pass *analysis.Pass) (any, error) {
+	return analyze(pass, nil)
+}
+
+// overlayAnalyzer returns a copy of Analyzer that reads the content of
+// the files in overlay from it rather than from disk, as Apply requires
+// for the files it changes.
+func overlayAnalyzer(overlay map[string][]byte) *analysis.Analyzer {
+	a := *Analyzer
+	a.Run = func(pass *analysis.Pass) (any, error) { return analyze(pass, overlay) }
+	return &a
+}
+
+// analyze runs the analysis, with the file content of overlay, if any.
+func analyze(pass *analysis.Pass, overlay map[string][]byte) (any, error) {
 	a := &analyzer{
 		pass:             pass,
+		overlay:          overlay,
 		root:             pass.ResultOf[inspect.Analyzer].(*inspector.Inspector).Root(),
 		fileContent:      make(map[string][]byte),
-		inlinableFuncs:   make(map[*types.Func]*inline.Callee),
+		inlinableFuncs:   make(map[*types.Func]*goFixInlineFuncFact),
 		inlinableConsts:  make(map[*types.Const]*goFixInlineConstFact),
+		inlinableVars:    make(map[*types.Var]*goFixInlineVarFact),
+		inlinableFields:  make(map[*types.Var]*goFixInlineFieldFact),
 		inlinableAliases: make(map[*types.TypeName]*goFixInlineAliasFact),
+		labeled:          make(map[ast.Node]bool),
+	}
+	if calleeCacheDir != "" {
+		cache, err := calleecache.Open(calleeCacheDir)
+		if err != nil {
+			return nil, err
+		}
+		if fp, ok := a.packageFingerprint(); ok {
+			pass.ExportPackageFact(&goFixPackageFact{Fingerprint: fp})
+			a.cache, a.fingerprint = cache, fp
+		}
 	}
 	findgofix.Find(pass, a.root, a)
 	a.inline()
 	return nil, nil
 }
 
+// packageFingerprint returns a hash of the source of the package and
+// of the fingerprints of its direct dependencies, and thus, indirectly,
+// of all of them. It reports false if a dependency has no fingerprint.
+func (a *analyzer) packageFingerprint() ([]byte, bool) {
+	h := sha256.New()
+	fmt.Fprintf(h, "package %q %s\n", a.pass.Pkg.Path(), a.pass.Pkg.GoVersion())
+	for _, file := range a.pass.Files {
+		content, err := a.readFile(file)
+		if err != nil {
+			return nil, false
+		}
+		fmt.Fprintf(h, "file %q %d\n", filepath.Base(a.pass.Fset.File(file.FileStart).Name()), len(content))
+		h.Write(content)
+	}
+	imports := slices.SortedFunc(slices.Values(a.pass.Pkg.Imports()), func(x, y *types.Package) int {
+		return strings.Compare(x.Path(), y.Path())
+	})
+	for _, imp := range imports {
+		if imp.Path() == "unsafe" {
+			continue
+		}
+		var fact goFixPackageFact
+		if !a.pass.ImportPackageFact(imp, &fact) {
+			return nil, false
+		}
+		fmt.Fprintf(h, "import %q %x\n", imp.Path(), fact.Fingerprint)
+	}
+	return h.Sum(nil), true
+}
+
 // HandleFunc exports a fact for functions marked with go:fix.
 func (a *analyzer) HandleFunc(decl *ast.FuncDecl) {
 	content, err := a.readFile(decl)
@@ -83,14 +163,94 @@ func (a *analyzer) HandleFunc(decl *ast.FuncDecl) {
 		a.pass.Reportf(decl.Doc.Pos(), "invalid inlining candidate: cannot read source file: %v", err)
 		return
 	}
-	callee, err := inline.AnalyzeCallee(discard, a.pass.Fset, a.pass.Pkg, a.pass.TypesInfo, decl, content)
+	fn := a.pass.TypesInfo.Defs[decl.Name].(*types.Func)
+	callee, err := a.analyzeCallee(fn, decl, content)
 	if err != nil {
 		a.pass.Reportf(decl.Doc.Pos(), "invalid inlining candidate: %v", err)
 		return
 	}
-	fn := a.pass.TypesInfo.Defs[decl.Name].(*types.Func)
-	a.pass.ExportObjectFact(fn, &goFixInlineFuncFact{callee})
-	a.inlinableFuncs[fn] = callee
+	fact := &goFixInlineFuncFact{Callee: callee, Forward: forwardee(a.pass.TypesInfo, decl)}
+	a.pass.ExportObjectFact(fn, fact)
+	a.inlinableFuncs[fn] = fact
+}
+
+// analyzeCallee returns the result of inline.AnalyzeCallee for the
+// declaration of fn, from the cache if possible.
+func (a *analyzer) analyzeCallee(fn *types.Func, decl *ast.FuncDecl, content []byte) (*inline.Callee, error) {
+	if a.cache == nil {
+		return inline.AnalyzeCallee(discard, a.pass.Fset, a.pass.Pkg, a.pass.TypesInfo, decl, content)
+	}
+	key := calleecache.NewKey(a.fingerprint, fn.FullName())
+	if callee, ok := a.cache.Get(key); ok {
+		return callee, nil
+	}
+	callee, err := inline.AnalyzeCallee(discard, a.pass.Fset, a.pass.Pkg, a.pass.TypesInfo, decl, content)
+	if err != nil {
+		return nil, err // errors are not cached
+	}
+	a.cache.Put(key, callee) // ignore error; the cache is an optimization
+	return callee, nil
+}
+
+// forwardee returns the name of the method to which the method decl
+// forwards its receiver and parameters unchanged, as in
+//
+//	func (t T) Old(x int) error { return t.New(x) }
+//
+// or "" if decl is not such a forwarding method. A method value or
+// expression that selects a forwarding method may be replaced by one
+// that selects the method to which it forwards.
+func forwardee(info *types.Info, decl *ast.FuncDecl) string {
+	if decl.Recv == nil || len(decl.Recv.List[0].Names) == 0 || len(decl.Body.List) != 1 {
+		return ""
+	}
+	fn := info.Defs[decl.Name].(*types.Func)
+	sig := fn.Type().(*types.Signature)
+
+	var call *ast.CallExpr
+	switch stmt := decl.Body.List[0].(type) {
+	case *ast.ReturnStmt:
+		if len(stmt.Results) == 1 {
+			call, _ = stmt.Results[0].(*ast.CallExpr)
+		}
+	case *ast.ExprStmt:
+		if sig.Results().Len() == 0 {
+			call, _ = stmt.X.(*ast.CallExpr)
+		}
+	}
+	if call == nil || call.Ellipsis.IsValid() != sig.Variadic() || len(call.Args) != sig.Params().Len() {
+		return ""
+	}
+
+	// The call must be recv.m(params...), where m is another method
+	// of the same type, of the same signature.
+	sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok {
+		return ""
+	}
+	seln := info.Selections[sel]
+	if seln == nil || seln.Kind() != types.MethodVal || len(seln.Index()) != 1 ||
+		seln.Obj() == fn || !types.Identical(seln.Type(), sig) {
+		return ""
+	}
+	if x, ok := ast.Unparen(sel.X).(*ast.Ident); !ok || info.Uses[x] != sig.Recv() {
+		return ""
+	}
+	for i, arg := range call.Args {
+		if id, ok := ast.Unparen(arg).(*ast.Ident); !ok || info.Uses[id] != sig.Params().At(i) {
+			return ""
+		}
+	}
+
+	// A method with a value receiver cannot forward to one with a
+	// pointer receiver, as not every value that has the former
+	// has the latter.
+	m := seln.Obj().(*types.Func)
+	if is[*types.Pointer](m.Type().(*types.Signature).Recv().Type()) &&
+		!is[*types.Pointer](sig.Recv().Type()) {
+		return ""
+	}
+	return m.Name()
 }
 
 // HandleAlias exports a fact for aliases marked with go:fix.
@@ -107,6 +267,37 @@ func (a *analyzer) HandleAlias(spec *ast.TypeSpec) {
 	}
 }
 
+// HandleVar exports a fact for package-level variables marked with go:fix.
+func (a *analyzer) HandleVar(nameIdent, rhsIdent *ast.Ident) {
+	lhs := a.pass.TypesInfo.Defs[nameIdent].(*types.Var)
+	rhs := a.pass.TypesInfo.Uses[rhsIdent].(*types.Var) // checked by findgofix
+	v := &goFixInlineVarFact{
+		RHSName:    rhs.Name(),
+		RHSPkgName: rhs.Pkg().Name(),
+		RHSPkgPath: rhs.Pkg().Path(),
+	}
+	if rhs.Pkg() == a.pass.Pkg {
+		v.rhsObj = rhs
+	}
+	a.inlinableVars[lhs] = v
+	// As with constants, create a fact only if the LHS is exported.
+	if lhs.Exported() {
+		a.pass.ExportObjectFact(lhs, v)
+	}
+}
+
+// HandleField exports a fact for struct fields marked with go:fix.
+func (a *analyzer) HandleField(nameIdent *ast.Ident, rhs *types.Var) {
+	lhs := a.pass.TypesInfo.Defs[nameIdent].(*types.Var)
+	field := &goFixInlineFieldFact{RHSName: rhs.Name()}
+	a.inlinableFields[lhs] = field
+	// The fact of a field of a local type is not encodable,
+	// and is silently discarded.
+	if lhs.Exported() {
+		a.pass.ExportObjectFact(lhs, field)
+	}
+}
+
 // HandleConst exports a fact for constants marked with go:fix.
 func (a *analyzer) HandleConst(nameIdent, rhsIdent *ast.Ident) {
 	lhs := a.pass.TypesInfo.Defs[nameIdent].(*types.Const)
@@ -129,7 +320,8 @@ func (a *analyzer) HandleConst(nameIdent, rhsIdent *ast.Ident) {
 }
 
 // inline inlines each static call to an inlinable function
-// and each reference to an inlinable constant or type alias.
+// and each reference to an inlinable constant, variable, field,
+// type alias, or forwarding method.
 //
 // TODO(adonovan):  handle multiple diffs that each add the same import.
 func (a *analyzer) inline() {
@@ -144,6 +336,14 @@ func (a *analyzer) inline() {
 				a.inlineAlias(t, cur)
 			case *types.Const:
 				a.inlineConst(t, cur)
+			case *types.Var:
+				if t.IsField() {
+					a.inlineField(t, cur)
+				} else {
+					a.inlineVar(t, cur)
+				}
+			case *types.Func:
+				a.inlineMethodValue(t, cur)
 			}
 		}
 	}
@@ -153,17 +353,11 @@ func (a *analyzer) inline() {
 func (a *analyzer) inlineCall(call *ast.CallExpr, cur inspector.Cursor) {
 	if fn := typeutil.StaticCallee(a.pass.TypesInfo, call); fn != nil {
 		// Inlinable?
-		callee, ok := a.inlinableFuncs[fn]
-		if !ok {
-			var fact goFixInlineFuncFact
-			if a.pass.ImportObjectFact(fn, &fact) {
-				callee = fact.Callee
-				a.inlinableFuncs[fn] = callee
-			}
-		}
-		if callee == nil {
+		fact := a.funcFact(fn)
+		if fact == nil {
 			return // nope
 		}
+		callee := fact.Callee
 
 		// Inline the call.
 		content, err := a.readFile(call)
@@ -180,7 +374,8 @@ func (a *analyzer) inlineCall(call *ast.CallExpr, cur inspector.Cursor) {
 			Call:    call,
 			Content: content,
 		}
-		res, err := inline.Inline(caller, callee, &inline.Options{Logf: discard})
+		var report inline.InlineReport
+		res, err := inline.Inline(caller, callee, &inline.Options{Logf: discard, Report: &report})
 		if err != nil {
 			a.pass.Reportf(call.Lparen, "%v", err)
 			return
@@ -193,7 +388,11 @@ func (a *analyzer) inlineCall(call *ast.CallExpr, cur inspector.Cursor) {
 			// (Unfortunately the inliner is very timid,
 			// and often literalizes when it cannot prove that
 			// reducing the call is safe; the user of this tool
-			// has no indication of what the problem is.)
+			// has no indication of what the problem is,
+			// unless they ask for an explanation.)
+			if explain {
+				a.reportNotInlined(call, callee, "it would require a function literal", report.Reasons)
+			}
 			return
 		}
 		if res.BindingDecl && !allowBindingDecl {
@@ -202,13 +401,29 @@ func (a *analyzer) inlineCall(call *ast.CallExpr, cur inspector.Cursor) {
 			// entirely eliminate the parameters and
 			// insert a 'var params = args' declaration.
 			// The flag allows them to decline such fixes.
+			if explain {
+				a.reportNotInlined(call, callee, "it would require a 'var params = args' declaration", report.Reasons)
+			}
 			return
 		}
-		got := res.Content
-
-		// Suggest the "fix".
+		if res.Label {
+			// Labels are scoped to the whole function, and the
+			// inlining of a call does not see the labels added by
+			// the fixes of others, so it may choose the same one.
+			// Only the first such call in a function is fixed; the
+			// others are fixed by the next round of Apply.
+			fn := enclosingFunc(cur)
+			if a.labeled[fn] {
+				a.pass.Reportf(call.Pos(), "Call of %v should be inlined", callee)
+				return
+			}
+			a.labeled[fn] = true
+		}
+		// Suggest the "fix". Use the structured edits, which leave
+		// the rest of the file alone, so that the fixes of several
+		// calls in the file do not conflict.
 		var textEdits []analysis.TextEdit
-		for _, edit := range diff.Bytes(content, got) {
+		for _, edit := range slices.Concat(res.ImportEdits, res.Edits) {
 			textEdits = append(textEdits, analysis.TextEdit{
 				Pos:     curFile.FileStart + token.Pos(edit.Start),
 				End:     curFile.FileStart + token.Pos(edit.End),
@@ -227,6 +442,175 @@ func (a *analyzer) inlineCall(call *ast.CallExpr, cur inspector.Cursor) {
 	}
 }
 
+// reportNotInlined reports that the call was not inlined, and why.
+func (a *analyzer) reportNotInlined(call *ast.CallExpr, callee *inline.Callee, why string, reasons []inline.Reason) {
+	var (
+		msgs    []string
+		related []analysis.RelatedInformation
+	)
+	for _, r := range reasons {
+		msgs = append(msgs, r.Message)
+		related = append(related, analysis.RelatedInformation{Pos: r.Pos, Message: r.String()})
+		if r.Decl.IsValid() {
+			related = append(related, analysis.RelatedInformation{Pos: r.Decl, Message: "related declaration"})
+		}
+	}
+	a.pass.Report(analysis.Diagnostic{
+		Pos:     call.Pos(),
+		End:     call.End(),
+		Message: fmt.Sprintf("Call of %v was not inlined: %s (%s)", callee, why, strings.Join(msgs, "; ")),
+		Related: related,
+	})
+}
+
+// funcFact returns the fact for fn if it is an inlinable function or
+// method, or nil.
+func (a *analyzer) funcFact(fn *types.Func) *goFixInlineFuncFact {
+	infunc, ok := a.inlinableFuncs[fn]
+	if !ok {
+		var fact goFixInlineFuncFact
+		if a.pass.ImportObjectFact(fn, &fact) {
+			infunc = &fact
+		}
+		a.inlinableFuncs[fn] = infunc
+	}
+	return infunc
+}
+
+// If fn is an inlinable forwarding method, and cur is the selector of a
+// method value x.f or method expression T.f that is not called, suggest
+// selecting the method to which it forwards. (Calls are inlined by
+// inlineCall.)
+func (a *analyzer) inlineMethodValue(fn *types.Func, curId inspector.Cursor) {
+	if ek, _ := curId.ParentEdge(); ek != edge.SelectorExpr_Sel {
+		return
+	}
+	curSel := curId.Parent()
+	cur := curSel
+	for {
+		ek, _ := cur.ParentEdge()
+		if ek == edge.CallExpr_Fun {
+			return // a call
+		}
+		if ek != edge.ParenExpr_X {
+			break
+		}
+		cur = cur.Parent()
+	}
+	sel := curSel.Node().(*ast.SelectorExpr)
+	seln := a.pass.TypesInfo.Selections[sel]
+	if seln == nil || (seln.Kind() != types.MethodVal && seln.Kind() != types.MethodExpr) {
+		return
+	}
+	fact := a.funcFact(fn)
+	if fact == nil || fact.Forward == "" {
+		return
+	}
+
+	// The forwardee is a method of the same type as fn, so it must
+	// be found by the same path, and be accessible here.
+	obj, index, _ := types.LookupFieldOrMethod(seln.Recv(), true, a.pass.Pkg, fact.Forward)
+	if _, ok := obj.(*types.Func); !ok || !sameParent(index, seln.Index()) {
+		return
+	}
+	kind := "method value"
+	if seln.Kind() == types.MethodExpr {
+		kind = "method expression"
+	}
+	a.reportRename(kind, sel, sel.Sel, fact.Forward)
+}
+
+// If v is an inlinable field, suggest replacing its use at cur, as the
+// selector of x.f, by its replacement. Uses that may update the field,
+// including keys of struct literals, are not replaced, lest they
+// diverge from the remaining uses of the field.
+func (a *analyzer) inlineField(v *types.Var, curId inspector.Cursor) {
+	infield, ok := a.inlinableFields[v]
+	if !ok {
+		var fact goFixInlineFieldFact
+		if a.pass.ImportObjectFact(v, &fact) {
+			infield = &fact
+		}
+		a.inlinableFields[v] = infield
+	}
+	if infield == nil {
+		return // nope
+	}
+
+	id := curId.Node().(*ast.Ident)
+	if ek, _ := curId.ParentEdge(); ek != edge.SelectorExpr_Sel {
+		return // a key of a struct literal
+	}
+	sel := curId.Parent().Node().(*ast.SelectorExpr)
+	seln := a.pass.TypesInfo.Selections[sel]
+	if seln == nil || seln.Kind() != types.FieldVal || a.isUpdated(curId.Parent()) {
+		return
+	}
+	// x.RHS must select the sibling of v that replaces it, not
+	// a shallower field or method of the same name, and it must
+	// be accessible here.
+	obj, index, _ := types.LookupFieldOrMethod(seln.Recv(), true, a.pass.Pkg, infield.RHSName)
+	if _, ok := obj.(*types.Var); !ok || !sameParent(index, seln.Index()) {
+		return
+	}
+	a.reportRename("field", sel, id, infield.RHSName)
+}
+
+// isUpdated reports whether the variable denoted by the expression at
+// cur may be updated there: whether the expression, or one that denotes
+// part of the same variable (such as x.f or x[i], where x is a struct
+// or an array), is assigned, or has its address taken, explicitly or by
+// a call to a method with a pointer receiver.
+func (a *analyzer) isUpdated(cur inspector.Cursor) bool {
+	info := a.pass.TypesInfo
+	for {
+		switch ek, _ := cur.ParentEdge(); ek {
+		case edge.AssignStmt_Lhs, edge.IncDecStmt_X, edge.RangeStmt_Key, edge.RangeStmt_Value:
+			return true
+
+		case edge.UnaryExpr_X:
+			return cur.Parent().Node().(*ast.UnaryExpr).Op == token.AND
+
+		case edge.ParenExpr_X, edge.SelectorExpr_Sel:
+			// The same variable (or the qualified identifier pkg.V).
+
+		case edge.SelectorExpr_X:
+			seln, ok := info.Selections[cur.Parent().Node().(*ast.SelectorExpr)]
+			if !ok {
+				return false
+			}
+			_, indirect := info.TypeOf(cur.Node().(ast.Expr)).Underlying().(*types.Pointer)
+			switch seln.Kind() {
+			case types.FieldVal:
+				if indirect {
+					return false // x.f is part of another variable
+				}
+			case types.MethodVal:
+				recv := seln.Obj().Type().(*types.Signature).Recv().Type()
+				_, ptrRecv := types.Unalias(recv).(*types.Pointer)
+				return ptrRecv && !indirect // implicit &x
+			default:
+				return false
+			}
+
+		case edge.IndexExpr_X:
+			if _, ok := info.TypeOf(cur.Node().(ast.Expr)).Underlying().(*types.Array); !ok {
+				return false // x[i] is part of another variable, if any
+			}
+
+		default:
+			return false
+		}
+		cur = cur.Parent()
+	}
+}
+
+// sameParent reports whether the field or method selected by the path
+// x belongs to the same type as that selected by y.
+func sameParent(x, y []int) bool {
+	return len(x) == len(y) && slices.Equal(x[:len(x)-1], y[:len(y)-1])
+}
+
 // If tn is the TypeName of an inlinable alias, suggest inlining its use at cur.
 func (a *analyzer) inlineAlias(tn *types.TypeName, curId inspector.Cursor) {
 	inalias, ok := a.inlinableAliases[tn]
@@ -422,13 +806,40 @@ func (a *analyzer) inlineConst(con *types.Const, cur inspector.Cursor) {
 	if incon == nil {
 		return // nope
 	}
+	a.inlineName("constant", "Constant", incon.RHSName, incon.RHSPkgName, incon.RHSPkgPath, incon.rhsObj, cur)
+}
 
+// If v is an inlinable package-level variable, suggest inlining its use at cur.
+func (a *analyzer) inlineVar(v *types.Var, cur inspector.Cursor) {
+	invar, ok := a.inlinableVars[v]
+	if !ok {
+		var fact goFixInlineVarFact
+		if a.pass.ImportObjectFact(v, &fact) {
+			invar = &fact
+		}
+		a.inlinableVars[v] = invar
+	}
+	if invar == nil {
+		return // nope
+	}
+	// Uses that may update the variable are not replaced, lest they
+	// update the other variable instead.
+	if a.isUpdated(cur) {
+		return
+	}
+	a.inlineName("variable", "Variable", invar.RHSName, invar.RHSPkgName, invar.RHSPkgPath, invar.rhsObj, cur)
+}
+
+// inlineName suggests replacing the use at cur of an inlinable constant
+// or variable A by the name of the package-level object B that A
+// refers to. rhsObj is B if it is in the current package.
+func (a *analyzer) inlineName(kind, capKind, rhsName, rhsPkgName, rhsPkgPath string, rhsObj types.Object, cur inspector.Cursor) {
 	// If n is qualified by a package identifier, we'll need the full selector expression.
 	curFile := currentFile(cur)
 	n := cur.Node().(*ast.Ident)
 
 	// We have an identifier A here (n), possibly qualified by a package identifier (sel.X,
-	// where sel is the parent of n), // and an inlinable "const A = B" elsewhere (incon).
+	// where sel is the parent of n), // and an inlinable "const A = B" elsewhere.
 	// Consider replacing A with B.
 
 	// Check that the expression we are inlining (B) means the same thing
@@ -436,20 +847,20 @@ func (a *analyzer) inlineConst(con *types.Const, cur inspector.Cursor) {
 	// If the RHS is not in the current package, AddImport will handle
 	// shadowing, so we only need to worry about when both expressions
 	// are in the current package.
-	if a.pass.Pkg.Path() == incon.RHSPkgPath {
-		// incon.rhsObj is the object referred to by B in the definition of A.
+	if a.pass.Pkg.Path() == rhsPkgPath {
+		// rhsObj is the object referred to by B in the definition of A.
 		scope := a.pass.TypesInfo.Scopes[curFile].Innermost(n.Pos()) // n's scope
-		_, obj := scope.LookupParent(incon.RHSName, n.Pos())         // what "B" means in n's scope
+		_, obj := scope.LookupParent(rhsName, n.Pos())               // what "B" means in n's scope
 		if obj == nil {
 			// Should be impossible: if code at n can refer to the LHS,
 			// it can refer to the RHS.
-			panic(fmt.Sprintf("no object for inlinable const %s RHS %s", n.Name, incon.RHSName))
+			panic(fmt.Sprintf("no object for inlinable %s %s RHS %s", kind, n.Name, rhsName))
 		}
-		if obj != incon.rhsObj {
+		if obj != rhsObj {
 			// "B" means something different here than at the inlinable const's scope.
 			return
 		}
-	} else if !analysisinternal.CanImport(a.pass.Pkg.Path(), incon.RHSPkgPath) {
+	} else if !analysisinternal.CanImport(a.pass.Pkg.Path(), rhsPkgPath) {
 		// If this package can't see the RHS's package, we can't inline.
 		return
 	}
@@ -457,16 +868,16 @@ func (a *analyzer) inlineConst(con *types.Const, cur inspector.Cursor) {
 		importPrefix string
 		edits        []analysis.TextEdit
 	)
-	if incon.RHSPkgPath != a.pass.Pkg.Path() {
+	if rhsPkgPath != a.pass.Pkg.Path() {
 		_, importPrefix, edits = analysisinternal.AddImport(
-			a.pass.TypesInfo, curFile, incon.RHSPkgName, incon.RHSPkgPath, incon.RHSName, n.Pos())
+			a.pass.TypesInfo, curFile, rhsPkgName, rhsPkgPath, rhsName, n.Pos())
 	}
 	// If n is qualified by a package identifier, we'll need the full selector expression.
 	var expr ast.Expr = n
 	if ek, _ := cur.ParentEdge(); ek == edge.SelectorExpr_Sel {
 		expr = cur.Parent().Node().(ast.Expr)
 	}
-	a.reportInline("constant", "Constant", expr, edits, importPrefix+incon.RHSName)
+	a.reportInline(kind, capKind, expr, edits, importPrefix+rhsName)
 }
 
 // reportInline reports a diagnostic for fixing an inlinable name.
@@ -488,8 +899,30 @@ func (a *analyzer) reportInline(kind, capKind string, ident ast.Expr, edits []an
 	})
 }
 
+// reportRename reports a diagnostic for replacing the use expr of an
+// inlinable field or method, whose name is id, by the name newName.
+func (a *analyzer) reportRename(kind string, expr ast.Expr, id *ast.Ident, newName string) {
+	name := analysisinternal.Format(a.pass.Fset, expr)
+	a.pass.Report(analysis.Diagnostic{
+		Pos:     expr.Pos(),
+		End:     expr.End(),
+		Message: fmt.Sprintf("Use of %s %s should be inlined", kind, name),
+		SuggestedFixes: []analysis.SuggestedFix{{
+			Message: fmt.Sprintf("Replace %s with %s", id.Name, newName),
+			TextEdits: []analysis.TextEdit{{
+				Pos:     id.Pos(),
+				End:     id.End(),
+				NewText: []byte(newName),
+			}},
+		}},
+	})
+}
+
 func (a *analyzer) readFile(node ast.Node) ([]byte, error) {
 	filename := a.pass.Fset.File(node.Pos()).Name()
+	if content, ok := a.overlay[filename]; ok {
+		return content, nil
+	}
 	content, ok := a.fileContent[filename]
 	if !ok {
 		var err error
@@ -510,9 +943,21 @@ func currentFile(c inspector.Cursor) *ast.File {
 	panic("no *ast.File enclosing a cursor: impossible")
 }
 
+// enclosingFunc returns the innermost FuncDecl or FuncLit enclosing a
+// cursor, which is the scope of its labels, or nil.
+func enclosingFunc(c inspector.Cursor) ast.Node {
+	for cf := range c.Enclosing((*ast.FuncDecl)(nil), (*ast.FuncLit)(nil)) {
+		return cf.Node()
+	}
+	return nil
+}
+
 // A goFixInlineFuncFact is exported for each function marked "//go:fix inline".
 // It holds information about the callee to support inlining.
-type goFixInlineFuncFact struct{ Callee *inline.Callee }
+type goFixInlineFuncFact struct {
+	Callee  *inline.Callee
+	Forward string // for a forwarding method, the name of the method it calls
+}
 
 func (f *goFixInlineFuncFact) String() string { return "goFixInline " + f.Callee.String() }
 func (*goFixInlineFuncFact) AFact()           {}
@@ -533,6 +978,43 @@ func (c *goFixInlineConstFact) String() string {
 
 func (*goFixInlineConstFact) AFact() {}
 
+// A goFixInlineVarFact is exported for each package-level variable
+// marked "//go:fix inline". It holds information about an inlinable
+// variable. Gob-serializable.
+type goFixInlineVarFact struct {
+	// Information about "var LHSName = RHSName".
+	RHSName    string
+	RHSPkgPath string
+	RHSPkgName string
+	rhsObj     types.Object // for current package
+}
+
+func (v *goFixInlineVarFact) String() string {
+	return fmt.Sprintf("goFixInline var %q.%s", v.RHSPkgPath, v.RHSName)
+}
+
+func (*goFixInlineVarFact) AFact() {}
+
+// A goFixInlineFieldFact is exported for each struct field marked
+// "//go:fix inline F". It holds the name F of the replacement field.
+// Gob-serializable.
+type goFixInlineFieldFact struct {
+	RHSName string
+}
+
+func (f *goFixInlineFieldFact) String() string { return "goFixInline field " + f.RHSName }
+func (*goFixInlineFieldFact) AFact()           {}
+
+// A goFixPackageFact is exported for each package when the callee
+// cache is enabled. It holds the fingerprint of the package, which
+// keys the cached analyses of its functions. Gob-serializable.
+type goFixPackageFact struct {
+	Fingerprint []byte
+}
+
+func (f *goFixPackageFact) String() string { return fmt.Sprintf("goFixPackage %x", f.Fingerprint) }
+func (*goFixPackageFact) AFact()           {}
+
 // A goFixInlineAliasFact is exported for each type alias marked "//go:fix inline".
 // It holds no information; its mere existence demonstrates that an alias is inlinable.
 type goFixInlineAliasFact struct{}
@@ -542,6 +1024,11 @@ func (*goFixInlineAliasFact) AFact()           {}
 
 func discard(string, ...any) {}
 
+func is[T any](x any) bool {
+	_, ok := x.(T)
+	return ok
+}
+
 type list[T any] interface {
 	Len() int
 	At(int) T
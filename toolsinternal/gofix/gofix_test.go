@@ -5,13 +5,18 @@
 package gofix
 
 import (
+	"bytes"
 	"fmt"
 	"go/ast"
 	"go/importer"
 	"go/parser"
 	"go/token"
 	"go/types"
+	"io/fs"
+	"os"
+	"path/filepath"
 	"slices"
+	"strings"
 	"testing"
 
 	gocmp "github.com/google/go-cmp/cmp"
@@ -41,6 +46,63 @@ func TestAllowBindingDeclFlag(t *testing.T) {
 	run(false) // testdata/src/binding_false
 }
 
+func TestExplainFlag(t *testing.T) {
+	saved := explain
+	defer func() { explain = saved }()
+
+	explain = true
+	analysistest.Run(t, analysistest.TestData(), Analyzer, "explain")
+}
+
+func TestCalleeCacheFlag(t *testing.T) {
+	if testenv.Go1Point() < 24 {
+		testenv.NeedsGoExperiment(t, "aliastypeparams")
+	}
+	saved := calleeCacheDir
+	defer func() { calleeCacheDir = saved }()
+
+	calleeCacheDir = t.TempDir()
+
+	// The first run populates the cache.
+	analysistest.RunWithSuggestedFixes(t, analysistest.TestData(), Analyzer, "cache")
+	var entries []string
+	filepath.WalkDir(calleeCacheDir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			entries = append(entries, path)
+		}
+		return nil
+	})
+	if len(entries) != 1 {
+		t.Fatalf("got %d cache entries after first run, want 1", len(entries))
+	}
+
+	// Poison the entry of Two, so that the second run,
+	// if it uses the entry, inlines a different body.
+	data, err := os.ReadFile(entries[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	poisoned := bytes.Replace(data, []byte("One() + 1"), []byte("One() + 2"), 1)
+	if bytes.Equal(poisoned, data) {
+		t.Fatalf("cache entry does not contain the body of Two")
+	}
+	if err := os.WriteFile(entries[0], poisoned, 0666); err != nil {
+		t.Fatal(err)
+	}
+	var errs errorRecorder
+	analysistest.RunWithSuggestedFixes(&errs, analysistest.TestData(), Analyzer, "cache")
+	if !strings.Contains(errs.String(), "One() + 2") {
+		t.Errorf("second run did not use the cache entry; errors:\n%s", &errs)
+	}
+}
+
+// An errorRecorder is an analysistest.Testing that records errors.
+type errorRecorder struct{ strings.Builder }
+
+func (r *errorRecorder) Errorf(format string, args ...any) {
+	fmt.Fprintf(r, format+"\n", args...)
+}
+
 func TestTypesWithNames(t *testing.T) {
 	// Test setup inspired by internal/analysisinternal/addimport_test.go.
 	testenv.NeedsDefaultImporter(t)
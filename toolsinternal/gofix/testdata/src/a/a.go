@@ -193,3 +193,121 @@ func _[V any]() {
 
 	var _ M[int] // want `Type alias M\[int\] should be inlined`
 }
+
+// Forwarding methods.
+
+type S struct{}
+
+func (S) New(x int) int   { return x }
+func (*S) PNew(x int) int { return x }
+
+//go:fix inline
+func (s S) Old(x int) int { return s.New(x) } // want Old:`goFixInline \(a.S\).Old`
+
+//go:fix inline
+func (s *S) POld(x int) int { return s.New(x) } // want POld:`goFixInline \(\*a.S\).POld`
+
+//go:fix inline
+func (s S) Bad(x int) int { return s.PNew(x) } // want Bad:`goFixInline \(a.S\).Bad`
+
+func _(s S) {
+	f := s.Old     // want `Use of method value s.Old should be inlined`
+	g := S.Old     // want `Use of method expression S.Old should be inlined`
+	h := (*S).Old  // want `Use of method expression \(\*S\).Old should be inlined`
+	i := s.POld    // want `Use of method value s.POld should be inlined`
+	j := s.Bad     // nope: not a forwarding method, as PNew needs a pointer
+	_ = (s.Old)(1) // want `Call of \(a.S\).Old should be inlined`
+	_, _, _, _, _ = f, g, h, i, j
+}
+
+// Variables.
+
+var NewVar = 1
+
+//go:fix inline
+var (
+	OldVar = NewVar // want OldVar: `goFixInline var "a".NewVar`
+
+	badVar1 int          // want `invalid //go:fix inline directive: var has no value`
+	badVar2     = 1      // want `invalid //go:fix inline directive: var value is not the name of another package-level variable`
+	badVar3 any = NewVar // want `invalid //go:fix inline directive: var type any differs from that of NewVar`
+)
+
+func _() {
+	_ = OldVar  // want `Variable OldVar should be inlined`
+	OldVar++    // nope: updates the variable
+	_ = &OldVar // nope: may update the variable
+
+	//go:fix inline
+	var local = NewVar // want `invalid //go:fix inline directive: not a package-level variable`
+	_ = local
+}
+
+// Fields.
+
+type Rec struct {
+	Name string
+
+	//go:fix inline Name
+	Title string // want Title: `goFixInline field Name`
+
+	//go:fix inline
+	Bad1 string // want `invalid //go:fix inline directive: field directive must name the replacement field`
+
+	//go:fix inline Name
+	Bad2 int // want `invalid //go:fix inline directive: type of field Bad2 differs from that of Name`
+
+	//go:fix inline Nope
+	Bad3 int // want `invalid //go:fix inline directive: no field Nope in struct`
+
+	//go:fix inline Name
+	Bad4 string `json:"bad4"` // want `invalid //go:fix inline directive: tag of field Bad4 differs from that of Name`
+}
+
+type Outer struct {
+	Rec
+	Name int // shadows Rec.Name
+}
+
+func _(r *Rec, o Outer) {
+	_ = r.Title         // want `Use of field r.Title should be inlined`
+	_ = o.Title         // nope: o.Name is a different field
+	_ = o.Rec.Title     // want `Use of field o.Rec.Title should be inlined`
+	r.Title = "x"       // nope: updates the field
+	_ = Rec{Title: "x"} // nope: initializes the field
+}
+
+// Functions with several returns.
+
+//go:fix inline
+func Abs(x int) int { // want Abs:`goFixInline a.Abs`
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func _(z int) {
+	y := Abs(z) // want `Call of a.Abs should be inlined`
+	print(y)
+}
+
+// A return within a loop jumps to a label. Only one call in a
+// function is fixed with a label in each pass, lest the labels of
+// two fixes collide; the next round of Apply fixes the other.
+
+//go:fix inline
+func Find(xs []int, v int) int { // want Find:`goFixInline a.Find`
+	for i, x := range xs {
+		if x == v {
+			return i
+		}
+	}
+	return -1
+}
+
+func _(xs []int) {
+	p := Find(xs, 1) // want `Call of a.Find should be inlined`
+	q := Find(xs, 2) // want `Call of a.Find should be inlined`
+	print(p, q)
+}
@@ -37,3 +37,12 @@ var _ a.C // want `Type alias a\.C should be inlined`
 var _ R   // want `Type alias R should be inlined`
 
 var _ a.G // nope: a.G refers to a type in a package that is not visible here
+
+func _(s a.S, r a.Rec) {
+	_ = s.Old            // want `Use of method value s.Old should be inlined`
+	_ = a.S.Old          // want `Use of method expression a.S.Old should be inlined`
+	_ = a.OldVar         // want `Variable a.OldVar should be inlined`
+	_ = r.Title          // want `Use of field r.Title should be inlined`
+	_ = a.Rec{Title: ""} // nope: initializes the field
+	a.OldVar = 2         // nope: updates the variable
+}
@@ -12,3 +12,27 @@ func g() {
 }
 
 func h(int) int
+
+//go:fix inline
+func must(v int, err error) int { // want must:`goFixInline a.must`
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func k() int {
+	return must(two()) // want `Call of a.must should be inlined`
+}
+
+func two() (int, error)
+
+//go:fix inline
+func show(x, y int) { // want show:`goFixInline a.show`
+	print(y)
+	print(x)
+}
+
+func m() {
+	show(h(1), h(2)) // want `Call of a.show should be inlined`
+}
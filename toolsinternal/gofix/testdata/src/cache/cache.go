@@ -0,0 +1,13 @@
+package cache // want package:"goFixPackage [0-9a-f]+"
+
+// Test of the callee cache: the analysis of Two is cached by the
+// first run, and used by the second.
+
+//go:fix inline
+func Two() int { return One() + 1 } // want Two:`goFixInline cache.Two`
+
+func One() int { return 1 }
+
+func _() {
+	_ = Two() // want `Call of cache.Two should be inlined`
+}
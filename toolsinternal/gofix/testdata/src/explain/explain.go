@@ -0,0 +1,43 @@
+package explain
+
+//go:fix inline
+func add(x, y int) int { return x + y } // want add:`goFixInline explain.add`
+
+//go:fix inline
+func deferred(x int) { defer print(x) } // want deferred:`goFixInline explain.deferred`
+
+//go:fix inline
+func must(v int, err error) int { // want must:`goFixInline explain.must`
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+//go:fix inline
+func sign(n int) int { // want sign:`goFixInline explain.sign`
+	if n < 0 {
+		return -1
+	}
+	return 1
+}
+
+type S struct{ f int }
+
+func _() {
+	_ = add(1, 2) // want `Call of explain.add should be inlined`
+
+	deferred(1) // want `Call of explain.deferred was not inlined: it would require a function literal \(callee uses defer\)`
+
+	print(must(two())) // want `Call of explain.must was not inlined: it would require a function literal \(parameter v and the following ones must be bound to the results of two\(\); callee has return statements, but the call is not a tail call, or a call statement or assignment in a block\)`
+}
+
+func _(s *S, n int) {
+	s.f = sign(n) // want `Call of explain.sign was not inlined: it would require a function literal \(the results of the call are assigned to s.f, which is not an identifier\)`
+}
+
+func k() int {
+	return must(two()) // want `Call of explain.must was not inlined: it would require a 'var params = args' declaration \(parameter v and the following ones must be bound to the results of two\(\)\)`
+}
+
+func two() (int, error)
@@ -0,0 +1,414 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jsonrpc2
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tenntenn/exp/toolsinternal/diff"
+)
+
+// This file implements recording and replay of JSON-RPC sessions.
+//
+// A transcript is a sequence of newline-delimited JSON objects, one per
+// message, each recording the time the message was observed, its direction
+// relative to the recording peer, and the message itself in wire form.
+//
+//	{"time":"2025-05-01T12:00:00Z","dir":"in","message":{"jsonrpc":"2.0","id":1,"method":"ping"}}
+//	{"time":"2025-05-01T12:00:00Z","dir":"out","message":{"jsonrpc":"2.0","id":1,"result":{}}}
+
+// A Direction records whether a transcript message was received or sent by
+// the recording peer.
+type Direction string
+
+const (
+	// Incoming marks a message read from the peer.
+	Incoming Direction = "in"
+	// Outgoing marks a message written to the peer.
+	Outgoing Direction = "out"
+)
+
+// A TranscriptEntry is a single line of a transcript.
+type TranscriptEntry struct {
+	// Time is the time at which the message was read or written.
+	Time time.Time `json:"time"`
+	// Dir is the direction of the message.
+	Dir Direction `json:"dir"`
+	// Message is the wire form of the message.
+	Message json.RawMessage `json:"message"`
+}
+
+// Decode decodes the message held by the entry.
+func (e TranscriptEntry) Decode() (Message, error) {
+	return DecodeMessage(e.Message)
+}
+
+// A Recorder writes a transcript of the messages passing through the readers
+// and writers it wraps.
+//
+// A Recorder is safe for concurrent use, so that the reader and writer of a
+// single connection may share it.
+type Recorder struct {
+	mu  sync.Mutex
+	w   io.Writer
+	now func() time.Time
+	err error // first write error, sticky
+}
+
+// NewRecorder returns a Recorder that writes a transcript to w.
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{w: w, now: time.Now}
+}
+
+// Err returns the first error encountered while writing the transcript, if
+// any. Recording errors never interrupt the recorded stream.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// record appends an entry for msg to the transcript.
+func (r *Recorder) record(dir Direction, msg Message) {
+	data, err := EncodeMessage(msg)
+	if err == nil {
+		data, err = json.Marshal(TranscriptEntry{
+			Time:    r.now().UTC(),
+			Dir:     dir,
+			Message: data,
+		})
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	if err != nil {
+		r.err = fmt.Errorf("encoding transcript entry: %v", err)
+		return
+	}
+	if _, err := r.w.Write(append(data, '\n')); err != nil {
+		r.err = err
+	}
+}
+
+// Framer returns a Framer that delegates to f, recording all messages read
+// and written by the resulting readers and writers.
+func (r *Recorder) Framer(f Framer) Framer {
+	return recordingFramer{delegate: f, r: r}
+}
+
+// Reader returns a Reader that delegates to in, recording each message that
+// is successfully read as [Incoming].
+func (r *Recorder) Reader(in Reader) Reader {
+	return &recordingReader{delegate: in, r: r}
+}
+
+// Writer returns a Writer that delegates to out, recording each message that
+// is successfully written as [Outgoing].
+func (r *Recorder) Writer(out Writer) Writer {
+	return &recordingWriter{delegate: out, r: r}
+}
+
+type recordingFramer struct {
+	delegate Framer
+	r        *Recorder
+}
+
+func (f recordingFramer) Reader(rw io.Reader) Reader {
+	return f.r.Reader(f.delegate.Reader(rw))
+}
+
+func (f recordingFramer) Writer(rw io.Writer) Writer {
+	return f.r.Writer(f.delegate.Writer(rw))
+}
+
+type recordingReader struct {
+	delegate Reader
+	r        *Recorder
+}
+
+func (r *recordingReader) Read(ctx context.Context) (Message, int64, error) {
+	msg, n, err := r.delegate.Read(ctx)
+	if err == nil {
+		r.r.record(Incoming, msg)
+	}
+	return msg, n, err
+}
+
+type recordingWriter struct {
+	delegate Writer
+	r        *Recorder
+}
+
+func (w *recordingWriter) Write(ctx context.Context, msg Message) (int64, error) {
+	n, err := w.delegate.Write(ctx, msg)
+	if err == nil {
+		w.r.record(Outgoing, msg)
+	}
+	return n, err
+}
+
+// ReadTranscript reads a transcript, as written by a [Recorder].
+// Blank lines are ignored.
+func ReadTranscript(r io.Reader) ([]TranscriptEntry, error) {
+	var entries []TranscriptEntry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64<<20) // messages may be large
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var entry TranscriptEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, fmt.Errorf("transcript line %d: %v", lineno, err)
+		}
+		switch entry.Dir {
+		case Incoming, Outgoing:
+		default:
+			return nil, fmt.Errorf("transcript line %d: invalid direction %q", lineno, entry.Dir)
+		}
+		if _, err := entry.Decode(); err != nil {
+			return nil, fmt.Errorf("transcript line %d: %v", lineno, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// A ReplayStream is a Reader, Writer and Closer that plays back the incoming
+// messages of a transcript, and records the outgoing messages actually
+// produced in response.
+//
+// To keep the replay deterministic, incoming messages are paced to match the
+// original session: an incoming request is not delivered until all previously
+// delivered calls have been answered, and an incoming response is not
+// delivered until the corresponding outgoing call has been written. Once the
+// transcript is exhausted and all calls are answered, Read returns io.EOF.
+//
+// Outgoing calls are expected to be made while handling an incoming call.
+// If all delivered calls have been answered without making the call whose
+// response is next in the transcript, the replay has diverged from the
+// recording, and Read fails with an error that is also reported by Err.
+type ReplayStream struct {
+	entries []TranscriptEntry
+	next    int // index of the next entry to consider; reads are serialized
+
+	written chan struct{} // signaled (non-blocking) after each Write
+	closed  chan struct{}
+	once    sync.Once
+
+	mu       sync.Mutex
+	pending  map[ID]bool // incoming calls awaiting a response
+	outCalls map[ID]bool // outgoing calls written so far
+	got      []Message   // outgoing messages, in order
+	err      error       // divergence from the transcript, if any
+}
+
+// NewReplayStream returns a ReplayStream for the given transcript.
+func NewReplayStream(entries []TranscriptEntry) *ReplayStream {
+	return &ReplayStream{
+		entries:  entries,
+		written:  make(chan struct{}, 1),
+		closed:   make(chan struct{}),
+		pending:  make(map[ID]bool),
+		outCalls: make(map[ID]bool),
+	}
+}
+
+// Read implements Reader.
+func (s *ReplayStream) Read(ctx context.Context) (Message, int64, error) {
+	for ; s.next < len(s.entries); s.next++ {
+		entry := s.entries[s.next]
+		if entry.Dir != Incoming {
+			continue
+		}
+		msg, err := entry.Decode()
+		if err != nil {
+			return nil, 0, err
+		}
+		var ready func() (bool, error)
+		switch msg := msg.(type) {
+		case *Request:
+			ready = s.answered
+		case *Response:
+			ready = func() (bool, error) {
+				if s.outCalls[msg.ID] {
+					return true, nil
+				}
+				if len(s.pending) == 0 {
+					// No handler remains that could make the call.
+					s.err = fmt.Errorf("replay diverged: call %v, answered by transcript entry %d, was not made", msg.ID.Raw(), s.next+1)
+					return false, s.err
+				}
+				return false, nil
+			}
+		}
+		if err := s.await(ctx, ready); err != nil {
+			return nil, 0, err
+		}
+		if req, ok := msg.(*Request); ok && req.IsCall() {
+			s.mu.Lock()
+			s.pending[req.ID] = true
+			s.mu.Unlock()
+		}
+		s.next++
+		return msg, int64(len(entry.Message)), nil
+	}
+	if err := s.await(ctx, s.answered); err != nil {
+		return nil, 0, err
+	}
+	return nil, 0, io.EOF
+}
+
+// answered reports whether all delivered calls have been answered.
+func (s *ReplayStream) answered() (bool, error) {
+	return len(s.pending) == 0, nil
+}
+
+// await blocks until ready reports true or an error, the context is
+// cancelled, or the stream is closed. ready is called with s.mu held.
+func (s *ReplayStream) await(ctx context.Context, ready func() (bool, error)) error {
+	for {
+		s.mu.Lock()
+		ok, err := ready()
+		s.mu.Unlock()
+		if ok || err != nil {
+			return err
+		}
+		select {
+		case <-s.written:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.closed:
+			return io.EOF
+		}
+	}
+}
+
+// Write implements Writer.
+func (s *ReplayStream) Write(ctx context.Context, msg Message) (int64, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	case <-s.closed:
+		return 0, io.ErrClosedPipe
+	default:
+	}
+	s.mu.Lock()
+	s.got = append(s.got, msg)
+	switch msg := msg.(type) {
+	case *Request:
+		if msg.IsCall() {
+			s.outCalls[msg.ID] = true
+		}
+	case *Response:
+		delete(s.pending, msg.ID)
+	}
+	s.mu.Unlock()
+	select {
+	case s.written <- struct{}{}:
+	default:
+	}
+	return 0, nil
+}
+
+// Err returns the error describing how the replay diverged from the
+// transcript, if it did.
+func (s *ReplayStream) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Close implements io.Closer.
+func (s *ReplayStream) Close() error {
+	s.once.Do(func() { close(s.closed) })
+	return nil
+}
+
+// Diff returns a unified diff between the outgoing messages recorded in the
+// transcript and those written to the stream so far. Messages are compared
+// in a canonical JSON form, one per line. The result is empty if the replay
+// reproduced the recording exactly.
+func (s *ReplayStream) Diff() (string, error) {
+	var want, got strings.Builder
+	for _, entry := range s.entries {
+		if entry.Dir != Outgoing {
+			continue
+		}
+		if err := writeCanonical(&want, entry.Message); err != nil {
+			return "", err
+		}
+	}
+	s.mu.Lock()
+	msgs := s.got
+	s.mu.Unlock()
+	for _, msg := range msgs {
+		data, err := EncodeMessage(msg)
+		if err != nil {
+			return "", err
+		}
+		if err := writeCanonical(&got, data); err != nil {
+			return "", err
+		}
+	}
+	return diff.Unified("recorded", "replayed", want.String(), got.String()), nil
+}
+
+// writeCanonical writes the JSON value data to b on a single line, with
+// object keys sorted, so that semantically equal messages compare equal.
+func writeCanonical(b *strings.Builder, data json.RawMessage) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return err
+	}
+	canon, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	b.Write(canon)
+	b.WriteByte('\n')
+	return nil
+}
+
+// Replay plays the incoming messages of transcript against handler, and
+// returns a unified diff between the recorded outgoing messages and those
+// actually produced; see [ReplayStream.Diff].
+//
+// The handler is bound to a fresh connection over a [ReplayStream], which is
+// closed once the transcript has been played. If the replay diverges so
+// that it cannot continue (see [ReplayStream.Err]), Replay returns the
+// error.
+func Replay(ctx context.Context, transcript []TranscriptEntry, handler Handler) (string, error) {
+	stream := NewReplayStream(transcript)
+	conn := NewConnection(ctx, ConnectionConfig{
+		Reader: stream,
+		Writer: stream,
+		Closer: stream,
+		Bind:   func(*Connection) Handler { return handler },
+	})
+	if err := conn.Wait(); err != nil {
+		return "", err
+	}
+	if err := stream.Err(); err != nil {
+		return "", err
+	}
+	return stream.Diff()
+}
@@ -0,0 +1,231 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jsonrpc2_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	jsonrpc2 "github.com/tenntenn/exp/toolsinternal/jsonrpc2_v2"
+	"github.com/tenntenn/exp/toolsinternal/stack/stacktest"
+)
+
+// echoHandler answers "echo" calls with their params, "add" notifications
+// by accumulating, and "get" calls with the accumulated total.
+type echoHandler struct {
+	mu    sync.Mutex
+	total int
+	bias  int // added to "get" results, to simulate a regression
+}
+
+func (h *echoHandler) Handle(ctx context.Context, req *jsonrpc2.Request) (any, error) {
+	switch req.Method {
+	case "echo":
+		return req.Params, nil
+	case "add":
+		var n int
+		if err := json.Unmarshal(req.Params, &n); err != nil {
+			return nil, err
+		}
+		h.mu.Lock()
+		h.total += n
+		h.mu.Unlock()
+		return nil, nil
+	case "get":
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		return h.total + h.bias, nil
+	}
+	return nil, jsonrpc2.ErrNotHandled
+}
+
+// recordSession runs a short session against a server using h, and returns
+// the server-side transcript.
+func recordSession(t *testing.T, h jsonrpc2.Handler) []byte {
+	t.Helper()
+	ctx := context.Background()
+	listener, err := jsonrpc2.NetPipeListener(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	rec := jsonrpc2.NewRecorder(&buf)
+	server := jsonrpc2.NewServer(ctx, listener, jsonrpc2.ConnectionOptions{
+		Framer:  rec.Framer(jsonrpc2.HeaderFramer()),
+		Handler: h,
+	})
+	client, err := jsonrpc2.Dial(ctx, listener.Dialer(), jsonrpc2.ConnectionOptions{
+		Framer: jsonrpc2.HeaderFramer(),
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got string
+	if err := client.Call(ctx, "echo", "hello").Await(ctx, &got); err != nil || got != "hello" {
+		t.Fatalf("echo: got %q, %v", got, err)
+	}
+	for i := 1; i <= 3; i++ {
+		if err := client.Notify(ctx, "add", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var total int
+	if err := client.Call(ctx, "get", nil).Await(ctx, &total); err != nil || total != 6 {
+		t.Fatalf("get: got %d, %v", total, err)
+	}
+	if err := client.Call(ctx, "missing", nil).Await(ctx, nil); err == nil {
+		t.Fatal("missing: unexpected success")
+	}
+	client.Close()
+	listener.Close()
+	server.Wait()
+	if err := rec.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestTranscriptRecord(t *testing.T) {
+	stacktest.NoLeak(t)
+	data := recordSession(t, &echoHandler{})
+	entries, err := jsonrpc2.ReadTranscript(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, entry := range entries {
+		if entry.Time.IsZero() {
+			t.Errorf("entry %s has no timestamp", entry.Message)
+		}
+		msg, err := entry.Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch msg := msg.(type) {
+		case *jsonrpc2.Request:
+			got = append(got, fmt.Sprintf("%s %s", entry.Dir, msg.Method))
+		case *jsonrpc2.Response:
+			got = append(got, fmt.Sprintf("%s response %v", entry.Dir, msg.ID.Raw()))
+		}
+	}
+	want := []string{
+		"in echo",
+		"out response 1",
+		"in add",
+		"in add",
+		"in add",
+		"in get",
+		"out response 2",
+		"in missing",
+		"out response 3",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("transcript:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestTranscriptReplay(t *testing.T) {
+	stacktest.NoLeak(t)
+	ctx := context.Background()
+	data := recordSession(t, &echoHandler{})
+	entries, err := jsonrpc2.ReadTranscript(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Replaying against an equivalent handler reproduces the session.
+	d, err := jsonrpc2.Replay(ctx, entries, &echoHandler{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d != "" {
+		t.Errorf("Replay: unexpected diff:\n%s", d)
+	}
+
+	// A regression in the handler shows up in the diff.
+	d, err = jsonrpc2.Replay(ctx, entries, &echoHandler{bias: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(d, `-{"id":2,"jsonrpc":"2.0","result":6}`) ||
+		!strings.Contains(d, `+{"id":2,"jsonrpc":"2.0","result":7}`) {
+		t.Errorf("Replay: diff does not report the regression:\n%s", d)
+	}
+}
+
+// callbackTranscript records a session in which the handler of an "ask"
+// call asks the peer a "question" before answering.
+const callbackTranscript = `
+{"dir":"in","message":{"jsonrpc":"2.0","id":"r1","method":"ask"}}
+{"dir":"out","message":{"jsonrpc":"2.0","id":1,"method":"question"}}
+{"dir":"in","message":{"jsonrpc":"2.0","id":1,"result":42}}
+{"dir":"out","message":{"jsonrpc":"2.0","id":"r1","result":42}}
+`
+
+func TestTranscriptReplayDiverged(t *testing.T) {
+	stacktest.NoLeak(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	entries, err := jsonrpc2.ReadTranscript(strings.NewReader(callbackTranscript))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A handler that makes the recorded call reproduces the session.
+	stream := jsonrpc2.NewReplayStream(entries)
+	conn := jsonrpc2.NewConnection(ctx, jsonrpc2.ConnectionConfig{
+		Reader: stream,
+		Writer: stream,
+		Closer: stream,
+		Bind: func(conn *jsonrpc2.Connection) jsonrpc2.Handler {
+			return jsonrpc2.HandlerFunc(func(ctx context.Context, req *jsonrpc2.Request) (any, error) {
+				var answer int
+				err := conn.Call(ctx, "question", nil).Await(ctx, &answer)
+				return answer, err
+			})
+		},
+	})
+	if err := conn.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if d, err := stream.Diff(); err != nil || d != "" {
+		t.Errorf("Diff() = %q, %v; want no diff", d, err)
+	}
+
+	// A handler that skips the call makes the replay fail, rather than
+	// wait for a response that cannot be delivered.
+	_, err = jsonrpc2.Replay(ctx, entries, jsonrpc2.HandlerFunc(func(ctx context.Context, req *jsonrpc2.Request) (any, error) {
+		return 42, nil
+	}))
+	if err == nil || !strings.Contains(err.Error(), "replay diverged: call 1") {
+		t.Errorf("Replay = %v, want divergence error", err)
+	}
+}
+
+func TestReadTranscriptErrors(t *testing.T) {
+	for _, test := range []struct {
+		name, input, want string
+	}{
+		{"bad json", "{", "line 1"},
+		{"bad direction", `{"dir":"sideways","message":{"jsonrpc":"2.0","method":"x"}}`, "invalid direction"},
+		{"bad message", "\n" + `{"dir":"in","message":{"jsonrpc":"2.0"}}`, "line 2"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := jsonrpc2.ReadTranscript(strings.NewReader(test.input))
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("ReadTranscript(%q) = %v, want error containing %q", test.input, err, test.want)
+			}
+		})
+	}
+}
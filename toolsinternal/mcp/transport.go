@@ -224,6 +224,48 @@ func (s *loggingStream) Close() error {
 	return s.delegate.Close()
 }
 
+// A RecordingTransport is a [Transport] that delegates to another transport,
+// writing a JSONL transcript of all messages to an io.Writer.
+//
+// Unlike the logs written by [LoggingTransport], transcripts are machine
+// readable: they may be read with [jsonrpc2.ReadTranscript] and played back
+// against a fresh session to reproduce a bug.
+type RecordingTransport struct {
+	delegate Transport
+	rec      *jsonrpc2.Recorder
+}
+
+// NewRecordingTransport creates a new RecordingTransport that delegates to
+// the provided transport, writing a transcript to the provided io.Writer.
+func NewRecordingTransport(delegate Transport, w io.Writer) *RecordingTransport {
+	return &RecordingTransport{delegate, jsonrpc2.NewRecorder(w)}
+}
+
+// Connect connects the underlying transport, returning a [Stream] that
+// records messages to the configured destination.
+func (t *RecordingTransport) Connect(ctx context.Context) (Stream, error) {
+	delegate, err := t.delegate.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &recordingStream{
+		Reader: t.rec.Reader(delegate),
+		Writer: t.rec.Writer(delegate),
+		Closer: delegate,
+	}, nil
+}
+
+// Err returns the first error encountered while writing the transcript.
+func (t *RecordingTransport) Err() error {
+	return t.rec.Err()
+}
+
+type recordingStream struct {
+	jsonrpc2.Reader
+	jsonrpc2.Writer
+	io.Closer
+}
+
 // A rwc binds an io.ReadCloser and io.WriteCloser together to create an
 // io.ReadWriteCloser.
 type rwc struct {
@@ -5,6 +5,7 @@
 package mcp
 
 import (
+	"bytes"
 	"context"
 	"io"
 	"testing"
@@ -60,3 +61,68 @@ func TestBatchFraming(t *testing.T) {
 		}
 	}
 }
+
+// replayTransport is a Transport that connects to a replay of a transcript.
+type replayTransport struct {
+	stream *jsonrpc2.ReplayStream
+}
+
+func (t replayTransport) Connect(context.Context) (Stream, error) {
+	return t.stream, nil
+}
+
+func TestRecordingTransport(t *testing.T) {
+	// This test checks that a server-side transcript may be played back against
+	// a fresh server to reproduce the original session.
+	ctx := context.Background()
+	ct, st := NewInMemoryTransports()
+
+	var buf bytes.Buffer
+	rt := NewRecordingTransport(st, &buf)
+	s := NewServer("testServer", "v1.0.0", nil)
+	s.AddTools(NewTool("greet", "say hi", sayHi))
+	ss, err := s.Connect(ctx, rt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient("testClient", "v1.0.0", nil)
+	cs, err := c.Connect(ctx, ct)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CallTool(ctx, cs, &CallToolParams[map[string]any]{
+		Name:      "greet",
+		Arguments: map[string]any{"name": "user"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	cs.Close()
+	ss.Wait()
+	if err := rt.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := jsonrpc2.ReadTranscript(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) == 0 {
+		t.Fatal("empty transcript")
+	}
+
+	replay := jsonrpc2.NewReplayStream(entries)
+	s2 := NewServer("testServer", "v1.0.0", nil)
+	s2.AddTools(NewTool("greet", "say hi", sayHi))
+	ss2, err := s2.Connect(ctx, replayTransport{replay})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss2.Wait()
+	diff, err := replay.Diff()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff != "" {
+		t.Errorf("replay did not reproduce the session:\n%s", diff)
+	}
+}
@@ -0,0 +1,902 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package extract implements the "extract function" refactoring, the
+// inverse of inlining: it replaces a sequence of statements by a call
+// to a new function whose body is those statements.
+//
+// Local variables that the statements use but do not declare become
+// parameters of the new function. Variables that the statements
+// declare and that are used after them, and variables that they assign
+// but do not declare, become its results.
+// A return, break or continue statement that leaves the sequence is
+// replaced by a return from the new function that sets a boolean
+// "flag" result, which the caller tests to perform the original jump:
+//
+//	x, shouldReturn := newFunction(a, b)
+//	if shouldReturn {
+//		return
+//	}
+//
+// Like the inliner, the refactoring must not change the behavior of
+// the program, so it rejects selections for which it cannot establish
+// this, such as those that use a variable whose address is taken (see
+// inline.Escape), contain a defer statement or a call to recover, or
+// whose function literals capture variables that are later assigned.
+package extract
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"go/types"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/tenntenn/exp/toolsinternal/diff"
+	"github.com/tenntenn/exp/toolsinternal/refactor/inline"
+	"github.com/tenntenn/exp/toolsinternal/typesinternal"
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// A Selection describes the statements to extract: those that lie
+// within the interval [Start, End) of the file. They must be
+// consecutive statements of a single block, within a function.
+type Selection struct {
+	Fset    *token.FileSet
+	Types   *types.Package
+	Info    *types.Info
+	File    *ast.File
+	Start   token.Pos
+	End     token.Pos
+	Content []byte // source of file containing the selection
+}
+
+type logger = func(string, ...any)
+
+// Options specifies parameters affecting the extraction.
+type Options struct {
+	Logf logger // log output function, records decision-making process
+	Name string // name of the new function; if empty, "newFunction"
+}
+
+// Result holds the result of code transformation.
+type Result struct {
+	Content []byte // formatted, transformed content of the file
+	Name    string // name of the new function, made unique if necessary
+
+	// Edits is the transformation in structured form, as edits to
+	// the original content of the file: one replaces the selected
+	// statements by the call, the other inserts the new function
+	// after the declaration that encloses them. Applying them and
+	// formatting the file gives Content.
+	Edits []diff.Edit
+}
+
+// Extract extracts the selected statements into a new function,
+// and returns the updated, formatted content of the file.
+//
+// Extract does not mutate any public fields of Selection.
+func Extract(sel *Selection, opts *Options) (*Result, error) {
+	copy := *opts // shallow copy
+	opts = &copy
+	// Set default options.
+	if opts.Logf == nil {
+		opts.Logf = func(string, ...any) {}
+	}
+	if opts.Name == "" {
+		opts.Name = "newFunction"
+	}
+
+	st := &state{sel: sel, opts: opts}
+	return st.extract()
+}
+
+// state holds the working state of the extraction.
+type state struct {
+	sel  *Selection
+	opts *Options
+
+	stmts      []ast.Stmt       // the selected statements
+	start, end token.Pos        // extent of the statements, and of the comments among them
+	scope      *types.Scope     // scope of the block that contains the statements
+	decl       *ast.FuncDecl    // top-level declaration enclosing the statements
+	sig        *types.Signature // signature of the innermost function enclosing the statements
+	qual       types.Qualifier  // qualifies names relative to the file
+	names      map[string]bool  // names used within decl, to be avoided by new names
+}
+
+// A variable is a local variable that is a parameter or a result of
+// the new function.
+type variable struct {
+	v     *types.Var
+	param bool // the statements use it but do not declare it
+	decl  bool // the statements declare it (at top level), and it is used after them
+	store bool // the statements assign it, though it is declared before them
+}
+
+// A jump is a return, break or continue statement that leaves the
+// selected statements.
+type jump struct {
+	stmt ast.Stmt // *ast.ReturnStmt or *ast.BranchStmt
+	flag int      // index of its flag in state.flags
+}
+
+// A flag is a boolean result that indicates a kind of jump, such as
+// a return, or a break to a given label.
+type flag struct {
+	name string // name of the flag variable at the call site
+	stmt string // statement that performs the jump at the call site (without operands)
+	key  string // "return", "break", "continue L", and so on
+}
+
+func (st *state) extract() (*Result, error) {
+	logf, sel := st.opts.Logf, st.sel
+
+	if err := st.selectStatements(); err != nil {
+		return nil, err
+	}
+	logf("extract %d statements at %v", len(st.stmts), sel.Fset.PositionFor(st.start, false))
+
+	st.names = make(map[string]bool)
+	ast.Inspect(st.decl, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok {
+			st.names[id.Name] = true
+		}
+		return true
+	})
+
+	// Record imports missing from the file.
+	var missing []string
+	fileQual := typesinternal.FileQualifier(sel.File, sel.Types)
+	imported := make(map[string]bool)
+	for _, imp := range sel.File.Imports {
+		path, _ := strconv.Unquote(imp.Path.Value)
+		imported[path] = true
+	}
+	st.qual = func(pkg *types.Package) string {
+		if pkg != sel.Types && !imported[pkg.Path()] && !slices.Contains(missing, pkg.Path()) {
+			missing = append(missing, pkg.Path())
+		}
+		return fileQual(pkg)
+	}
+
+	if err := st.checkStatements(); err != nil {
+		return nil, err
+	}
+	vars, err := st.variables()
+	if err != nil {
+		return nil, err
+	}
+	jumps, flags := st.jumps()
+
+	// Choose the names.
+	name := st.freshName(st.opts.Name, true)
+	for i := range flags {
+		flags[i].name = st.freshName(flags[i].name, false)
+	}
+	var returnValues []string // names of the variables for the results of a return jump, if any
+	if slices.ContainsFunc(flags, func(f flag) bool { return f.key == "return" }) {
+		for range st.sig.Results().Len() {
+			returnValues = append(returnValues, st.freshName("returnValue", false))
+		}
+	}
+
+	// Format the signature of the new function.
+	var params, results []string
+	for _, v := range vars {
+		if v.param {
+			params = append(params, v.v.Name()+" "+types.TypeString(v.v.Type(), st.qual))
+		}
+	}
+	for _, v := range vars {
+		if v.decl || v.store {
+			results = append(results, types.TypeString(v.v.Type(), st.qual))
+		}
+	}
+	if returnValues != nil {
+		for v := range st.sig.Results().Variables() {
+			results = append(results, types.TypeString(v.Type(), st.qual))
+		}
+	}
+	for range flags {
+		results = append(results, "bool")
+	}
+
+	// Format the body of the new function: the statements,
+	// with each jump replaced by a return.
+	content := sel.Content
+	file := sel.Fset.File(st.start)
+	offset := func(pos token.Pos) int { return file.Offset(pos) }
+	var bodyEdits []diff.Edit
+	for _, j := range jumps {
+		operands, err := st.jumpOperands(j, vars, returnValues != nil, flags)
+		if err != nil {
+			return nil, err
+		}
+		bodyEdits = append(bodyEdits, diff.Edit{
+			Start: offset(j.stmt.Pos()) - offset(st.start),
+			End:   offset(j.stmt.End()) - offset(st.start),
+			New:   "return " + strings.Join(operands, ", "),
+		})
+	}
+	body, err := diff.Apply(string(content[offset(st.start):offset(st.end)]), bodyEdits)
+	if err != nil {
+		return nil, fmt.Errorf("internal error: %v", err)
+	}
+	if len(results) > 0 && !st.endsWithJump(jumps) {
+		var operands []string
+		for _, v := range vars {
+			if v.decl || v.store {
+				operands = append(operands, v.v.Name())
+			}
+		}
+		if returnValues != nil {
+			for v := range st.sig.Results().Variables() {
+				zero, err := st.zero(v.Type())
+				if err != nil {
+					return nil, err
+				}
+				operands = append(operands, zero)
+			}
+		}
+		for range flags {
+			operands = append(operands, "false")
+		}
+		body += "\nreturn " + strings.Join(operands, ", ")
+	}
+
+	var fn strings.Builder
+	fmt.Fprintf(&fn, "\n\nfunc %s(%s)", name, strings.Join(params, ", "))
+	switch len(results) {
+	case 0:
+	case 1:
+		fmt.Fprintf(&fn, " %s", results[0])
+	default:
+		fmt.Fprintf(&fn, " (%s)", strings.Join(results, ", "))
+	}
+	fmt.Fprintf(&fn, " {\n%s\n}", body)
+
+	// Format the call.
+	call := st.call(name, vars, returnValues, flags)
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("new function would refer to packages not imported by the file: %s", strings.Join(missing, ", "))
+	}
+
+	res := &Result{
+		Name: name,
+		Edits: []diff.Edit{
+			{Start: offset(st.start), End: offset(st.end), New: call},
+			{Start: offset(st.decl.End()), End: offset(st.decl.End()), New: fn.String()},
+		},
+	}
+	newContent, err := diff.ApplyBytes(content, res.Edits)
+	if err != nil {
+		return nil, fmt.Errorf("internal error: %v", err)
+	}
+	res.Content, err = format.Source(newContent)
+	if err != nil {
+		return nil, fmt.Errorf("internal error: extraction produced invalid code: %v", err)
+	}
+	return res, nil
+}
+
+// selectStatements finds the statements within the selection, and
+// their enclosing function.
+func (st *state) selectStatements() error {
+	sel := st.sel
+	path, _ := astutil.PathEnclosingInterval(sel.File, sel.Start, sel.End)
+	var block ast.Node
+	for i, n := range path {
+		var list []ast.Stmt
+		switch n := n.(type) {
+		case *ast.BlockStmt:
+			list = n.List
+		case *ast.CaseClause:
+			list = n.Body
+		case *ast.CommClause:
+			list = n.Body
+		case *ast.FuncDecl, *ast.FuncLit:
+			return fmt.Errorf("selection does not consist of statements")
+		default:
+			continue
+		}
+		if sel.Start <= n.Pos() && n.End() <= sel.End {
+			continue // the selection includes the block itself
+		}
+		for _, stmt := range list {
+			if stmt.End() <= sel.Start || sel.End <= stmt.Pos() {
+				continue // no overlap
+			}
+			if stmt.Pos() < sel.Start || sel.End < stmt.End() {
+				return fmt.Errorf("selection does not consist of whole statements")
+			}
+			switch stmt.(type) {
+			case *ast.CaseClause, *ast.CommClause:
+				return fmt.Errorf("cannot extract case clauses")
+			}
+			st.stmts = append(st.stmts, stmt)
+		}
+		if len(st.stmts) == 0 {
+			continue
+		}
+		block = n
+		for _, n := range path[i:] {
+			switch n := n.(type) {
+			case *ast.FuncLit:
+				if st.sig == nil {
+					st.sig, _ = sel.Info.TypeOf(n).(*types.Signature)
+					if st.scope == nil {
+						st.scope = sel.Info.Scopes[n.Type]
+					}
+				}
+			case *ast.FuncDecl:
+				st.decl = n
+				if st.sig == nil {
+					st.sig = sel.Info.Defs[n.Name].Type().(*types.Signature)
+					if st.scope == nil {
+						st.scope = sel.Info.Scopes[n.Type]
+					}
+				}
+			}
+		}
+		break
+	}
+	if block == nil {
+		return fmt.Errorf("selection contains no statements")
+	}
+	if st.decl == nil || st.sig == nil {
+		return fmt.Errorf("selection is not within a function")
+	}
+	if scope := sel.Info.Scopes[block]; scope != nil {
+		st.scope = scope // (a function body has no scope of its own)
+	}
+
+	// Include the comments among the statements.
+	st.start, st.end = st.stmts[0].Pos(), last(st.stmts).End()
+	for _, cg := range sel.File.Comments {
+		if sel.Start <= cg.Pos() && cg.End() <= sel.End {
+			st.start = min(st.start, cg.Pos())
+			st.end = max(st.end, cg.End())
+		}
+	}
+	return nil
+}
+
+// inSelection reports whether pos lies within the selected statements.
+func (st *state) inSelection(pos token.Pos) bool {
+	return st.start <= pos && pos < st.end
+}
+
+// checkStatements rejects statements whose behavior would change if
+// they were executed in a function of their own.
+func (st *state) checkStatements() error {
+	info := st.sel.Info
+	var err error
+	for _, stmt := range st.stmts {
+		inspectNoLits(stmt, func(n ast.Node) {
+			if err != nil {
+				return
+			}
+			switch n := n.(type) {
+			case *ast.DeferStmt:
+				err = fmt.Errorf("selection contains a defer statement, which would run when the new function returns")
+			case *ast.CallExpr:
+				if id, ok := ast.Unparen(n.Fun).(*ast.Ident); ok {
+					if b, ok := info.Uses[id].(*types.Builtin); ok && b.Name() == "recover" {
+						err = fmt.Errorf("selection calls recover, which would have no effect in the new function")
+					}
+				}
+			case *ast.BranchStmt:
+				switch n.Tok {
+				case token.FALLTHROUGH:
+					err = fmt.Errorf("selection contains a fallthrough statement")
+				case token.GOTO:
+					if !st.inSelection(info.Uses[n.Label].Pos()) {
+						err = fmt.Errorf("goto %s jumps out of the selection", n.Label.Name)
+					}
+				}
+			case *ast.ReturnStmt:
+				if results := st.sig.Results(); results.Len() > 0 {
+					if len(n.Results) == 0 {
+						err = fmt.Errorf("selection contains a return statement without operands")
+					} else if len(n.Results) != results.Len() {
+						err = fmt.Errorf("selection returns the results of a call")
+					}
+				}
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Reject jumps into the selection.
+	ast.Inspect(st.decl, func(n ast.Node) bool {
+		if n != nil && st.inSelection(n.Pos()) {
+			return false
+		}
+		if br, ok := n.(*ast.BranchStmt); ok && br.Tok == token.GOTO && st.inSelection(info.Uses[br.Label].Pos()) {
+			err = fmt.Errorf("goto %s jumps into the selection", br.Label.Name)
+		}
+		return err == nil
+	})
+	return err
+}
+
+// variables returns the parameters and results of the new function.
+func (st *state) variables() ([]*variable, error) {
+	info := st.sel.Info
+
+	// Find the assigned and address-taken variables.
+	var (
+		escapes           = make(map[*types.Var]bool)
+		assigned          = make(map[*types.Var]bool) // assigned by the selection
+		assignedElsewhere = make(map[*types.Var]bool) // assigned outside the selection
+	)
+	inline.Escape(info, st.decl, func(v *types.Var, escaping bool) {
+		if escaping {
+			escapes[v] = true
+		}
+	})
+	for _, stmt := range st.stmts {
+		inline.Escape(info, stmt, func(v *types.Var, _ bool) { assigned[v] = true })
+	}
+	ast.Inspect(st.decl, func(n ast.Node) bool {
+		switch {
+		case n == nil:
+		case n.End() <= st.start || st.end <= n.Pos(): // disjoint
+			inline.Escape(info, n, func(v *types.Var, _ bool) { assignedElsewhere[v] = true })
+		case n.Pos() < st.start || st.end < n.End(): // encloses the selection
+			return true
+		}
+		return false
+	})
+
+	// Find the variables captured by function literals
+	// within and outside the selection.
+	capturedWithin := make(map[*types.Var]bool)
+	capturedOutside := make(map[*types.Var]bool)
+	ast.Inspect(st.decl, func(n ast.Node) bool {
+		lit, ok := n.(*ast.FuncLit)
+		if !ok {
+			return true
+		}
+		captured := capturedOutside
+		if st.inSelection(lit.Pos()) {
+			captured = capturedWithin
+		}
+		ast.Inspect(lit.Body, func(n ast.Node) bool {
+			if id, ok := n.(*ast.Ident); ok {
+				if v, ok := info.Uses[id].(*types.Var); ok && !v.IsField() && !(lit.Pos() <= v.Pos() && v.Pos() < lit.End()) {
+					captured[v] = true
+				}
+			}
+			return true
+		})
+		return true
+	})
+
+	// Find the uses of local variables outside the selection.
+	// Named results are used by bare returns and deferred calls.
+	usedOutside := make(map[*types.Var]bool)
+	for v := range st.sig.Results().Variables() {
+		if v.Name() != "" {
+			usedOutside[v] = true
+		}
+	}
+	for id, obj := range info.Uses {
+		if v, ok := obj.(*types.Var); ok && st.decl.Pos() <= id.Pos() && id.Pos() < st.decl.End() && !st.inSelection(id.Pos()) {
+			usedOutside[v] = true
+		}
+	}
+
+	var (
+		vars   []*variable
+		byVar  = make(map[*types.Var]*variable)
+		err    error
+		params = make(map[string]bool) // names of parameters
+	)
+	add := func(v *types.Var) *variable {
+		x, ok := byVar[v]
+		if !ok {
+			x = &variable{v: v}
+			byVar[v] = x
+			vars = append(vars, x)
+		}
+		return x
+	}
+	for _, stmt := range st.stmts {
+		ast.Inspect(stmt, func(n ast.Node) bool {
+			id, ok := n.(*ast.Ident)
+			if !ok || err != nil {
+				return err == nil
+			}
+			if obj := info.Defs[id]; obj != nil && obj.Parent() == st.scope {
+				// Declared at the top level of the selection.
+				switch obj := obj.(type) {
+				case *types.Var:
+					if usedOutside[obj] {
+						// The variable of the caller must be the
+						// only one, so it must not be shared.
+						if escapes[obj] {
+							err = fmt.Errorf("selection declares variable %s, whose address is taken", obj.Name())
+							return false
+						}
+						if capturedWithin[obj] && (assigned[obj] || assignedElsewhere[obj]) {
+							err = fmt.Errorf("a function literal in the selection captures variable %s, which is assigned", obj.Name())
+							return false
+						}
+						if err = st.checkType(obj.Type()); err != nil {
+							return false
+						}
+						add(obj).decl = true
+					}
+				default:
+					if st.usedOutside(obj) {
+						err = fmt.Errorf("%s %s is declared by the selection and used after it", objectKind(obj), obj.Name())
+					}
+				}
+				return true
+			}
+			obj := info.Uses[id]
+			if obj == nil || typesinternal.IsPackageLevel(obj) || obj.Pkg() != st.sel.Types || st.inSelection(obj.Pos()) {
+				return true
+			}
+			switch obj := obj.(type) {
+			case *types.Var:
+				if obj.IsField() {
+					break
+				}
+				if escapes[obj] {
+					err = fmt.Errorf("selection uses variable %s, whose address is taken", obj.Name())
+					return false
+				}
+				if capturedWithin[obj] && (assigned[obj] || assignedElsewhere[obj]) {
+					err = fmt.Errorf("a function literal in the selection captures variable %s, which is assigned", obj.Name())
+					return false
+				}
+				if assigned[obj] && capturedOutside[obj] {
+					err = fmt.Errorf("selection assigns variable %s, which is captured by a function literal", obj.Name())
+					return false
+				}
+				if err = st.checkType(obj.Type()); err != nil {
+					return false
+				}
+				x := add(obj)
+				x.param = true
+				params[obj.Name()] = true
+				// The variable must be updated even if it is not
+				// used after the selection, since a loop that encloses
+				// the selection may use it again.
+				if assigned[obj] {
+					x.store = true
+				}
+			case *types.Const, *types.TypeName:
+				err = fmt.Errorf("selection refers to local %s %s", objectKind(obj), obj.Name())
+			}
+			return err == nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, x := range vars {
+		if x.decl && params[x.v.Name()] {
+			return nil, fmt.Errorf("selection uses two variables named %s", x.v.Name())
+		}
+	}
+	return vars, nil
+}
+
+// usedOutside reports whether obj is used outside the selection.
+func (st *state) usedOutside(obj types.Object) bool {
+	for id, use := range st.sel.Info.Uses {
+		if use == obj && !st.inSelection(id.Pos()) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkType reports an error if t, the type of a parameter or result,
+// refers to a type declared within a function, or to a type parameter.
+func (st *state) checkType(t types.Type) error {
+	var err error
+	var visit func(t types.Type)
+	visit = func(t types.Type) {
+		if err != nil {
+			return
+		}
+		switch t := t.(type) {
+		case *types.TypeParam:
+			err = fmt.Errorf("selection uses type parameter %s", t.Obj().Name())
+		case *types.Named:
+			if obj := t.Obj(); obj.Pkg() != nil && !typesinternal.IsPackageLevel(obj) {
+				err = fmt.Errorf("selection uses local type %s", obj.Name())
+			}
+			for t := range t.TypeArgs().Types() {
+				visit(t)
+			}
+		case *types.Alias:
+			if obj := t.Obj(); obj.Pkg() != nil && !typesinternal.IsPackageLevel(obj) {
+				err = fmt.Errorf("selection uses local type %s", obj.Name())
+			}
+			visit(types.Unalias(t))
+		case *types.Pointer:
+			visit(t.Elem())
+		case *types.Slice:
+			visit(t.Elem())
+		case *types.Array:
+			visit(t.Elem())
+		case *types.Chan:
+			visit(t.Elem())
+		case *types.Map:
+			visit(t.Key())
+			visit(t.Elem())
+		case *types.Signature:
+			for v := range t.Params().Variables() {
+				visit(v.Type())
+			}
+			for v := range t.Results().Variables() {
+				visit(v.Type())
+			}
+		case *types.Struct:
+			for f := range t.Fields() {
+				visit(f.Type())
+			}
+		}
+	}
+	visit(t)
+	return err
+}
+
+// jumps returns the jumps out of the selection, and their flags.
+func (st *state) jumps() ([]jump, []flag) {
+	info := st.sel.Info
+	var (
+		jumps []jump
+		flags []flag
+	)
+	addJump := func(stmt ast.Stmt, key, name, callStmt string) {
+		i := slices.IndexFunc(flags, func(f flag) bool { return f.key == key })
+		if i < 0 {
+			i = len(flags)
+			flags = append(flags, flag{name: name, stmt: callStmt, key: key})
+		}
+		jumps = append(jumps, jump{stmt: stmt, flag: i})
+	}
+	for _, stmt := range st.stmts {
+		inspectNoLits(stmt, func(n ast.Node) {
+			switch n := n.(type) {
+			case *ast.ReturnStmt:
+				addJump(n, "return", "shouldReturn", "return")
+
+			case *ast.BranchStmt:
+				if n.Tok != token.BREAK && n.Tok != token.CONTINUE {
+					break
+				}
+				var target token.Pos // position of the target statement
+				if n.Label != nil {
+					target = info.Uses[n.Label].Pos()
+				} else {
+					path, _ := astutil.PathEnclosingInterval(st.sel.File, n.Pos(), n.End())
+				outer:
+					for _, n2 := range path[1:] {
+						switch n2.(type) {
+						case *ast.ForStmt, *ast.RangeStmt:
+							target = n2.Pos()
+							break outer
+						case *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt:
+							if n.Tok == token.BREAK {
+								target = n2.Pos()
+								break outer
+							}
+						}
+					}
+				}
+				if st.inSelection(target) {
+					break // the jump remains within the selection
+				}
+				name := "should" + capitalize(n.Tok.String())
+				key := n.Tok.String()
+				if n.Label != nil {
+					name += capitalize(n.Label.Name)
+					key += " " + n.Label.Name
+				}
+				addJump(n, key, name, key)
+			}
+		})
+	}
+	return jumps, flags
+}
+
+// jumpOperands returns the operands of the return statement that
+// replaces j in the new function.
+func (st *state) jumpOperands(j jump, vars []*variable, returns bool, flags []flag) ([]string, error) {
+	var operands []string
+	for _, v := range vars {
+		switch {
+		case v.store:
+			// The caller may use the variable after the jump.
+			if _, obj := st.sel.Types.Scope().Innermost(j.stmt.Pos()).LookupParent(v.v.Name(), j.stmt.Pos()); obj != v.v {
+				return nil, fmt.Errorf("variable %s is shadowed at %v", v.v.Name(), st.sel.Fset.PositionFor(j.stmt.Pos(), false))
+			}
+			operands = append(operands, v.v.Name())
+		case v.decl:
+			// The variable is out of scope after the jump.
+			zero, err := st.zero(v.v.Type())
+			if err != nil {
+				return nil, err
+			}
+			operands = append(operands, zero)
+		}
+	}
+	if returns {
+		if ret, ok := j.stmt.(*ast.ReturnStmt); ok {
+			content := st.sel.Content
+			file := st.sel.Fset.File(ret.Pos())
+			for _, res := range ret.Results {
+				operands = append(operands, string(content[file.Offset(res.Pos()):file.Offset(res.End())]))
+			}
+		} else {
+			for v := range st.sig.Results().Variables() {
+				zero, err := st.zero(v.Type())
+				if err != nil {
+					return nil, err
+				}
+				operands = append(operands, zero)
+			}
+		}
+	}
+	for i := range flags {
+		operands = append(operands, strconv.FormatBool(i == j.flag))
+	}
+	return operands, nil
+}
+
+// endsWithJump reports whether the last selected statement is a jump,
+// so that the new function needs no final return statement.
+func (st *state) endsWithJump(jumps []jump) bool {
+	return slices.ContainsFunc(jumps, func(j jump) bool { return j.stmt == last(st.stmts) })
+}
+
+// call returns the statements that replace the selected ones: the call
+// of the new function, and the jumps it indicates.
+func (st *state) call(name string, vars []*variable, returnValues []string, flags []flag) string {
+	var args, lhs, newVars []string
+	var decls []string // declarations of new variables, if the call must use "="
+	hasStore := false
+	for _, v := range vars {
+		if v.param {
+			args = append(args, v.v.Name())
+		}
+	}
+	for _, v := range vars {
+		if v.decl || v.store {
+			lhs = append(lhs, v.v.Name())
+		}
+		if v.decl {
+			newVars = append(newVars, v.v.Name())
+			decls = append(decls, v.v.Name()+" "+types.TypeString(v.v.Type(), st.qual))
+		}
+		hasStore = hasStore || v.store
+	}
+	for i, name := range returnValues {
+		lhs = append(lhs, name)
+		newVars = append(newVars, name)
+		decls = append(decls, name+" "+types.TypeString(st.sig.Results().At(i).Type(), st.qual))
+	}
+	for _, f := range flags {
+		lhs = append(lhs, f.name)
+		newVars = append(newVars, f.name)
+		decls = append(decls, f.name+" bool")
+	}
+
+	indent := st.indent()
+	var b strings.Builder
+	callExpr := fmt.Sprintf("%s(%s)", name, strings.Join(args, ", "))
+	switch {
+	case len(lhs) == 0:
+		b.WriteString(callExpr)
+	case !hasStore:
+		fmt.Fprintf(&b, "%s := %s", strings.Join(lhs, ", "), callExpr)
+	case len(newVars) == 0:
+		fmt.Fprintf(&b, "%s = %s", strings.Join(lhs, ", "), callExpr)
+	default:
+		// A short variable declaration would declare
+		// new variables for those that are assigned.
+		if len(decls) == 1 {
+			fmt.Fprintf(&b, "var %s\n%s", decls[0], indent)
+		} else {
+			fmt.Fprintf(&b, "var (\n%s\t%s\n%s)\n%s", indent, strings.Join(decls, "\n"+indent+"\t"), indent, indent)
+		}
+		fmt.Fprintf(&b, "%s = %s", strings.Join(lhs, ", "), callExpr)
+	}
+	for _, f := range flags {
+		jump := f.stmt
+		if f.key == "return" && len(returnValues) > 0 {
+			jump += " " + strings.Join(returnValues, ", ")
+		}
+		fmt.Fprintf(&b, "\n%sif %s {\n%s\t%s\n%s}", indent, f.name, indent, jump, indent)
+	}
+	return b.String()
+}
+
+// indent returns the indentation of the line of the first statement.
+func (st *state) indent() string {
+	content := st.sel.Content
+	offset := st.sel.Fset.File(st.start).Offset(st.start)
+	lineStart := bytes.LastIndexByte(content[:offset], '\n') + 1
+	indent := content[lineStart:offset]
+	if len(bytes.TrimLeft(indent, " \t")) > 0 {
+		return "" // not at the start of a line
+	}
+	return string(indent)
+}
+
+// zero returns the zero value of type t.
+func (st *state) zero(t types.Type) (string, error) {
+	zero, ok := typesinternal.ZeroString(t, st.qual)
+	if !ok {
+		return "", fmt.Errorf("cannot express the zero value of type %s", types.TypeString(t, st.qual))
+	}
+	return zero, nil
+}
+
+// freshName returns a name, based on name, that is not used within
+// the enclosing declaration, nor declared in the package (if pkgLevel)
+// or the universe.
+func (st *state) freshName(name string, pkgLevel bool) string {
+	base := name
+	for i := 1; ; i++ {
+		taken := st.names[name] || types.Universe.Lookup(name) != nil
+		if pkgLevel {
+			taken = taken || st.sel.Types.Scope().Lookup(name) != nil
+		}
+		if !taken {
+			st.names[name] = true
+			return name
+		}
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+}
+
+// inspectNoLits calls f for each node of the tree rooted at root,
+// except those within function literals.
+func inspectNoLits(root ast.Node, f func(ast.Node)) {
+	ast.Inspect(root, func(n ast.Node) bool {
+		if _, ok := n.(*ast.FuncLit); ok {
+			return false
+		}
+		if n != nil {
+			f(n)
+		}
+		return true
+	})
+}
+
+// objectKind returns the kind of a local object, for error messages.
+func objectKind(obj types.Object) string {
+	switch obj.(type) {
+	case *types.Const:
+		return "constant"
+	case *types.TypeName:
+		return "type"
+	case *types.Label:
+		return "label"
+	}
+	return "variable"
+}
+
+// capitalize returns s with its first letter in upper case.
+func capitalize(s string) string {
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+func last[T any](slice []T) T { return slice[len(slice)-1] }
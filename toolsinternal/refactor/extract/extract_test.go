@@ -0,0 +1,645 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package extract_test
+
+import (
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/tenntenn/exp/toolsinternal/diff"
+	"github.com/tenntenn/exp/toolsinternal/refactor/extract"
+	"github.com/tenntenn/exp/toolsinternal/testenv"
+)
+
+// Each test extracts the statements between « and » in src, and
+// expects the resulting file content, or an error matching a
+// substring.
+var tests = []struct {
+	name string
+	src  string
+	want string // file content, or "error: substring"
+}{
+	{
+		"Parameters and a declared result.",
+		`package p
+
+func f(a, b int) int {
+	«c := a + b
+	c *= 2»
+	return c
+}`,
+		`package p
+
+func f(a, b int) int {
+	c := newFunction(a, b)
+	return c
+}
+
+func newFunction(a int, b int) int {
+	c := a + b
+	c *= 2
+	return c
+}
+`,
+	},
+	{
+		"No parameters or results.",
+		`package p
+
+func f() {
+	«println("hello")»
+}`,
+		`package p
+
+func f() {
+	newFunction()
+}
+
+func newFunction() {
+	println("hello")
+}
+`,
+	},
+	{
+		"An assigned variable used afterwards is a parameter and a result.",
+		`package p
+
+func f(a int) int {
+	x := 1
+	«x += a»
+	return x
+}`,
+		`package p
+
+func f(a int) int {
+	x := 1
+	x = newFunction(x, a)
+	return x
+}
+
+func newFunction(x int, a int) int {
+	x += a
+	return x
+}
+`,
+	},
+	{
+		"A variable carried across loop iterations is a result.",
+		`package p
+
+func f(xs []int) {
+	prev := 0
+	for _, x := range xs {
+		«println(prev, x)
+		prev = x»
+	}
+}`,
+		`package p
+
+func f(xs []int) {
+	prev := 0
+	for _, x := range xs {
+		prev = newFunction(prev, x)
+	}
+}
+
+func newFunction(prev int, x int) int {
+	println(prev, x)
+	prev = x
+	return prev
+}
+`,
+	},
+	{
+		"Declared and assigned results require a var declaration.",
+		`package p
+
+func f(a int) int {
+	x := 1
+	«x += a
+	y := x * 2»
+	return x + y
+}`,
+		`package p
+
+func f(a int) int {
+	x := 1
+	var y int
+	x, y = newFunction(x, a)
+	return x + y
+}
+
+func newFunction(x int, a int) (int, int) {
+	x += a
+	y := x * 2
+	return x, y
+}
+`,
+	},
+	{
+		"Returns are replaced by a flag and the return values.",
+		`package p
+
+func f(x int) (int, error) {
+	«if x < 0 {
+		return -1, nil
+	}
+	y := x * 2»
+	return y, nil
+}`,
+		`package p
+
+func f(x int) (int, error) {
+	y, returnValue, returnValue1, shouldReturn := newFunction(x)
+	if shouldReturn {
+		return returnValue, returnValue1
+	}
+	return y, nil
+}
+
+func newFunction(x int) (int, int, error, bool) {
+	if x < 0 {
+		return 0, -1, nil, true
+	}
+	y := x * 2
+	return y, 0, nil, false
+}
+`,
+	},
+	{
+		"A return from a function without results.",
+		`package p
+
+func f(x int) {
+	«if x < 0 {
+		return
+	}
+	println(x)»
+}`,
+		`package p
+
+func f(x int) {
+	shouldReturn := newFunction(x)
+	if shouldReturn {
+		return
+	}
+}
+
+func newFunction(x int) bool {
+	if x < 0 {
+		return true
+	}
+	println(x)
+	return false
+}
+`,
+	},
+	{
+		"Break and continue are replaced by flags.",
+		`package p
+
+func f(xs []int) int {
+	sum := 0
+	for _, x := range xs {
+		«if x < 0 {
+			break
+		}
+		if x == 0 {
+			continue
+		}
+		sum += x»
+	}
+	return sum
+}`,
+		`package p
+
+func f(xs []int) int {
+	sum := 0
+	for _, x := range xs {
+		var (
+			shouldBreak    bool
+			shouldContinue bool
+		)
+		sum, shouldBreak, shouldContinue = newFunction(x, sum)
+		if shouldBreak {
+			break
+		}
+		if shouldContinue {
+			continue
+		}
+	}
+	return sum
+}
+
+func newFunction(x int, sum int) (int, bool, bool) {
+	if x < 0 {
+		return sum, true, false
+	}
+	if x == 0 {
+		return sum, false, true
+	}
+	sum += x
+	return sum, false, false
+}
+`,
+	},
+	{
+		"Labeled continue.",
+		`package p
+
+func f(m [][]int) {
+outer:
+	for _, row := range m {
+		for _, x := range row {
+			«if x == 0 {
+				continue outer
+			}
+			println(x)»
+		}
+	}
+}`,
+		`package p
+
+func f(m [][]int) {
+outer:
+	for _, row := range m {
+		for _, x := range row {
+			shouldContinueOuter := newFunction(x)
+			if shouldContinueOuter {
+				continue outer
+			}
+		}
+	}
+}
+
+func newFunction(x int) bool {
+	if x == 0 {
+		return true
+	}
+	println(x)
+	return false
+}
+`,
+	},
+	{
+		"Jumps within the selection are unchanged.",
+		`package p
+
+func f(xs []int) {
+	«for _, x := range xs {
+		if x < 0 {
+			break
+		}
+		switch x {
+		case 0:
+			continue
+		}
+		println(x)
+	}»
+}`,
+		`package p
+
+func f(xs []int) {
+	newFunction(xs)
+}
+
+func newFunction(xs []int) {
+	for _, x := range xs {
+		if x < 0 {
+			break
+		}
+		switch x {
+		case 0:
+			continue
+		}
+		println(x)
+	}
+}
+`,
+	},
+	{
+		"Names are made unique.",
+		`package p
+
+func newFunction() {}
+
+func f(shouldReturn bool) {
+	«if shouldReturn {
+		return
+	}»
+	println()
+}`,
+		`package p
+
+func newFunction() {}
+
+func f(shouldReturn bool) {
+	shouldReturn1 := newFunction1(shouldReturn)
+	if shouldReturn1 {
+		return
+	}
+	println()
+}
+
+func newFunction1(shouldReturn bool) bool {
+	if shouldReturn {
+		return true
+	}
+	return false
+}
+`,
+	},
+	{
+		"Types are qualified relative to the file.",
+		`package p
+
+import str "strings"
+
+func f() string {
+	«r := str.NewReplacer("a", "b")»
+	return r.Replace("abc")
+}`,
+		`package p
+
+import str "strings"
+
+func f() string {
+	r := newFunction()
+	return r.Replace("abc")
+}
+
+func newFunction() *str.Replacer {
+	r := str.NewReplacer("a", "b")
+	return r
+}
+`,
+	},
+	{
+		"Declared variable whose address is taken.",
+		`package p
+
+import "strings"
+
+func f() string {
+	«var b strings.Builder
+	b.WriteString("x")»
+	return b.String()
+}`,
+		"error: selection declares variable b, whose address is taken",
+	},
+	{
+		"Closures may capture variables that are not assigned.",
+		`package p
+
+func f(xs []int) func() int {
+	«g := func() int { return len(xs) }»
+	return g
+}`,
+		`package p
+
+func f(xs []int) func() int {
+	g := newFunction(xs)
+	return g
+}
+
+func newFunction(xs []int) func() int {
+	g := func() int { return len(xs) }
+	return g
+}
+`,
+	},
+	{
+		"Address-taken variable.",
+		`package p
+
+func f() int {
+	x := 1
+	p := &x
+	«x++»
+	return *p
+}`,
+		"error: address is taken",
+	},
+	{
+		"Defer.",
+		`package p
+
+func f() {
+	«defer println()»
+}`,
+		"error: defer",
+	},
+	{
+		"Recover.",
+		`package p
+
+func f() {
+	defer func() {
+		«recover()»
+	}()
+}`,
+		"error: recover",
+	},
+	{
+		"Closure capturing an assigned variable.",
+		`package p
+
+func f() func() int {
+	x := 1
+	«g := func() int { return x }»
+	x = 2
+	return g
+}`,
+		"error: captures variable x, which is assigned",
+	},
+	{
+		"Assignment to a variable captured by a closure.",
+		`package p
+
+func f() int {
+	x := 1
+	g := func() int { return x }
+	«x = 2»
+	return g()
+}`,
+		"error: captured by a function literal",
+	},
+	{
+		"Closure capturing a declared variable assigned afterwards.",
+		`package p
+
+func f() int {
+	«x := 1
+	g := func() int { return x }»
+	x = 2
+	return g()
+}`,
+		"error: captures variable x, which is assigned",
+	},
+	{
+		"Closure assigning a declared variable.",
+		`package p
+
+func f() int {
+	«x := 0
+	inc := func() { x++ }»
+	inc()
+	return x
+}`,
+		"error: captures variable x, which is assigned",
+	},
+	{
+		"Goto out of the selection.",
+		`package p
+
+func f(x int) {
+	«if x > 0 {
+		goto done
+	}»
+done:
+}`,
+		"error: goto done jumps out",
+	},
+	{
+		"Local type.",
+		`package p
+
+func f() {
+	type T int
+	var x T
+	«println(x)»
+}`,
+		"error: local type T",
+	},
+	{
+		"Type parameter.",
+		`package p
+
+func f[T any](x T) {
+	«println(x)»
+}`,
+		"error: type parameter T",
+	},
+	{
+		"Return of a multi-valued call.",
+		`package p
+
+func g() (int, error) { return 0, nil }
+
+func f() (int, error) {
+	«return g()»
+}`,
+		"error: returns the results of a call",
+	},
+	{
+		"Partial statement.",
+		`package p
+
+func f() {
+	x := «1 + 2»
+	println(x)
+}`,
+		"error: selection does not consist of whole statements",
+	},
+	{
+		"Declaration used after the selection.",
+		`package p
+
+func f() {
+	«const c = 1»
+	println(c)
+}`,
+		"error: constant c is declared by the selection and used after it",
+	},
+}
+
+func TestExtract(t *testing.T) {
+	testenv.NeedsDefaultImporter(t)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			src := test.src
+			start := strings.Index(src, "«")
+			src = strings.Replace(src, "«", "", 1)
+			end := strings.Index(src, "»")
+			src = strings.Replace(src, "»", "", 1)
+
+			fset := token.NewFileSet()
+			f, err := parser.ParseFile(fset, "p.go", src, parser.ParseComments)
+			if err != nil {
+				t.Fatal(err)
+			}
+			info := &types.Info{
+				Types:      make(map[ast.Expr]types.TypeAndValue),
+				Defs:       make(map[*ast.Ident]types.Object),
+				Uses:       make(map[*ast.Ident]types.Object),
+				Implicits:  make(map[ast.Node]types.Object),
+				Selections: make(map[*ast.SelectorExpr]*types.Selection),
+				Scopes:     make(map[ast.Node]*types.Scope),
+			}
+			conf := &types.Config{Importer: importer.Default()}
+			pkg, err := conf.Check("p", fset, []*ast.File{f}, info)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tokFile := fset.File(f.FileStart)
+			sel := &extract.Selection{
+				Fset:    fset,
+				Types:   pkg,
+				Info:    info,
+				File:    f,
+				Start:   tokFile.Pos(start),
+				End:     tokFile.Pos(end),
+				Content: []byte(src),
+			}
+			res, err := extract.Extract(sel, &extract.Options{Logf: t.Logf})
+			if want, ok := strings.CutPrefix(test.want, "error: "); ok {
+				if err == nil {
+					t.Fatalf("Extract succeeded, want error containing %q; output:\n%s", want, res.Content)
+				}
+				if !strings.Contains(err.Error(), want) {
+					t.Fatalf("Extract failed with %q, want error containing %q", err, want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(res.Content); got != test.want {
+				t.Errorf("Extract: got\n%s\nwant:\n%s\ndiff:\n%s", got, test.want, diff.Unified("want", "got", test.want, got))
+			}
+
+			// The edits, once formatted, give the same result.
+			edited, err := diff.ApplyBytes([]byte(src), res.Edits)
+			if err != nil {
+				t.Fatal(err)
+			}
+			formatted, err := format.Source(edited)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(formatted) != string(res.Content) {
+				t.Errorf("formatted edits differ from Content:\n%s", diff.Unified("Content", "edits", string(res.Content), string(formatted)))
+			}
+
+			// The result is well typed.
+			fset2 := token.NewFileSet()
+			f2, err := parser.ParseFile(fset2, "p.go", res.Content, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := conf.Check("p", fset2, []*ast.File{f2}, nil); err != nil {
+				t.Errorf("extracted code does not type-check: %v", err)
+			}
+		})
+	}
+}
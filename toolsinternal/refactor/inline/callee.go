@@ -30,6 +30,10 @@ type Callee struct {
 
 func (callee *Callee) String() string { return callee.impl.Name }
 
+// gobCallee is the serialized form of a Callee. Changes to it, or to
+// the analysis of AnalyzeCallee that populates it, must be accompanied
+// by an increment of gofix/calleecache.Version, lest stale cache
+// entries be used.
 type gobCallee struct {
 	Content []byte // file content, compacted to a single func decl
 
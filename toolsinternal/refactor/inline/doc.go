@@ -233,6 +233,13 @@ which they can be addressed.
 
 More complex callee functions are inlinable with more elaborate and
 invasive changes to the statements surrounding the call expression.
+For example, a call statement f(x), or an assignment y := f(x), is
+reducible even if the body of f has several return statements: each
+return statement becomes an assignment of its operands to y, and the
+statements that follow an early return are moved into an else block,
+or, where that is not possible, the early return jumps to a label at
+the end of the inlined statements. (This is not possible if f uses
+defer, as the deferred calls would run at the end of the caller.)
 
 TODO(adonovan): future work:
 
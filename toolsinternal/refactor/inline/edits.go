@@ -0,0 +1,174 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package inline
+
+// This file computes the structured edits of a Result.
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tenntenn/exp/toolsinternal/diff"
+)
+
+// localEdits returns minimal edits that transform old, the text at offset
+// base of a file, into new. The edits are line-based, but trimmed of the
+// text that their old and new parts have in common.
+func localEdits(base int, old, new string) []diff.Edit {
+	edits := diff.Lines(old, new, nil)
+	for i, e := range edits {
+		x, y := old[e.Start:e.End], e.New
+		prefix := commonPrefixLen(x, y)
+		suffix := commonSuffixLen(x[prefix:], y[prefix:])
+		edits[i] = diff.Edit{
+			Start: base + e.Start + prefix,
+			End:   base + e.End - suffix,
+			New:   y[prefix : len(y)-suffix],
+		}
+	}
+	return edits
+}
+
+// commonPrefixLen returns the length of the common prefix of x and y,
+// reduced to a rune boundary.
+func commonPrefixLen(x, y string) int {
+	n := 0
+	for n < len(x) && n < len(y) && x[n] == y[n] {
+		n++
+	}
+	for n > 0 && n < len(x) && !utf8.RuneStart(x[n]) {
+		n--
+	}
+	return n
+}
+
+// commonSuffixLen returns the length of the common suffix of x and y,
+// reduced to a rune boundary.
+func commonSuffixLen(x, y string) int {
+	n := 0
+	for n < len(x) && n < len(y) && x[len(x)-1-n] == y[len(y)-1-n] {
+		n++
+	}
+	for n > 0 && n < len(x) && !utf8.RuneStart(x[len(x)-n]) {
+		n--
+	}
+	return n
+}
+
+// lineIndent returns the space and tabs that begin the line of content
+// that contains offset.
+func lineIndent(content []byte, offset int) string {
+	start := bytes.LastIndexByte(content[:offset], '\n') + 1
+	end := start
+	for end < offset && (content[end] == ' ' || content[end] == '\t') {
+		end++
+	}
+	return string(content[start:end])
+}
+
+// indent adds prefix to every non-empty line of the formatted Go code
+// src after the first, except those within raw string literals, so that
+// the code may replace text on a line with that indentation.
+func indent(src, prefix string) string {
+	if prefix == "" || !strings.Contains(src, "\n") {
+		return src
+	}
+
+	// Find the raw string literals that span lines.
+	type span struct{ start, end int }
+	var raw []span
+	var s scanner.Scanner
+	file := token.NewFileSet().AddFile("", -1, len(src))
+	s.Init(file, []byte(src), nil, scanner.ScanComments)
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok == token.STRING && strings.HasPrefix(lit, "`") && strings.Contains(lit, "\n") {
+			start := file.Offset(pos)
+			raw = append(raw, span{start, start + len(lit)})
+		}
+	}
+
+	var b strings.Builder
+	offset := 0 // offset of line in src
+	for i, line := range strings.SplitAfter(src, "\n") {
+		inRaw := false
+		for _, r := range raw {
+			if r.start < offset && offset < r.end {
+				inRaw = true
+			}
+		}
+		if i > 0 && line != "" && line != "\n" && !inRaw {
+			b.WriteString(prefix)
+		}
+		b.WriteString(line)
+		offset += len(line)
+	}
+	return b.String()
+}
+
+// computeImportEdits returns the edits that transform the import
+// declarations of the caller file into those of newSrc, the formatted
+// result of the transformation.
+func computeImportEdits(caller *Caller, newSrc []byte) ([]diff.Edit, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "new.go", newSrc, parser.ImportsOnly|parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	content := caller.Content
+	oldStart, oldEnd, oldOK := importRange(caller.Fset, caller.File)
+	newStart, newEnd, newOK := importRange(fset, f)
+	switch {
+	case !oldOK && !newOK:
+		return nil, nil
+
+	case !oldOK:
+		// Insert the new declarations after the package clause.
+		pos := offsetOf(caller.Fset, caller.File.Name.End())
+		if i := bytes.IndexByte(content[pos:], '\n'); i >= 0 {
+			pos += i
+		} else {
+			pos = len(content)
+		}
+		return []diff.Edit{{Start: pos, End: pos, New: "\n\n" + string(newSrc[newStart:newEnd])}}, nil
+
+	case !newOK:
+		// Delete the old declarations and the blank lines after them.
+		end := oldEnd
+		for end < len(content) && content[end] == '\n' {
+			end++
+		}
+		return []diff.Edit{{Start: oldStart, End: end}}, nil
+	}
+	return localEdits(oldStart, string(content[oldStart:oldEnd]), string(newSrc[newStart:newEnd])), nil
+}
+
+// importRange returns the range of offsets of the import declarations of
+// a file, without their doc comments, and whether it has any.
+func importRange(fset *token.FileSet, f *ast.File) (start, end int, ok bool) {
+	var first, last *ast.GenDecl
+	for _, decl := range f.Decls {
+		decl, ok := decl.(*ast.GenDecl)
+		if !ok || decl.Tok != token.IMPORT {
+			break
+		}
+		if first == nil {
+			first = decl
+		}
+		last = decl
+	}
+	if first == nil {
+		return 0, 0, false
+	}
+	return offsetOf(fset, first.Pos()), offsetOf(fset, last.End()), true
+}
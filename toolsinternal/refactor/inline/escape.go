@@ -11,6 +11,12 @@ import (
 	"go/types"
 )
 
+// Escape is the escape analysis used by the inliner, exported for use
+// by other refactorings, such as extract. See escape for details.
+func Escape(info *types.Info, root ast.Node, f func(v *types.Var, escapes bool)) {
+	escape(info, root, f)
+}
+
 // escape implements a simple "address-taken" escape analysis. It
 // calls f for each local variable that appears on the left side of an
 // assignment (escapes=false) or has its address taken (escapes=true).
@@ -24,6 +24,7 @@ import (
 	"golang.org/x/tools/go/ast/astutil"
 	"golang.org/x/tools/go/types/typeutil"
 	"github.com/tenntenn/exp/toolsinternal/analysisinternal"
+	"github.com/tenntenn/exp/toolsinternal/diff"
 	internalastutil "github.com/tenntenn/exp/toolsinternal/astutil"
 	"github.com/tenntenn/exp/toolsinternal/typeparams"
 	"github.com/tenntenn/exp/toolsinternal/typesinternal"
@@ -32,6 +33,8 @@ import (
 // A Caller describes the function call and its enclosing context.
 //
 // The client is responsible for populating this struct and passing it to Inline.
+// Info must record the Instances of the file, so that the type
+// arguments of a call to a generic function may be inferred.
 type Caller struct {
 	Fset    *token.FileSet
 	Types   *types.Package
@@ -49,8 +52,9 @@ type logger = func(string, ...any)
 // Options specifies parameters affecting the inliner algorithm.
 // All fields are optional.
 type Options struct {
-	Logf          logger // log output function, records decision-making process
-	IgnoreEffects bool   // ignore potential side effects of arguments (unsound)
+	Logf          logger        // log output function, records decision-making process
+	IgnoreEffects bool          // ignore potential side effects of arguments (unsound)
+	Report        *InlineReport // if non-nil, records the reasons that prevented reduction of the call
 }
 
 // Result holds the result of code transformation.
@@ -58,11 +62,17 @@ type Result struct {
 	Content     []byte // formatted, transformed content of caller file
 	Literalized bool   // chosen strategy replaced callee() with func(){...}()
 	BindingDecl bool   // transformation added "var params = args" declaration
-
-	// TODO(adonovan): provide an API for clients that want structured
-	// output: a list of import additions and deletions plus one or more
-	// localized diffs (or even AST transformations, though ownership and
-	// mutation are tricky) near the call site.
+	Label       bool   // transformation added a label to the caller's function
+
+	// Edits and ImportEdits are the transformation in structured form,
+	// as edits to the original content of the caller file: Edits
+	// replace the call, or the statement or block that encloses it,
+	// and ImportEdits change the import declarations. Unlike Content,
+	// they leave the rest of the file as it was, so the edits of
+	// several inlinings in one file can be combined with diff.Merge.
+	// Applying both lists and formatting the file gives Content.
+	Edits       []diff.Edit // minimal edits near the call site
+	ImportEdits []diff.Edit // edits of the import declarations, if any
 }
 
 // Inline inlines the called function (callee) into the function call (caller)
@@ -82,14 +92,27 @@ func Inline(caller *Caller, callee *Callee, opts *Options) (*Result, error) {
 		callee: callee,
 		opts:   opts,
 	}
-	return st.inline()
+	res, err := st.inline()
+	if report := opts.Report; report != nil {
+		switch {
+		case err != nil:
+			if len(st.reasons) == 0 {
+				st.explain(Unsupported, token.NoPos, token.NoPos, "%v", err)
+			}
+		case !res.Literalized && !res.BindingDecl:
+			st.reasons = nil // the call was reduced
+		}
+		report.Reasons = append(report.Reasons, st.reasons...)
+	}
+	return res, err
 }
 
 // state holds the working state of the inliner.
 type state struct {
-	caller *Caller
-	callee *Callee
-	opts   *Options
+	caller  *Caller
+	callee  *Callee
+	opts    *Options
+	reasons []Reason // reasons that prevented reduction of the call
 }
 
 func (st *state) inline() (*Result, error) {
@@ -199,6 +222,8 @@ func (st *state) inline() (*Result, error) {
 					logf("keeping block braces: caller uses control labels")
 				} else if intersects(declares(newBlock.List), callerNames) {
 					logf("keeping block braces: avoids name conflict")
+				} else if is[*ast.LabeledStmt](last(newBlock.List)) {
+					logf("keeping block braces: block ends with a label")
 				} else {
 					elideBraces = true
 				}
@@ -232,11 +257,11 @@ func (st *state) inline() (*Result, error) {
 		}
 		return nil
 	}
+	var callEdits []diff.Edit
 	{
 		start := offsetOf(fset, res.old.Pos())
 		end := offsetOf(fset, res.old.End())
 		var out bytes.Buffer
-		out.Write(content[:start])
 		// TODO(adonovan): might it make more sense to use
 		// callee.Fset when formatting res.new?
 		// The new tree is a mix of (cloned) caller nodes for
@@ -265,8 +290,9 @@ func (st *state) inline() (*Result, error) {
 				return nil, err
 			}
 		}
-		out.Write(content[end:])
-		content = out.Bytes()
+		repl := out.String()
+		callEdits = localEdits(start, string(content[start:end]), indent(repl, lineIndent(content, start)))
+		content = slices.Concat(content[:start], out.Bytes(), content[end:])
 		if err := reparse(); err != nil {
 			return nil, err
 		}
@@ -343,7 +369,7 @@ func (st *state) inline() (*Result, error) {
 			// Check that the new imports are accessible.
 			path, _ := strconv.Unquote(imp.spec.Path.Value)
 			if !analysisinternal.CanImport(caller.Types.Path(), path) {
-				return nil, fmt.Errorf("can't inline function %v as its body refers to inaccessible package %q", callee, path)
+				return nil, st.reject(Unexported, token.NoPos, token.NoPos, "can't inline function %v as its body refers to inaccessible package %q", callee, path)
 			}
 			if lastPos.IsValid() {
 				lastPos++
@@ -386,6 +412,14 @@ func (st *state) inline() (*Result, error) {
 	}
 	newSrc := out.Bytes()
 
+	var importEdits []diff.Edit
+	if len(newImports) > 0 || len(res.oldImports) > 0 {
+		importEdits, err = computeImportEdits(caller, newSrc)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	literalized := false
 	if call, ok := res.new.(*ast.CallExpr); ok && is[*ast.FuncLit](call.Fun) {
 		literalized = true
@@ -393,8 +427,11 @@ func (st *state) inline() (*Result, error) {
 
 	return &Result{
 		Content:     newSrc,
+		Edits:       callEdits,
+		ImportEdits: importEdits,
 		Literalized: literalized,
 		BindingDecl: res.bindingDecl,
+		Label:       res.label,
 	}, nil
 }
 
@@ -604,6 +641,7 @@ type inlineCallResult struct {
 	// replace as little syntax as possible.
 	elideBraces bool
 	bindingDecl bool     // transformation inserted "var params = args" declaration
+	label       bool     // transformation added a label to the caller's function
 	old, new    ast.Node // e.g. replace call expr by callee function body expression
 }
 
@@ -673,7 +711,7 @@ func (st *state) inlineCall() (*inlineCallResult, error) {
 	// free references to unexported symbols.
 	samePkg := caller.Types.Path() == callee.PkgPath
 	if !samePkg && len(callee.Unexported) > 0 {
-		return nil, fmt.Errorf("cannot inline call to %s because body refers to non-exported %s",
+		return nil, st.reject(Unexported, token.NoPos, token.NoPos, "cannot inline call to %s because body refers to non-exported %s",
 			callee.Name, callee.Unexported[0])
 	}
 
@@ -856,12 +894,13 @@ func (st *state) inlineCall() (*inlineCallResult, error) {
 		}
 	}
 
-	typeArgs := st.typeArguments(caller.Call)
-	if len(typeArgs) != len(callee.TypeParams) {
-		return nil, fmt.Errorf("cannot inline: type parameter inference is not yet supported")
+	typeArgs, err := st.typeArguments(caller.Call, istate)
+	if err != nil {
+		return nil, err
 	}
+	res.newImports = istate.newImports // may have been added by typeArguments
 	if err := substituteTypeParams(logf, callee.TypeParams, typeArgs, params, replaceCalleeID); err != nil {
-		return nil, err
+		return nil, st.reject(Shadowing, token.NoPos, token.NoPos, "%v", err) // type argument is shadowed
 	}
 
 	// Log effective arguments.
@@ -876,13 +915,13 @@ func (st *state) inlineCall() (*inlineCallResult, error) {
 
 	// Perform parameter substitution.
 	// May eliminate some elements of params/args.
-	substitute(logf, caller, params, args, callee.Effects, callee.Falcon, replaceCalleeID)
+	substitute(logf, st.explain, caller, params, args, callee.Effects, callee.Falcon, replaceCalleeID)
 
 	// Update the callee's signature syntax.
 	updateCalleeParams(calleeDecl, params)
 
 	// Create a var (param = arg; ...) decl for use by some strategies.
-	bindingDecl := createBindingDecl(logf, caller, args, calleeDecl, callee.Results)
+	bindingDecl := createBindingDecl(logf, st.explain, caller, params, args, calleeDecl, callee.Results)
 
 	var remainingArgs []ast.Expr
 	for _, arg := range args {
@@ -1159,6 +1198,9 @@ func (st *state) inlineCall() (*inlineCallResult, error) {
 				}
 				return res, nil
 			}
+			st.explain(BodyShape, token.NoPos, token.NoPos, "the conversions of the callee's spread return cannot be made explicit")
+		} else if bindingDecl != nil {
+			st.explain(BindingDecl, token.NoPos, token.NoPos, "parameters must be bound by a declaration, which cannot appear in an expression")
 		}
 	}
 
@@ -1176,6 +1218,7 @@ func (st *state) inlineCall() (*inlineCallResult, error) {
 	// - call is a tail-call;
 	// - all returns in body have trivial result conversions,
 	//   or the caller's return type matches the callee's,
+	//   or the conversions can be made explicit;
 	// - there is no label conflict;
 	// - no result variable is referenced by name,
 	//   or implicitly by a bare return.
@@ -1187,23 +1230,30 @@ func (st *state) inlineCall() (*inlineCallResult, error) {
 	// call', i.e. a call statement prior to an (explicit
 	// or implicit) return.
 	parent, _ := callContext(caller.path)
-	if ret, ok := parent.(*ast.ReturnStmt); ok &&
-		len(ret.Results) == 1 &&
-		tailCallSafeReturn(caller, calleeSymbol, callee) &&
-		!callee.HasBareReturn &&
-		(!needBindingDecl || bindingDecl != nil) &&
-		!hasLabelConflict(caller.path, callee.Labels) &&
-		allResultsUnreferenced {
-		logf("strategy: reduce tail-call")
-		body := calleeDecl.Body
-		clearPositions(body)
-		if needBindingDecl {
-			res.bindingDecl = true
-			body.List = prepend(bindingDecl.stmt, body.List...)
+	if ret, ok := parent.(*ast.ReturnStmt); ok && len(ret.Results) == 1 {
+		switch {
+		case callee.HasBareReturn:
+			st.explain(BodyShape, token.NoPos, token.NoPos, "callee has a bare return")
+		case needBindingDecl && bindingDecl == nil:
+			// createBindingDecl explained why.
+		case hasLabelConflict(caller.path, callee.Labels):
+			st.explain(Shadowing, token.NoPos, token.NoPos, "callee's labels conflict with those of the caller")
+		case !allResultsUnreferenced:
+			st.explain(BodyShape, token.NoPos, token.NoPos, "callee refers to its named results")
+		case !tailCallSafeReturn(caller, calleeSymbol, callee) && !st.convertReturns(calleeDecl):
+			// convertReturns explained why.
+		default:
+			logf("strategy: reduce tail-call")
+			body := calleeDecl.Body
+			clearPositions(body)
+			if needBindingDecl {
+				res.bindingDecl = true
+				body.List = prepend(bindingDecl.stmt, body.List...)
+			}
+			res.old = ret
+			res.new = body
+			return res, nil
 		}
-		res.old = ret
-		res.new = body
-		return res, nil
 	}
 
 	// Special case: call to void function
@@ -1222,20 +1272,58 @@ func (st *state) inlineCall() (*inlineCallResult, error) {
 	// - all parameters and result vars can be eliminated
 	//   or replaced by a binding decl,
 	// - caller ExprStmt is in unrestricted statement context.
-	if stmt := callStmt(caller.path, true); stmt != nil &&
-		(!needBindingDecl || bindingDecl != nil) &&
-		!callee.HasDefer &&
-		!hasLabelConflict(caller.path, callee.Labels) &&
-		len(callee.Returns) == 0 {
-		logf("strategy: reduce stmt-context call to { stmts }")
-		body := calleeDecl.Body
-		var repl ast.Stmt = body
-		clearPositions(repl)
-		if needBindingDecl {
-			body.List = prepend(bindingDecl.stmt, body.List...)
+	if stmt := callStmt(caller.path, true); stmt != nil && len(callee.Returns) == 0 {
+		switch {
+		case needBindingDecl && bindingDecl == nil:
+			// createBindingDecl explained why.
+		case callee.HasDefer:
+			st.explain(BodyShape, token.NoPos, token.NoPos, "callee uses defer")
+		case hasLabelConflict(caller.path, callee.Labels):
+			st.explain(Shadowing, token.NoPos, token.NoPos, "callee's labels conflict with those of the caller")
+		default:
+			logf("strategy: reduce stmt-context call to { stmts }")
+			body := calleeDecl.Body
+			var repl ast.Stmt = body
+			clearPositions(repl)
+			if needBindingDecl {
+				res.bindingDecl = true
+				body.List = prepend(bindingDecl.stmt, body.List...)
+			}
+			res.old = stmt
+			res.new = repl
+			return res, nil
 		}
-		res.old = stmt
-		res.new = repl
+	}
+
+	// Special case: call to a function with returns, in statement
+	// or assignment context.
+	//
+	// Inlining:
+	//         f(args)
+	//         x, y = f(args)
+	//         x, y := f(args)
+	// where:
+	//	   func f(params) (results) { stmts }
+	// reduces to:
+	//         { var (bindings); stmts' }
+	//         var x T1; var y T2; { var (bindings); stmts' }
+	// where stmts' are the stmts in which each return statement
+	// is replaced by an assignment of its operands to x, y (or _).
+	// Where possible, the statements that follow an early return
+	// are moved into an else block; otherwise each early return
+	// jumps to a label at the end of the block.
+	// This is possible so long as:
+	// - callee does not use defer;
+	// - callee has no bare returns;
+	// - there is no label conflict between caller and callee;
+	// - all parameters and result vars can be eliminated
+	//   or replaced by a binding decl;
+	// - the statement is in unrestricted statement context;
+	// - the assigned variables are identifiers that are not
+	//   shadowed by the callee's declarations.
+	if len(callee.Returns) > 0 &&
+		(!needBindingDecl || bindingDecl != nil) && // otherwise createBindingDecl explained why
+		st.reduceReturns(res, calleeDecl, bindingDecl, istate) {
 		return res, nil
 	}
 
@@ -1265,7 +1353,11 @@ func (st *state) inlineCall() (*inlineCallResult, error) {
 	// which is not a valid argument list because g() must appear alone.
 	// Reject this case for now.
 	if len(args) == 2 && args[0] != nil && args[1] != nil && is[*types.Tuple](args[1].typ) {
-		return nil, fmt.Errorf("can't yet inline spread call to method")
+		return nil, st.reject(Unsupported, args[1].expr.Pos(), token.NoPos, "can't yet inline spread call to method")
+	}
+
+	if len(st.reasons) == 0 {
+		st.explain(Unsupported, token.NoPos, token.NoPos, "no strategy reduces the call in this context")
 	}
 
 	// Infallible general case: literalization.
@@ -1345,7 +1437,7 @@ func (st *state) renameFreeObjs(istate *importState) ([]ast.Expr, error) {
 			// check not shadowed at caller.
 			found := caller.lookup(obj.Name) // always finds something
 			if found.Pos().IsValid() {
-				return nil, fmt.Errorf("cannot inline, because the callee refers to built-in %q, which in the caller is shadowed by a %s (declared at line %d)",
+				return nil, st.reject(Shadowing, token.NoPos, found.Pos(), "cannot inline, because the callee refers to built-in %q, which in the caller is shadowed by a %s (declared at line %d)",
 					obj.Name, objectKind(found),
 					caller.Fset.PositionFor(found.Pos(), false).Line)
 			}
@@ -1365,7 +1457,7 @@ func (st *state) renameFreeObjs(istate *importState) ([]ast.Expr, error) {
 					// around the refactored signature.
 					found := caller.lookup(obj.Name)
 					if found != nil && !isPkgLevel(found) {
-						return nil, fmt.Errorf("cannot inline, because the callee refers to %s %q, which in the caller is shadowed by a %s (declared at line %d)",
+						return nil, st.reject(Shadowing, token.NoPos, found.Pos(), "cannot inline, because the callee refers to %s %q, which in the caller is shadowed by a %s (declared at line %d)",
 							obj.Kind, obj.Name,
 							objectKind(found),
 							caller.Fset.PositionFor(found.Pos(), false).Line)
@@ -1408,33 +1500,184 @@ type argument struct {
 	desugaredRecv bool            // is *recv or &recv, where operator was elided
 }
 
-// typeArguments returns the type arguments of the call.
-// It only collects the arguments that are explicitly provided; it does
-// not attempt type inference.
-func (st *state) typeArguments(call *ast.CallExpr) []*argument {
+// typeArguments returns the type arguments of the call, one for each
+// type parameter of the callee: the explicit type arguments of the
+// call, followed by syntax for those that were inferred or, for a
+// method of a generic type, those of the receiver type. References in
+// the synthesized syntax to other packages use istate to find or add
+// imports.
+func (st *state) typeArguments(call *ast.CallExpr, istate *importState) ([]*argument, error) {
+	caller, tparams := st.caller, st.callee.impl.TypeParams
+	if len(tparams) == 0 {
+		return nil, nil
+	}
+
+	fun := ast.Unparen(call.Fun)
 	var exprs []ast.Expr
-	switch d := ast.Unparen(call.Fun).(type) {
+	switch d := fun.(type) {
 	case *ast.IndexExpr:
 		exprs = []ast.Expr{d.Index}
+		fun = ast.Unparen(d.X)
 	case *ast.IndexListExpr:
 		exprs = d.Indices
-	default:
-		// No type  arguments
-		return nil
+		fun = ast.Unparen(d.X)
 	}
 	var args []*argument
 	for _, e := range exprs {
-		arg := &argument{expr: e, freevars: freeVars(st.caller.Info, e)}
+		args = append(args, &argument{expr: e, freevars: freeVars(caller.Info, e)})
+	}
+
+	if len(args) < len(tparams) {
+		// Find the complete list of type arguments.
+		var targs *types.TypeList
+		if sel, ok := fun.(*ast.SelectorExpr); ok && caller.Info.Selections[sel] != nil {
+			// Method of a generic type: use the type arguments of
+			// the receiver type of the selected (instantiated) method.
+			recv := caller.Info.Selections[sel].Obj().Type().(*types.Signature).Recv().Type()
+			if ptr, ok := types.Unalias(recv).(*types.Pointer); ok {
+				recv = ptr.Elem()
+			}
+			if named, ok := types.Unalias(recv).(*types.Named); ok {
+				targs = named.TypeArgs()
+			}
+		} else {
+			var id *ast.Ident
+			switch fun := fun.(type) {
+			case *ast.Ident:
+				id = fun
+			case *ast.SelectorExpr:
+				id = fun.Sel // qualified identifier
+			}
+			if id != nil {
+				targs = caller.Info.Instances[id].TypeArgs
+			}
+		}
+		if targs.Len() != len(tparams) {
+			return nil, fmt.Errorf("cannot inline: can't determine type arguments of call")
+		}
+		for i := len(args); i < targs.Len(); i++ {
+			expr, kind, err := st.typeArgExpr(targs.At(i), istate, tparams[i].Shadow)
+			if err != nil {
+				return nil, st.reject(kind, token.NoPos, token.NoPos, "cannot inline: inferred type argument #%d (type parameter %s): %v", i, tparams[i].Name, err)
+			}
+			logf := st.opts.Logf
+			logf("inferred type argument %s = %s", tparams[i].Name, debugFormatNode(token.NewFileSet(), expr))
+			free := make(map[string]bool)
+			freeishNames(free, expr, false)
+			args = append(args, &argument{expr: expr, freevars: free})
+		}
+	}
+
+	for _, arg := range args {
 		// Wrap the instantiating type in parens when it's not an
 		// ident or qualified ident to prevent "if x == struct{}"
 		// parsing ambiguity, or "T(x)" where T = "*int" or "func()"
 		// from misparsing.
-		if _, ok := arg.expr.(*ast.Ident); !ok {
+		switch arg.expr.(type) {
+		case *ast.Ident, *ast.SelectorExpr:
+		default:
 			arg.expr = &ast.ParenExpr{X: arg.expr}
 		}
-		args = append(args, arg)
 	}
-	return args
+	return args, nil
+}
+
+// typeArgExpr returns syntax that denotes the type argument t at the
+// call site, and within the callee at references where the names in
+// shadow are shadowed. It returns an error, and its kind, if t cannot
+// be referred to from the caller, for example because it involves an
+// unexported type of another package or a type whose name is shadowed
+// at the call.
+func (st *state) typeArgExpr(t types.Type, istate *importState, shadow shadowMap) (ast.Expr, ReasonKind, error) {
+	caller := st.caller
+	var (
+		kind ReasonKind
+		err  error
+	)
+	forEachTypeName(t, func(tn *types.TypeName) {
+		if err != nil {
+			return
+		}
+		switch {
+		case tn.Pkg() == nil || tn.Pkg() == caller.Types:
+			// Universe, or caller package: the name must refer to tn
+			// at the call.
+			if caller.lookup(tn.Name()) != tn {
+				kind, err = Shadowing, fmt.Errorf("%s is shadowed at the call", tn.Name())
+			}
+		case !tn.Exported() || tn.Parent() != tn.Pkg().Scope():
+			kind, err = Unexported, fmt.Errorf("%s.%s is not accessible from the caller", tn.Pkg().Name(), tn.Name())
+		case !analysisinternal.CanImport(caller.Types.Path(), tn.Pkg().Path()):
+			kind, err = Unexported, fmt.Errorf("package %q is not accessible from the caller", tn.Pkg().Path())
+		}
+	})
+	if err != nil {
+		return nil, kind, err
+	}
+	qual := func(pkg *types.Package) string {
+		if pkg == nil || pkg == caller.Types { // universe, or caller package
+			return ""
+		}
+		return istate.localName(pkg.Path(), pkg.Name(), shadow)
+	}
+	return typesinternal.TypeExpr(t, qual), 0, nil
+}
+
+// forEachTypeName calls f for the name of each named, alias, basic, or
+// type parameter type within t.
+func forEachTypeName(t types.Type, f func(*types.TypeName)) {
+	var visit func(t types.Type)
+	visitTuple := func(tuple *types.Tuple) {
+		for v := range tuple.Variables() {
+			visit(v.Type())
+		}
+	}
+	visit = func(t types.Type) {
+		switch t := t.(type) {
+		case *types.Basic:
+			if obj, ok := types.Universe.Lookup(t.Name()).(*types.TypeName); ok {
+				f(obj)
+			}
+		case *types.Alias:
+			f(t.Obj())
+			for targ := range t.TypeArgs().Types() {
+				visit(targ)
+			}
+		case *types.Named:
+			f(t.Obj())
+			for targ := range t.TypeArgs().Types() {
+				visit(targ)
+			}
+		case *types.TypeParam:
+			f(t.Obj())
+		case *types.Pointer:
+			visit(t.Elem())
+		case *types.Slice:
+			visit(t.Elem())
+		case *types.Array:
+			visit(t.Elem())
+		case *types.Chan:
+			visit(t.Elem())
+		case *types.Map:
+			visit(t.Key())
+			visit(t.Elem())
+		case *types.Signature:
+			visitTuple(t.Params())
+			visitTuple(t.Results())
+		case *types.Struct:
+			for field := range t.Fields() {
+				visit(field.Type())
+			}
+		case *types.Interface:
+			for m := range t.ExplicitMethods() {
+				visit(m.Type())
+			}
+			for embed := range t.EmbeddedTypes() {
+				visit(embed)
+			}
+		}
+	}
+	visit(t)
 }
 
 // arguments returns the effective arguments of the call.
@@ -1472,10 +1715,12 @@ func (st *state) arguments(caller *Caller, calleeDecl *ast.FuncDecl, assign1 fun
 
 	callArgs := caller.Call.Args
 	if calleeDecl.Recv != nil {
-		if len(st.callee.impl.TypeParams) > 0 {
-			return nil, fmt.Errorf("cannot inline: generic methods not yet supported")
+		sel, ok := ast.Unparen(caller.Call.Fun).(*ast.SelectorExpr)
+		if !ok {
+			// e.g. x.f[T](), which is ill-typed: methods
+			// cannot have type parameters of their own.
+			return nil, fmt.Errorf("cannot inline: method call has explicit type arguments")
 		}
-		sel := ast.Unparen(caller.Call.Fun).(*ast.SelectorExpr)
 		seln := caller.Info.Selections[sel]
 		var recvArg ast.Expr
 		switch seln.Kind() {
@@ -1509,7 +1754,7 @@ func (st *state) arguments(caller *Caller, calleeDecl *ast.FuncDecl, assign1 fun
 			for _, index := range indices[:len(indices)-1] {
 				fld := typeparams.CoreType(typeparams.Deref(arg.typ)).(*types.Struct).Field(index)
 				if fld.Pkg() != caller.Types && !fld.Exported() {
-					return nil, fmt.Errorf("in %s, implicit reference to unexported field .%s cannot be made explicit",
+					return nil, st.reject(Unexported, caller.Call.Fun.Pos(), fld.Pos(), "in %s, implicit reference to unexported field .%s cannot be made explicit",
 						debugFormatNode(caller.Fset, caller.Call.Fun),
 						fld.Name())
 				}
@@ -1663,7 +1908,7 @@ func identsNamed(n ast.Node, name string) []*ast.Ident {
 // parameter, and is provided with its relative offset and replacement
 // expression (argument), and the corresponding elements of params and
 // args are replaced by nil.
-func substitute(logf logger, caller *Caller, params []*parameter, args []*argument, effects []int, falcon falconResult, replace replacer) {
+func substitute(logf logger, explain explainer, caller *Caller, params []*parameter, args []*argument, effects []int, falcon falconResult, replace replacer) {
 	// Inv:
 	//  in        calls to     variadic, len(args) >= len(params)-1
 	//  in spread calls to non-variadic, len(args) <  len(params)
@@ -1700,24 +1945,31 @@ next:
 			// spread => last argument, but not always last parameter
 			logf("keeping param %q and following ones: argument %s is spread",
 				param.info.Name, debugFormatNode(caller.Fset, arg.expr))
+			explain(BindingDecl, arg.expr.Pos(), token.NoPos, "parameter %s and the following ones must be bound to the results of %v",
+				param.info.Name, arg.expr)
 			return // give up
 		}
 		assert(!param.variadic, "unsimplified variadic parameter")
 		if param.info.Escapes {
 			logf("keeping param %q: escapes from callee", param.info.Name)
+			explain(BindingDecl, arg.expr.Pos(), token.NoPos, "the address of parameter %s is taken by the callee", param.info.Name)
 			continue
 		}
 		if param.info.Assigned {
 			logf("keeping param %q: assigned by callee", param.info.Name)
+			explain(BindingDecl, arg.expr.Pos(), token.NoPos, "parameter %s is assigned by the callee", param.info.Name)
 			continue // callee needs the parameter variable
 		}
 		if len(param.info.Refs) > 1 && !arg.duplicable {
 			logf("keeping param %q: argument is not duplicable", param.info.Name)
+			explain(BindingDecl, arg.expr.Pos(), token.NoPos, "parameter %s is referenced %d times, but its argument cannot be duplicated",
+				param.info.Name, len(param.info.Refs))
 			continue // incorrect or poor style to duplicate an expression
 		}
 		if len(param.info.Refs) == 0 {
 			if arg.effects {
 				logf("keeping param %q: though unreferenced, it has effects", param.info.Name)
+				explain(EffectsOrder, arg.expr.Pos(), token.NoPos, "argument of unreferenced parameter %s has effects", param.info.Name)
 				continue
 			}
 
@@ -1748,6 +2000,8 @@ next:
 						if !usedElsewhere() {
 							logf("keeping param %q: arg contains perhaps the last reference to caller local %v @ %v",
 								param.info.Name, v, caller.Fset.PositionFor(v.Pos(), false))
+							explain(BindingDecl, arg.expr.Pos(), v.Pos(), "argument of unreferenced parameter %s contains perhaps the last reference to %s",
+								param.info.Name, v.Name())
 							continue next
 						}
 					}
@@ -1773,6 +2027,9 @@ next:
 			switch s := param.info.Shadow[free]; {
 			case s < 0:
 				// Shadowed by a non-parameter symbol, so arg is not substitutable.
+				logf("keeping param %q: %s is shadowed in the callee", param.info.Name, free)
+				explain(Shadowing, arg.expr.Pos(), token.NoPos, "argument of parameter %s refers to %s, which is shadowed in the callee",
+					param.info.Name, free)
 				delete(sg, arg)
 			case s > 0:
 				// Shadowed by a parameter; arg may be substitutable, if only shadowed
@@ -1849,7 +2106,7 @@ next:
 	//
 	// Keep redoing the analysis until we no longer reject additional arguments,
 	// as the set of substituted parameters affects the falcon package.
-	for checkFalconConstraints(logf, params, args, falcon, sg) {
+	for checkFalconConstraints(logf, explain, params, args, falcon, sg) {
 		sg.prune()
 	}
 
@@ -1859,7 +2116,7 @@ next:
 	//
 	// As with the falcon analysis, keep redoing the analysis until the no more
 	// arguments are rejected.
-	for resolveEffects(logf, args, effects, sg) {
+	for resolveEffects(logf, explain, args, effects, sg) {
 		sg.prune()
 	}
 
@@ -2006,7 +2263,7 @@ func isUsedOutsideCall(caller *Caller, v *types.Var) bool {
 // TODO(adonovan): we could obtain a finer result rejecting only the
 // freevars of each failed constraint, and processing constraints in
 // order of increasing arity, but failures are quite rare.
-func checkFalconConstraints(logf logger, params []*parameter, args []*argument, falcon falconResult, sg substGraph) bool {
+func checkFalconConstraints(logf logger, explain explainer, params []*parameter, args []*argument, falcon falconResult, sg substGraph) bool {
 	// Create a dummy package, as this is the only
 	// way to create an environment for CheckExpr.
 	pkg := types.NewPackage("falcon", "falcon")
@@ -2054,6 +2311,8 @@ func checkFalconConstraints(logf logger, params []*parameter, args []*argument,
 			for j, arg := range args {
 				if arg.constant != nil && sg.has(arg) {
 					logf("keeping param %q due falcon violation", params[j].info.Name)
+					explain(BindingDecl, arg.expr.Pos(), token.NoPos, "substituting constant argument of parameter %s would violate constraint %s",
+						params[j].info.Name, falcon)
 					removed = sg.remove(arg) || removed
 				}
 			}
@@ -2108,7 +2367,7 @@ func checkFalconConstraints(logf logger, params []*parameter, args []*argument,
 // current argument. Subsequent iterations cannot introduce hazards
 // with that argument because they can result only in additional
 // binding of lower-ordered arguments.
-func resolveEffects(logf logger, args []*argument, effects []int, sg substGraph) bool {
+func resolveEffects(logf logger, explain explainer, args []*argument, effects []int, sg substGraph) bool {
 	effectStr := func(effects bool, idx int) string {
 		i := fmt.Sprint(idx)
 		if idx == len(args) {
@@ -2138,6 +2397,11 @@ func resolveEffects(logf logger, args []*argument, effects []int, sg substGraph)
 					if ji > i && (jw || argi.effects) { // out of order evaluation
 						logf("binding argument %s: preceded by %s",
 							effectStr(argi.effects, i), effectStr(jw, ji))
+						if ji == len(args) {
+							explain(EffectsOrder, argi.expr.Pos(), token.NoPos, "substituting argument #%d would evaluate it after the callee's other operations", i)
+						} else {
+							explain(EffectsOrder, argi.expr.Pos(), token.NoPos, "substituting argument #%d would evaluate it after argument #%d", i, ji)
+						}
 
 						removed = sg.remove(argi) || removed
 						break
@@ -2154,6 +2418,7 @@ func resolveEffects(logf logger, args []*argument, effects []int, sg substGraph)
 				if (argi.effects || argj.effects) && sg.has(argj) {
 					logf("binding argument %s: %s is bound",
 						effectStr(argj.effects, j), effectStr(argi.effects, i))
+					explain(EffectsOrder, argj.expr.Pos(), token.NoPos, "argument #%d must be evaluated before argument #%d, which is bound", j, i)
 
 					removed = sg.remove(argj) || removed
 				}
@@ -2349,29 +2614,18 @@ type bindingDeclInfo struct {
 // parameters are resolved simultaneously and assigned
 // simultaneously.
 //
+// A spread call f(g()), where g returns a tuple, binds the parameters
+// to the results of g in a single spec, var p0, p1, p2 = g(), after
+// any spec for the receiver. The spec has the type of the parameters
+// if they form a single group; otherwise each parameter must have the
+// type of the corresponding result.
+//
 // The pX names should already be blank ("_") if the parameter
 // is unreferenced; this avoids "unreferenced local var" checks.
 //
 // Strategies may impose additional checks on return
 // conversions, labels, defer, etc.
-func createBindingDecl(logf logger, caller *Caller, args []*argument, calleeDecl *ast.FuncDecl, results []*paramInfo) *bindingDeclInfo {
-	// Spread calls are tricky as they may not align with the
-	// parameters' field groupings nor types.
-	// For example, given
-	//   func g() (int, string)
-	// the call
-	//   f(g())
-	// is legal with these decls of f:
-	//   func f(int, string)
-	//   func f(x, y any)
-	//   func f(x, y ...any)
-	// TODO(adonovan): support binding decls for spread calls by
-	// splitting parameter groupings as needed.
-	if lastArg := last(args); lastArg != nil && lastArg.spread {
-		logf("binding decls not yet supported for spread calls")
-		return nil
-	}
-
+func createBindingDecl(logf logger, explain explainer, caller *Caller, params []*parameter, args []*argument, calleeDecl *ast.FuncDecl, results []*paramInfo) *bindingDeclInfo {
 	var (
 		specs []ast.Spec
 		names = make(map[string]bool) // names defined by previous specs
@@ -2392,11 +2646,14 @@ func createBindingDecl(logf logger, caller *Caller, args []*argument, calleeDecl
 				free[name] = true
 			}
 		}
-		const includeComplitIdents = true
-		freeishNames(free, spec.Type, includeComplitIdents)
+		if spec.Type != nil {
+			const includeComplitIdents = true
+			freeishNames(free, spec.Type, includeComplitIdents)
+		}
 		for name := range free {
 			if names[name] {
 				logf("binding decl would shadow free name %q", name)
+				explain(Shadowing, token.NoPos, token.NoPos, "a binding declaration would shadow %s", name)
 				return true
 			}
 		}
@@ -2419,7 +2676,73 @@ func createBindingDecl(logf logger, caller *Caller, args []*argument, calleeDecl
 			values = append(values, arg.expr)
 		}
 	}
-	for _, field := range calleeDecl.Type.Params.List {
+	fields := calleeDecl.Type.Params.List
+
+	// A spread call f(recv?, g()) binds all the parameters that
+	// follow the receiver to the tuple g(), regardless of their
+	// field groupings. (Spread parameters are never substituted.)
+	// The ordinary specs are followed by a single spec
+	//
+	//	var p1, ..., pN T = g()
+	//
+	// which preserves the order of evaluation of the call.
+	var spread *ast.ValueSpec
+	if lastArg := last(args); lastArg != nil && lastArg.spread {
+		tuple := lastArg.typ.(*types.Tuple)
+		values = values[:len(values)-1]
+
+		// Find the fields of the spread parameters.
+		i, n := len(fields), 0
+		for i > 0 && n < tuple.Len() {
+			i--
+			n += len(fields[i].Names)
+		}
+		if n != tuple.Len() {
+			logf("binding decl: spread parameters do not align with fields")
+			explain(Unsupported, lastArg.expr.Pos(), token.NoPos, "spread parameters do not align with parameter groups")
+			return nil
+		}
+		var spreadNames []*ast.Ident
+		for _, field := range fields[i:] {
+			if is[*ast.Ellipsis](field.Type) {
+				// A variadic parameter receives only part of the tuple.
+				logf("binding decls not yet supported for spread calls to variadic functions")
+				explain(Unsupported, lastArg.expr.Pos(), token.NoPos, "spread calls to variadic functions cannot use a binding declaration")
+				return nil
+			}
+			spreadNames = append(spreadNames, cleanNodes(field.Names)...)
+		}
+		spread = &ast.ValueSpec{
+			Names:  spreadNames,
+			Values: []ast.Expr{lastArg.expr},
+		}
+		if len(fields[i:]) == 1 {
+			// A single group: each element is assignable to its type.
+			spread.Type = cleanNode(fields[i].Type)
+		} else {
+			// Several groups: the variables must have the types of
+			// the tuple elements.
+			var spreadParams []*parameter
+			for _, param := range params {
+				if param != nil {
+					spreadParams = append(spreadParams, param)
+				}
+			}
+			spreadParams = spreadParams[len(spreadParams)-tuple.Len():]
+			for j, param := range spreadParams {
+				if !types.Identical(param.obj.Type(), tuple.At(j).Type()) {
+					logf("binding decl: type of spread parameter %s differs from result type %s",
+						param.obj.Name(), tuple.At(j).Type())
+					explain(Unsupported, lastArg.expr.Pos(), token.NoPos, "type of spread parameter %s differs from result type %s",
+						param.obj.Name(), tuple.At(j).Type())
+					return nil
+				}
+			}
+		}
+		fields = fields[:i]
+	}
+
+	for _, field := range fields {
 		// Each field (param group) becomes a ValueSpec.
 		spec := &ast.ValueSpec{
 			Names:  cleanNodes(field.Names),
@@ -2433,6 +2756,12 @@ func createBindingDecl(logf logger, caller *Caller, args []*argument, calleeDecl
 		specs = append(specs, spec)
 	}
 	assert(len(values) == 0, "args/params mismatch")
+	if spread != nil {
+		if shadow(spread) {
+			return nil
+		}
+		specs = append(specs, spread)
+	}
 
 	// results
 	//
@@ -3743,6 +4072,495 @@ func hasNonTrivialReturn(returnInfo [][]returnOperandFlags) bool {
 	return false
 }
 
+// convertReturns makes explicit the non-trivial implicit conversions
+// of the operands of the callee's return statements to the result
+// types, so that the statements may be moved into the caller. It
+// reports whether it succeeded, and explains why if not: the
+// conversions of spread returns cannot be made explicit.
+func (st *state) convertReturns(calleeDecl *ast.FuncDecl) bool {
+	logf, callee := st.opts.Logf, &st.callee.impl
+
+	flags := returnFlags(calleeDecl.Body, callee.Returns)
+	for ret, retFlags := range flags {
+		if len(ret.Results) < len(retFlags) && slices.ContainsFunc(retFlags, func(f returnOperandFlags) bool { return f&nonTrivialResult != 0 }) {
+			st.explain(BodyShape, token.NoPos, token.NoPos, "the conversion of spread return %v cannot be made explicit", ret)
+			return false
+		}
+	}
+	resTypes := resultTypes(calleeDecl)
+	for ret, retFlags := range flags {
+		if len(ret.Results) == len(retFlags) {
+			for i, f := range retFlags {
+				if f&nonTrivialResult != 0 {
+					ret.Results[i] = convert(cleanNode(resTypes[i]), ret.Results[i])
+				}
+			}
+		}
+	}
+	logf("made return conversions explicit")
+	return true
+}
+
+// reduceReturns implements the strategy that reduces a call statement
+// or an assignment of the results of a call, f(args) or x, y = f(args),
+// to the statements of the callee, in which each return statement
+// assigns its operands to x, y. It reports whether it succeeded, and
+// if so sets res; if not, it explains why, unless another strategy
+// is responsible for the context of the call.
+func (st *state) reduceReturns(res *inlineCallResult, calleeDecl *ast.FuncDecl, bindingDecl *bindingDeclInfo, istate *importState) bool {
+	logf, caller, callee := st.opts.Logf, st.caller, &st.callee.impl
+
+	// Find the statement and the variables it assigns, if any.
+	var (
+		stmt   ast.Stmt
+		lhs    []*ast.Ident // assigned variables, or nil for a call statement
+		define bool         // whether lhs are declared by :=
+	)
+	parent, grandparent := callContext(caller.path)
+	if s := callStmt(caller.path, true); s != nil {
+		stmt = s
+	} else if assign, ok := parent.(*ast.AssignStmt); ok &&
+		(assign.Tok == token.ASSIGN || assign.Tok == token.DEFINE) &&
+		len(assign.Rhs) == 1 && ast.Unparen(assign.Rhs[0]) == caller.Call &&
+		(is[*ast.BlockStmt](grandparent) || is[*ast.CaseClause](grandparent) || is[*ast.CommClause](grandparent)) {
+		for _, expr := range assign.Lhs {
+			id, ok := expr.(*ast.Ident)
+			if !ok {
+				st.explain(Unsupported, expr.Pos(), token.NoPos, "the results of the call are assigned to %v, which is not an identifier", expr)
+				return false
+			}
+			lhs = append(lhs, id)
+		}
+		stmt, define = assign, assign.Tok == token.DEFINE
+	} else {
+		// A tail call is left to the tail-call strategy, and a call
+		// to { return exprs } in expression context to its own.
+		body := calleeDecl.Body.List
+		if ret, ok := parent.(*ast.ReturnStmt); (!ok || len(ret.Results) != 1) &&
+			!(len(body) == 1 && is[*ast.ReturnStmt](body[0]) && len(body[0].(*ast.ReturnStmt).Results) > 0) {
+			st.explain(BodyShape, token.NoPos, token.NoPos, "callee has return statements, but the call is not a tail call, or a call statement or assignment in a block")
+		}
+		return false
+	}
+
+	switch {
+	case callee.HasDefer:
+		st.explain(BodyShape, token.NoPos, token.NoPos, "callee uses defer")
+		return false
+	case callee.HasBareReturn:
+		st.explain(BodyShape, token.NoPos, token.NoPos, "callee has a bare return")
+		return false
+	case hasLabelConflict(caller.path, callee.Labels):
+		st.explain(Shadowing, token.NoPos, token.NoPos, "callee's labels conflict with those of the caller")
+		return false
+	}
+
+	// The assigned variables must not be shadowed
+	// by the parameters or locals of the callee.
+	declared := declaredNames(calleeDecl)
+	if bindingDecl != nil {
+		for name := range bindingDecl.names {
+			declared[name] = true
+		}
+	}
+	for _, id := range lhs {
+		if id.Name != "_" && declared[id.Name] {
+			st.explain(Shadowing, id.Pos(), token.NoPos, "assigned variable %s would be shadowed by a declaration in the callee", id.Name)
+			return false
+		}
+	}
+
+	// Variables declared by := are declared before the statements,
+	// so they must not shadow names referenced by the statements.
+	var specs []ast.Spec
+	if define {
+		free := make(map[string]bool)
+		freeishNames(free, calleeDecl.Body, false)
+		if bindingDecl != nil {
+			freeishNames(free, bindingDecl.stmt, false)
+		}
+		for _, id := range lhs {
+			v, ok := caller.Info.Defs[id].(*types.Var)
+			if !ok {
+				continue // blank, or not new
+			}
+			if free[id.Name] {
+				st.explain(Shadowing, id.Pos(), token.NoPos, "declaration of %s would shadow a reference to %s in the inlined statements", id.Name, id.Name)
+				return false
+			}
+			typ, _, err := st.typeArgExpr(v.Type(), istate, nil)
+			if err != nil {
+				st.explain(Unsupported, id.Pos(), token.NoPos, "type of %s: %v", id.Name, err)
+				return false
+			}
+			specs = append(specs, &ast.ValueSpec{
+				Names: []*ast.Ident{makeIdent(id.Name)},
+				Type:  typ,
+			})
+		}
+	}
+
+	// Compute the replacement of each return statement.
+	var (
+		results  = caller.Info.TypeOf(caller.Call.Fun).Underlying().(*types.Signature).Results()
+		resTypes = resultTypes(calleeDecl)
+		repl     = make(map[*ast.ReturnStmt][]ast.Stmt)
+	)
+	for ret, retFlags := range returnFlags(calleeDecl.Body, callee.Returns) {
+		if len(ret.Results) == 0 {
+			repl[ret] = nil // void function
+			continue
+		}
+		spread := len(ret.Results) < len(retFlags)
+		var rhs []ast.Expr
+		if lhs == nil {
+			// Evaluate the operands, except untyped nils,
+			// which cannot be assigned to _.
+			for i, expr := range ret.Results {
+				if spread || retFlags[i]&untypedNilResult == 0 {
+					rhs = append(rhs, expr)
+				}
+			}
+		} else {
+			// Make a non-trivial conversion explicit unless
+			// the assignment performs the same conversion.
+			rhs = slices.Clone(ret.Results)
+			for i, f := range retFlags {
+				if f&nonTrivialResult == 0 {
+					continue
+				}
+				var needed bool
+				if lhs[i].Name == "_" {
+					needed = f&untypedNilResult != 0
+				} else {
+					needed = !types.Identical(caller.Info.TypeOf(lhs[i]), results.At(i).Type())
+				}
+				if needed {
+					if spread {
+						st.explain(BodyShape, lhs[i].Pos(), token.NoPos, "the conversion of spread return %v cannot be made explicit", ret)
+						return false
+					}
+					rhs[i] = convert(cleanNode(resTypes[i]), rhs[i])
+				}
+			}
+		}
+		if len(rhs) == 0 {
+			repl[ret] = nil
+			continue
+		}
+		var vars []ast.Expr
+		if lhs == nil {
+			n := len(rhs)
+			if spread {
+				n = callee.NumResults
+			}
+			vars = blanks[ast.Expr](n)
+		} else {
+			for _, id := range lhs {
+				vars = append(vars, makeIdent(id.Name))
+			}
+		}
+		repl[ret] = []ast.Stmt{&ast.AssignStmt{
+			Lhs: vars,
+			Tok: token.ASSIGN,
+			Rhs: rhs,
+		}}
+	}
+
+	// Replace the returns, preferably by restructuring the
+	// statements that follow an early return into an else block
+	// (which would break jumps to labels into the block), or
+	// otherwise by a jump to a label at the end of the statements.
+	var (
+		stmts []ast.Stmt
+		ok    bool
+	)
+	if len(callee.Labels) == 0 {
+		stmts, ok = tailReturns(calleeDecl.Body.List, repl)
+	}
+	if ok {
+		logf("strategy: reduce stmt-context call to { stmts } with returns")
+	} else {
+		logf("strategy: reduce stmt-context call to { stmts } with returns and jumps")
+		labels := make(map[string]bool)
+		maps.Copy(labels, callerLabels(caller.path))
+		for _, label := range callee.Labels {
+			labels[label] = true
+		}
+		// Labels are scoped to the whole function, so the label must
+		// be distinct from those of the caller's function. (Labels
+		// added by other inlinings not yet applied to it are not
+		// visible here; see Result.Label.)
+		label := "done"
+		for i := 1; labels[label]; i++ {
+			label = fmt.Sprintf("done%d", i)
+		}
+
+		body := calleeDecl.Body
+		if ret, ok := last(body.List).(*ast.ReturnStmt); ok {
+			// The final return needs no jump.
+			body.List = append(body.List[:len(body.List)-1], repl[ret]...)
+		}
+		jumps := false
+		astutil.Apply(body, func(c *astutil.Cursor) bool {
+			switch n := c.Node().(type) {
+			case *ast.FuncLit:
+				return false // prune traversal
+			case *ast.ReturnStmt:
+				jump := &ast.BranchStmt{Tok: token.GOTO, Label: makeIdent(label)}
+				if c.Index() >= 0 {
+					for _, s := range repl[n] {
+						c.InsertBefore(s)
+					}
+					c.Replace(jump)
+				} else {
+					c.Replace(&ast.BlockStmt{List: append(repl[n], jump)})
+				}
+				jumps = true
+			}
+			return true
+		}, nil)
+		stmts = body.List
+		if jumps {
+			// Unless the statements declare no variables, they
+			// are enclosed in a block so that the jumps do not
+			// skip the declarations.
+			if slices.ContainsFunc(stmts, declaresVars) {
+				stmts = []ast.Stmt{body}
+			}
+			stmts = append(stmts, &ast.LabeledStmt{
+				Label: makeIdent(label),
+				Stmt:  &ast.EmptyStmt{Implicit: true},
+			})
+			res.label = true
+		}
+	}
+
+	block := &ast.BlockStmt{List: stmts}
+	clearPositions(block)
+	if bindingDecl != nil {
+		res.bindingDecl = true
+		block.List = prepend(bindingDecl.stmt, block.List...)
+	}
+	res.old = stmt
+	res.new = block
+	if len(specs) > 0 {
+		// Declare the new variables before the block, and splice both
+		// into the caller's block, along with the statements of the
+		// block if it declares nothing.
+		decl := &ast.DeclStmt{
+			Decl: &ast.GenDecl{
+				Tok:   token.VAR,
+				Specs: specs,
+			},
+		}
+		list := []ast.Stmt{decl, block}
+		if len(declares(block.List)) == 0 && !is[*ast.LabeledStmt](last(block.List)) {
+			list = prepend[ast.Stmt](decl, block.List...)
+		}
+		res.new = &ast.BlockStmt{List: list}
+		res.elideBraces = true
+	}
+	return true
+}
+
+// tailReturns returns stmts, a list of statements at the end of the
+// callee, with each return statement replaced by its replacement in
+// repl. It moves the statements that follow an 'if cond { ...; return }'
+// statement into an else block. It reports false if the returns
+// cannot be eliminated in this way.
+func tailReturns(stmts []ast.Stmt, repl map[*ast.ReturnStmt][]ast.Stmt) ([]ast.Stmt, bool) {
+	for i, stmt := range stmts {
+		if !hasReturn(stmt) {
+			continue
+		}
+		rest := stmts[i+1:]
+		switch stmt := stmt.(type) {
+		case *ast.ReturnStmt:
+			if len(rest) > 0 {
+				return nil, false // unreachable statements
+			}
+			return append(stmts[:i:i], repl[stmt]...), true
+
+		case *ast.BlockStmt:
+			if len(rest) > 0 {
+				return nil, false
+			}
+			list, ok := tailReturns(stmt.List, repl)
+			if !ok {
+				return nil, false
+			}
+			return append(stmts[:i:i], &ast.BlockStmt{List: list}), true
+
+		case *ast.IfStmt:
+			then, ok := tailReturns(stmt.Body.List, repl)
+			if !ok {
+				return nil, false
+			}
+			var els []ast.Stmt // statements of the else block, if any
+			switch {
+			case stmt.Else != nil:
+				if len(rest) > 0 {
+					return nil, false
+				}
+				if block, ok := stmt.Else.(*ast.BlockStmt); ok {
+					els, ok = tailReturns(block.List, repl)
+					if !ok {
+						return nil, false
+					}
+				} else {
+					els, ok = tailReturns([]ast.Stmt{stmt.Else}, repl)
+					if !ok {
+						return nil, false
+					}
+				}
+			case len(rest) > 0:
+				// The then block must not fall through to the
+				// rest, nor may the rest be moved into the
+				// scope of the variables declared by Init.
+				if !is[*ast.ReturnStmt](last(stmt.Body.List)) || stmt.Init != nil {
+					return nil, false
+				}
+				els, ok = tailReturns(rest, repl)
+				if !ok {
+					return nil, false
+				}
+			}
+			newIf := &ast.IfStmt{
+				Init: stmt.Init,
+				Cond: stmt.Cond,
+				Body: &ast.BlockStmt{List: then},
+			}
+			switch {
+			case len(els) == 0:
+			case len(then) == 0 && stmt.Init == nil:
+				// if cond {} else { els } => if !cond { els }
+				newIf.Cond = negate(stmt.Cond)
+				newIf.Body.List = els
+			case len(els) == 1 && is[*ast.IfStmt](els[0]):
+				newIf.Else = els[0] // else if
+			default:
+				newIf.Else = &ast.BlockStmt{List: els}
+			}
+			return append(stmts[:i:i], newIf), true
+
+		default:
+			return nil, false
+		}
+	}
+	return stmts, true // falls off the end
+}
+
+// returnFlags returns the metadata about the operands of each return
+// statement of the callee body, which are recorded in flags in
+// traversal order.
+func returnFlags(body *ast.BlockStmt, flags [][]returnOperandFlags) map[*ast.ReturnStmt][]returnOperandFlags {
+	res := make(map[*ast.ReturnStmt][]returnOperandFlags)
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false // prune traversal
+		case *ast.ReturnStmt:
+			res[n] = flags[len(res)]
+		}
+		return true
+	})
+	assert(len(res) == len(flags), "inconsistent returns")
+	return res
+}
+
+// resultTypes returns the syntax of the type of each result of the
+// callee, in order.
+func resultTypes(calleeDecl *ast.FuncDecl) []ast.Expr {
+	var types []ast.Expr
+	if results := calleeDecl.Type.Results; results != nil {
+		for _, field := range results.List {
+			for range max(1, len(field.Names)) {
+				types = append(types, field.Type)
+			}
+		}
+	}
+	return types
+}
+
+// hasReturn reports whether n contains a return statement, other than
+// within a function literal.
+func hasReturn(n ast.Node) bool {
+	found := false
+	ast.Inspect(n, func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.FuncLit:
+			return false // prune traversal
+		case *ast.ReturnStmt:
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// declaredNames returns the set of names declared anywhere within n,
+// including function literals.
+func declaredNames(n ast.Node) map[string]bool {
+	names := make(map[string]bool)
+	declare := func(exprs ...ast.Expr) {
+		for _, expr := range exprs {
+			if id, ok := expr.(*ast.Ident); ok {
+				names[id.Name] = true
+			}
+		}
+	}
+	ast.Inspect(n, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Field:
+			for _, id := range n.Names {
+				declare(id)
+			}
+		case *ast.ValueSpec:
+			for _, id := range n.Names {
+				declare(id)
+			}
+		case *ast.TypeSpec:
+			declare(n.Name)
+		case *ast.AssignStmt:
+			if n.Tok == token.DEFINE {
+				declare(n.Lhs...)
+			}
+		case *ast.RangeStmt:
+			if n.Tok == token.DEFINE {
+				declare(n.Key, n.Value)
+			}
+		}
+		return true
+	})
+	return names
+}
+
+// declaresVars reports whether the statement declares variables in
+// the enclosing block.
+func declaresVars(stmt ast.Stmt) bool {
+	switch stmt := stmt.(type) {
+	case *ast.DeclStmt:
+		return stmt.Decl.(*ast.GenDecl).Tok == token.VAR
+	case *ast.AssignStmt:
+		return stmt.Tok == token.DEFINE
+	}
+	return false
+}
+
+// negate returns syntax for the negation of the boolean expression x.
+func negate(x ast.Expr) ast.Expr {
+	switch x := x.(type) {
+	case *ast.UnaryExpr:
+		if x.Op == token.NOT {
+			return x.X
+		}
+	case *ast.BinaryExpr:
+		return &ast.UnaryExpr{Op: token.NOT, X: &ast.ParenExpr{X: x}}
+	}
+	return &ast.UnaryExpr{Op: token.NOT, X: x}
+}
+
 // soleUse returns the ident that refers to obj, if there is exactly one.
 func soleUse(info *types.Info, obj types.Object) (sole *ast.Ident) {
 	// This is not efficient, but it is called infrequently.
@@ -11,6 +11,8 @@ import (
 	"encoding/gob"
 	"fmt"
 	"go/ast"
+	"go/format"
+	"go/importer"
 	"go/parser"
 	"go/token"
 	"go/types"
@@ -18,6 +20,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"regexp"
+	"slices"
 	"strings"
 	"testing"
 	"unsafe"
@@ -304,6 +307,9 @@ func doInlineNote(logf func(string, ...any), pkg *packages.Package, file *ast.Fi
 	}
 
 	// Inline succeeded.
+	if err := checkEdits(caller.Content, res); err != nil {
+		return err
+	}
 	got := res.Content
 	if want, ok := want.([]byte); ok {
 		got = append(bytes.TrimSpace(got), '\n')
@@ -372,16 +378,71 @@ type testcase struct {
 func TestErrors(t *testing.T) {
 	runTests(t, []testcase{
 		{
-			"Inference of type parameters is not yet supported.",
+			"Inferred type argument is shadowed in the callee.",
+			`type U int; func f[T any](x T) { type U bool; var y T; print(x, y) }`,
+			`func _() { f(U(0)) }`,
+			`error: cannot inline:.*shadow`,
+		},
+		{
+			"Inferred type argument is a local type that is shadowed at the call.",
+			`func f[T any](x T) { print(x) }`,
+			`func _() { type U int; var x U; { type U bool; f(x) } }`,
+			`error: cannot inline:.*U is shadowed`,
+		},
+	})
+}
+
+func TestGenerics(t *testing.T) {
+	runTests(t, []testcase{
+		{
+			"Inferred type argument.",
 			`func f[T any](x T) T { return x }`,
 			`var _ = f(0)`,
-			`error: type parameter inference is not yet supported`,
+			`var _ = int(0)`,
+		},
+		{
+			"Inferred type argument of composite type.",
+			`func f[T any](x []T) { var y T; print(x, y) }`,
+			`func _() { f([]string{}) }`,
+			`func _() {
+	var y string
+	print([]string{}, y)
+}`,
 		},
 		{
-			"Methods on generic types are not yet supported.",
+			"Partially inferred type arguments.",
+			`func f[T, U any](x U) { var y T; print(x, y) }`,
+			`func _() { f[int8](uint8(1)) }`,
+			`func _() {
+	var y int8
+	print(uint8(uint8(1)), y)
+}`,
+		},
+		{
+			"Inferred type argument is a local type of the caller.",
+			`func f[T any](x T) { var y T; print(x, y) }`,
+			`func _() { type L int; f(L(0)) }`,
+			`func _() {
+	type L int
+	var y L
+	print(L(L(0)), y)
+}`,
+		},
+		{
+			"Method of a generic type.",
 			`type G[T any] struct{}; func (G[T]) f(x T) T { return x }`,
 			`var _ = G[int]{}.f(0)`,
-			`error: generic methods not yet supported`,
+			`var _ = 0`,
+		},
+		{
+			"Pointer method of a generic type.",
+			`type G[T any] struct{ v T }; func (g *G[T]) f(x T) { var y T; g.v = x; print(y) }`,
+			`func _(g *G[string]) { g.f("") }`,
+			`func _(g *G[string]) {
+	var y string
+	g.v = ""
+	print(y)
+}`,
 		},
 	})
 }
@@ -858,19 +919,145 @@ func TestTailCallStrategy(t *testing.T) {
 	})
 }
 
+func TestMultiReturnStrategy(t *testing.T) {
+	runTests(t, []testcase{
+		{
+			"Call statement: operands are evaluated, except nil.",
+			`func f(x int) error { if x < 0 { return e }; return nil }; var e error`,
+			`func _() { f(1) }`,
+			`func _() {
+	if 1 < 0 {
+		_ = e
+	}
+}`,
+		},
+		{
+			"Assignment: non-trivial conversions are explicit.",
+			`func f(x int) (*int, int64) { if x < 0 { return nil, 0 }; return &x, 1 }`,
+			`func _() { var y any; _, y = f(1); _ = y }`,
+			`func _() {
+	var y any
+	var x int = 1
+	if x < 0 {
+		_, y = (*int)(nil), int64(0)
+	} else {
+		_, y = &x, int64(1)
+	}
+	_ = y
+}`,
+		},
+		{
+			"Assignment: trivial conversions are implicit.",
+			`func f(x int) (int, error) { if x < 0 { return 0, e }; return x, nil }; var e error`,
+			`func _() { var err error; _, err = f(1); _ = err }`,
+			`func _() {
+	var err error
+	if 1 < 0 {
+		_, err = 0, e
+	} else {
+		_, err = 1, nil
+	}
+	_ = err
+}`,
+		},
+		{
+			"Assigned variable is shadowed in callee.", // => literalized
+			`func f() int { y := 1; if y > 0 { return y }; return 0 }`,
+			`func _() { var y int; y = f(); _ = y }`,
+			`func _() {
+	var y int
+	y = func() int {
+		y := 1
+		if y > 0 {
+			return y
+		}
+		return 0
+	}()
+	_ = y
+}`,
+		},
+		{
+			"Declared variable would shadow a reference.", // => literalized
+			`func f() int { if y > 0 { return y }; return 0 }; var y int`,
+			`func _() { y := f(); _ = y }`,
+			`func _() {
+	y := func() int {
+		if y > 0 {
+			return y
+		}
+		return 0
+	}()
+	_ = y
+}`,
+		},
+	})
+}
+
 func TestSpreadCalls(t *testing.T) {
 	runTests(t, []testcase{
 		{
 			"Edge case: cannot literalize spread method call.",
 			`type I int
  			func g() (I, I)
-			func (r I) f(x, y I) I {
+			func (r I) f(x I, y any) I {
 				defer g() // force literalization
-				return x + y + r
+				return x + r
 			}`,
 			`func _() I { return recover().(I).f(g()) }`,
 			`error: can't yet inline spread call to method`,
 		},
+		{
+			"Spread method call is reduced using a binding decl.",
+			`type I int
+ 			func g() (I, I)
+			func (r I) f(x, y I) I {
+				defer g()
+				return x + y + r
+			}`,
+			`func _() I { return recover().(I).f(g()) }`,
+			`func _() I {
+	var (
+		r    I = recover().(I)
+		x, y I = g()
+	)
+	defer g()
+	return x + y + r
+}`,
+		},
+		{
+			"Spread call binds a parameter group.",
+			`func f(x, y any) { print(x, y); print(x, y) }; func g() (int, error)`,
+			`func _() { f(g()) }`,
+			`func _() {
+	var x, y any = g()
+	print(x, y)
+	print(x, y)
+}`,
+		},
+		{
+			"Spread call binds several parameter groups of the result types.",
+			`func f(v int, err error) int { if err != nil { panic(err) }; return v }; func g() (int, error)`,
+			`func _() int { return f(g()) }`,
+			`func _() int {
+	var v, err = g()
+	if err != nil {
+		panic(err)
+	}
+	return v
+}`,
+		},
+		{
+			"Spread call cannot bind several parameter groups of other types.",
+			`func f(x int, y any) { print(x); print(y) }; func g() (int, int)`,
+			`func _() { f(g()) }`,
+			`func _() { func(x int, y any) { print(x); print(y) }(g()) }`,
+		},
+		{
+			"Spread call to variadic function cannot be bound.",
+			`func f(xs ...int) { print(xs); print(xs) }; func g() (int, int)`,
+			`func _() { f(g()) }`,
+			`func _() { func(xs ...int) { print(xs); print(xs) }(g()) }`,
+		},
 		{
 			"Spread argument evaluated for effect.",
 			`func f(int, int) {}; func g() (int, int)`,
@@ -1592,10 +1779,14 @@ func TestSubstitutionPreservesParameterType(t *testing.T) {
 			`func _() { var t T; _ = &t == (t).id() }`,
 		},
 		{
-			"Implicit dereference is made explicit outside of selector", // TODO(rfindley): avoid unnecessary literalization here
+			"Implicit dereference is made explicit outside of selector",
 			`type T int; func (x T) f() bool { return x == x.id() }; func (x T) id() T { return x }`,
 			`func _() { var t *T; _ = t.f() }`,
-			`func _() { var t *T; _ = func() bool { var x T = *t; return x == x.id() }() }`,
+			`func _() {
+	var t *T
+	var x T = *t
+	_ = x == x.id()
+}`,
 		},
 		{
 			"Check for shadowing error on type used in the conversion.",
@@ -1807,158 +1998,201 @@ func TestRedundantConversions(t *testing.T) {
 
 func runTests(t *testing.T, tests []testcase) {
 	for _, test := range tests {
-		t.Run(test.descr, func(t *testing.T) {
-			fset := token.NewFileSet()
-			mustParse := func(filename string, content any) *ast.File {
-				f, err := parser.ParseFile(fset, filename, content, parser.ParseComments|parser.SkipObjectResolution)
-				if err != nil {
-					t.Fatalf("ParseFile: %v", err)
-				}
-				return f
-			}
-
-			// Parse callee file and find first func decl named f.
-			calleeContent := "package p\n" + test.callee
-			calleeFile := mustParse("callee.go", calleeContent)
-			var decl *ast.FuncDecl
-			for _, d := range calleeFile.Decls {
-				if d, ok := d.(*ast.FuncDecl); ok && d.Name.Name == funcName {
-					decl = d
-					break
-				}
-			}
-			if decl == nil {
-				t.Fatalf("declaration of func %s not found: %s", funcName, test.callee)
-			}
+		t.Run(test.descr, func(t *testing.T) { runTest(t, test, nil) })
+	}
+}
 
-			// Parse caller file and find first call to f().
-			callerContent := "package p\n" + test.caller
-			callerFile := mustParse("caller.go", callerContent)
-			var call *ast.CallExpr
-			ast.Inspect(callerFile, func(n ast.Node) bool {
-				if n, ok := n.(*ast.CallExpr); ok {
-					switch fun := n.Fun.(type) {
-					case *ast.SelectorExpr:
-						if fun.Sel.Name == funcName {
-							call = n
-						}
-					case *ast.Ident:
-						if fun.Name == funcName {
-							call = n
-						}
-					case *ast.IndexExpr:
-						if id, ok := fun.X.(*ast.Ident); ok && id.Name == funcName {
-							call = n
-						}
-					case *ast.IndexListExpr:
-						if id, ok := fun.X.(*ast.Ident); ok && id.Name == funcName {
-							call = n
-						}
-					}
-				}
-				return call == nil
-			})
-			if call == nil {
-				t.Fatalf("call to %s not found: %s", funcName, test.caller)
-			}
+// runTest runs a single testcase. If reasons is non-nil, it also checks
+// the kinds of the reasons given by the InlineReport.
+func runTest(t *testing.T, test testcase, reasons []inline.ReasonKind) {
+	fset := token.NewFileSet()
+	mustParse := func(filename string, content any) *ast.File {
+		f, err := parser.ParseFile(fset, filename, content, parser.ParseComments|parser.SkipObjectResolution)
+		if err != nil {
+			t.Fatalf("ParseFile: %v", err)
+		}
+		return f
+	}
 
-			// Type check both files as one package.
-			info := &types.Info{
-				Defs:       make(map[*ast.Ident]types.Object),
-				Uses:       make(map[*ast.Ident]types.Object),
-				Types:      make(map[ast.Expr]types.TypeAndValue),
-				Implicits:  make(map[ast.Node]types.Object),
-				Selections: make(map[*ast.SelectorExpr]*types.Selection),
-				Scopes:     make(map[ast.Node]*types.Scope),
-			}
-			conf := &types.Config{Error: func(err error) { t.Error(err) }}
-			pkg, err := conf.Check("p", fset, []*ast.File{callerFile, calleeFile}, info)
-			if err != nil {
-				t.Fatal("transformation introduced type errors")
-			}
+	// Parse callee file and find first func decl named f.
+	calleeContent := "package p\n" + test.callee
+	calleeFile := mustParse("callee.go", calleeContent)
+	var decl *ast.FuncDecl
+	for _, d := range calleeFile.Decls {
+		if d, ok := d.(*ast.FuncDecl); ok && d.Name.Name == funcName {
+			decl = d
+			break
+		}
+	}
+	if decl == nil {
+		t.Fatalf("declaration of func %s not found: %s", funcName, test.callee)
+	}
 
-			// Analyze callee and inline call.
-			doIt := func() (*inline.Result, error) {
-				callee, err := inline.AnalyzeCallee(t.Logf, fset, pkg, info, decl, []byte(calleeContent))
-				if err != nil {
-					return nil, err
+	// Parse caller file and find first call to f().
+	callerContent := "package p\n" + test.caller
+	callerFile := mustParse("caller.go", callerContent)
+	var call *ast.CallExpr
+	ast.Inspect(callerFile, func(n ast.Node) bool {
+		if n, ok := n.(*ast.CallExpr); ok {
+			switch fun := n.Fun.(type) {
+			case *ast.SelectorExpr:
+				if fun.Sel.Name == funcName {
+					call = n
 				}
-				if err := checkTranscode(callee); err != nil {
-					t.Fatal(err)
+			case *ast.Ident:
+				if fun.Name == funcName {
+					call = n
 				}
-
-				caller := &inline.Caller{
-					Fset:    fset,
-					Types:   pkg,
-					Info:    info,
-					File:    callerFile,
-					Call:    call,
-					Content: []byte(callerContent),
-				}
-				check := checkNoMutation(caller.File)
-				defer check()
-				return inline.Inline(caller, callee, &inline.Options{
-					Logf:          t.Logf,
-					IgnoreEffects: strings.Contains(test.descr, "IgnoreEffects"),
-				})
-			}
-			res, err := doIt()
-
-			// Want error?
-			if rest, ok := strings.CutPrefix(test.want, "error: "); ok {
-				if err == nil {
-					t.Fatalf("unexpected success: want error matching %q", rest)
+			case *ast.IndexExpr:
+				if id, ok := fun.X.(*ast.Ident); ok && id.Name == funcName {
+					call = n
 				}
-				msg := err.Error()
-				if ok, err := regexp.MatchString(rest, msg); err != nil {
-					t.Fatalf("invalid regexp: %v", err)
-				} else if !ok {
-					t.Fatalf("wrong error: %s (want match for %q)", msg, rest)
+			case *ast.IndexListExpr:
+				if id, ok := fun.X.(*ast.Ident); ok && id.Name == funcName {
+					call = n
 				}
-				return
 			}
+		}
+		return call == nil
+	})
+	if call == nil {
+		t.Fatalf("call to %s not found: %s", funcName, test.caller)
+	}
 
-			// Want success.
-			if err != nil {
-				t.Fatal(err)
-			}
+	// Type check both files as one package.
+	info := &types.Info{
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Implicits:  make(map[ast.Node]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		Scopes:     make(map[ast.Node]*types.Scope),
+		Instances:  make(map[*ast.Ident]types.Instance),
+	}
+	conf := &types.Config{Error: func(err error) { t.Error(err) }}
+	pkg, err := conf.Check("p", fset, []*ast.File{callerFile, calleeFile}, info)
+	if err != nil {
+		t.Fatal("transformation introduced type errors")
+	}
 
-			gotContent := res.Content
+	// Analyze callee and inline call.
+	var report inline.InlineReport
+	doIt := func() (*inline.Result, error) {
+		callee, err := inline.AnalyzeCallee(t.Logf, fset, pkg, info, decl, []byte(calleeContent))
+		if err != nil {
+			return nil, err
+		}
+		if err := checkTranscode(callee); err != nil {
+			t.Fatal(err)
+		}
 
-			// Compute a single-hunk line-based diff.
-			srcLines := strings.Split(callerContent, "\n")
-			gotLines := strings.Split(string(gotContent), "\n")
-			for len(srcLines) > 0 && len(gotLines) > 0 &&
-				srcLines[0] == gotLines[0] {
-				srcLines = srcLines[1:]
-				gotLines = gotLines[1:]
-			}
-			for len(srcLines) > 0 && len(gotLines) > 0 &&
-				srcLines[len(srcLines)-1] == gotLines[len(gotLines)-1] {
-				srcLines = srcLines[:len(srcLines)-1]
-				gotLines = gotLines[:len(gotLines)-1]
-			}
-			got := strings.Join(gotLines, "\n")
-
-			if strings.TrimSpace(got) != strings.TrimSpace(test.want) {
-				t.Fatalf("\nInlining this call:\t%s\nof this callee:    \t%s\nproduced:\n%s\nWant:\n\n%s",
-					test.caller,
-					test.callee,
-					got,
-					test.want)
+		caller := &inline.Caller{
+			Fset:    fset,
+			Types:   pkg,
+			Info:    info,
+			File:    callerFile,
+			Call:    call,
+			Content: []byte(callerContent),
+		}
+		check := checkNoMutation(caller.File)
+		defer check()
+		return inline.Inline(caller, callee, &inline.Options{
+			Logf:          t.Logf,
+			IgnoreEffects: strings.Contains(test.descr, "IgnoreEffects"),
+			Report:        &report,
+		})
+	}
+	res, err := doIt()
+
+	// Want reasons?
+	if reasons != nil {
+		var got []inline.ReasonKind
+		for _, r := range report.Reasons {
+			t.Logf("%s: %v", fset.Position(r.Pos), r)
+			got = append(got, r.Kind)
+			if !(callerFile.FileStart <= r.Pos && r.Pos <= callerFile.FileEnd) {
+				t.Errorf("reason %q is not within the caller", r)
 			}
+		}
+		if !slices.Equal(got, reasons) {
+			t.Errorf("got reasons %v, want %v", got, reasons)
+		}
+	}
 
-			// Check that resulting code type-checks.
-			newCallerFile := mustParse("newcaller.go", gotContent)
-			if _, err := conf.Check("p", fset, []*ast.File{newCallerFile, calleeFile}, nil); err != nil {
-				t.Fatalf("modified source failed to typecheck: <<%s>>", gotContent)
-			}
-		})
+	// Want error?
+	if rest, ok := strings.CutPrefix(test.want, "error: "); ok {
+		if err == nil {
+			t.Fatalf("unexpected success: want error matching %q", rest)
+		}
+		msg := err.Error()
+		if ok, err := regexp.MatchString(rest, msg); err != nil {
+			t.Fatalf("invalid regexp: %v", err)
+		} else if !ok {
+			t.Fatalf("wrong error: %s (want match for %q)", msg, rest)
+		}
+		return
+	}
+
+	// Want success.
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := checkEdits([]byte(callerContent), res); err != nil {
+		t.Fatal(err)
+	}
+	gotContent := res.Content
+
+	// Compute a single-hunk line-based diff.
+	srcLines := strings.Split(callerContent, "\n")
+	gotLines := strings.Split(string(gotContent), "\n")
+	for len(srcLines) > 0 && len(gotLines) > 0 &&
+		srcLines[0] == gotLines[0] {
+		srcLines = srcLines[1:]
+		gotLines = gotLines[1:]
+	}
+	for len(srcLines) > 0 && len(gotLines) > 0 &&
+		srcLines[len(srcLines)-1] == gotLines[len(gotLines)-1] {
+		srcLines = srcLines[:len(srcLines)-1]
+		gotLines = gotLines[:len(gotLines)-1]
+	}
+	got := strings.Join(gotLines, "\n")
+
+	if strings.TrimSpace(got) != strings.TrimSpace(test.want) {
+		t.Fatalf("\nInlining this call:\t%s\nof this callee:    \t%s\nproduced:\n%s\nWant:\n\n%s",
+			test.caller,
+			test.callee,
+			got,
+			test.want)
+	}
+
+	// Check that resulting code type-checks.
+	newCallerFile := mustParse("newcaller.go", gotContent)
+	if _, err := conf.Check("p", fset, []*ast.File{newCallerFile, calleeFile}, nil); err != nil {
+		t.Fatalf("modified source failed to typecheck: <<%s>>", gotContent)
 	}
 }
 
 // -- helpers --
 
+// checkEdits checks that the structured edits of res, applied to the
+// original content of the caller file and formatted, give res.Content.
+func checkEdits(content []byte, res *inline.Result) error {
+	edits := slices.Concat(res.ImportEdits, res.Edits)
+	got, err := diff.ApplyBytes(content, edits)
+	if err != nil {
+		return fmt.Errorf("invalid edits %v: %v", edits, err)
+	}
+	formatted, err := format.Source(got)
+	if err != nil {
+		return fmt.Errorf("edits %v give invalid source: %v\n%s", edits, err, got)
+	}
+	if !bytes.Equal(formatted, res.Content) {
+		return fmt.Errorf("edits %v give:\n%s\nwant:\n%s", edits, formatted, res.Content)
+	}
+	return nil
+}
+
 // checkNoMutation returns a function that, when called,
 // asserts that file was not modified since the checkNoMutation call.
 func checkNoMutation(file *ast.File) func() {
@@ -2093,3 +2327,190 @@ func TestDeepHash(t *testing.T) {
 		t.Fatal("bad")
 	}
 }
+
+// TestStructuredEdits checks that the edits of separate inlinings in one
+// file can be merged, and that they preserve the rest of the file.
+func TestStructuredEdits(t *testing.T) {
+	const callerContent = `package p
+
+import "fmt"
+
+func _() {
+	fmt.Println(   "unformatted"   )
+	a := f(1, 2)
+	b := f(3, 4)
+	fmt.Println(a, b)
+}
+`
+	const calleeContent = `package p
+
+import "strings"
+
+func f(x, y int) int { return len(strings.Repeat("x", x)) + y }
+`
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for i, content := range []string{callerContent, calleeContent} {
+		f, err := parser.ParseFile(fset, fmt.Sprintf("%d.go", i), content, parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, f)
+	}
+	info := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Implicits:  make(map[ast.Node]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		Scopes:     make(map[ast.Node]*types.Scope),
+	}
+	conf := &types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	pkg, err := conf.Check("p", fset, files, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decl := files[1].Decls[1].(*ast.FuncDecl)
+	callee, err := inline.AnalyzeCallee(t.Logf, fset, pkg, info, decl, []byte(calleeContent))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var merged []diff.Edit
+	ast.Inspect(files[0], func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok && types.ExprString(call.Fun) == funcName {
+			caller := &inline.Caller{
+				Fset:    fset,
+				Types:   pkg,
+				Info:    info,
+				File:    files[0],
+				Call:    call,
+				Content: []byte(callerContent),
+			}
+			res, err := inline.Inline(caller, callee, &inline.Options{Logf: t.Logf})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := checkEdits(caller.Content, res); err != nil {
+				t.Fatal(err)
+			}
+			var ok bool
+			merged, ok = diff.Merge(merged, slices.Concat(res.ImportEdits, res.Edits))
+			if !ok {
+				t.Fatalf("edits of %s conflict", types.ExprString(call))
+			}
+		}
+		return true
+	})
+
+	got, err := diff.Apply(callerContent, merged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `package p
+
+import (
+	"fmt"
+	"strings"
+)
+
+func _() {
+	fmt.Println(   "unformatted"   )
+	a := len(strings.Repeat("x", 1)) + 2
+	b := len(strings.Repeat("x", 3)) + 4
+	fmt.Println(a, b)
+}
+`
+	if got != want {
+		t.Errorf("merged edits give:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// TestReport checks the reasons that an InlineReport gives for
+// inlinings that could not reduce the call.
+func TestReport(t *testing.T) {
+	for _, test := range []struct {
+		testcase
+		reasons []inline.ReasonKind
+	}{
+		{
+			testcase{
+				"Reduced call has no reasons.",
+				`func f(x int) int { return x }`,
+				`func _() { _ = f(1) }`,
+				`func _() { _ = 1 }`,
+			},
+			[]inline.ReasonKind{},
+		},
+		{
+			testcase{
+				"Deferred statements.",
+				`func f(x int) { defer print(x) }`,
+				`func _() { f(1) }`,
+				`func _() { func() { defer print(1) }() }`,
+			},
+			[]inline.ReasonKind{inline.BodyShape},
+		},
+		{
+			testcase{
+				"Assigned parameter, in expression context.",
+				`func f(x int) int { x++; return x }`,
+				`func _() { print(f(1)) }`,
+				`func _() { print(func() int { var x int = 1; x++; return x }()) }`,
+			},
+			[]inline.ReasonKind{inline.BindingDecl, inline.BodyShape},
+		},
+		{
+			testcase{
+				"Results assigned to a field.",
+				`func f(x int) int { if x < 0 { return -1 }; return 1 }; type S struct{ f int }`,
+				`func _(s *S) { s.f = f(2) }`,
+				`func _(s *S) {
+	s.f = func() int {
+		if 2 < 0 {
+			return -1
+		}
+		return 1
+	}()
+}`,
+			},
+			[]inline.ReasonKind{inline.Unsupported},
+		},
+		{
+			testcase{
+				"Argument effects.",
+				`func f(x, y int) int { return y + x }; func g() int`,
+				`func _() { print(f(g(), g())) }`,
+				`func _() { print(func() int { var x int = g(); return g() + x }()) }`,
+			},
+			[]inline.ReasonKind{inline.EffectsOrder, inline.BindingDecl},
+		},
+		{
+			testcase{
+				"Argument shadowed in callee.",
+				`func f(x int) int { y := 0; return x + y }`,
+				`func _() { y := 1; _ = f(y) }`,
+				`func _() {
+	y := 1
+	{
+		var x int = y
+		y := 0
+		_ = x + y
+	}
+}`,
+			},
+			[]inline.ReasonKind{inline.Shadowing},
+		},
+		{
+			testcase{
+				"Built-in shadowed in caller.",
+				`func f() int { return len("") }`,
+				`func _() { len := 0; _ = f(); _ = len }`,
+				`error: refers to built-in "len", which in the caller is shadowed`,
+			},
+			[]inline.ReasonKind{inline.Shadowing},
+		},
+	} {
+		t.Run(test.descr, func(t *testing.T) { runTest(t, test.testcase, test.reasons) })
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package inline
+
+// This file defines the report of the reasons that prevented the
+// inliner from reducing a call.
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+)
+
+// An InlineReport explains why the inliner could not reduce a call to
+// its simplest form: why it had to bind some parameters with a
+// "var params = args" declaration, why it literalized the call as
+// func(params){body}(args), or why it failed.
+//
+// A report is populated only if Options.Report is set. Reasons are
+// appended to it only when the result uses a binding declaration or is
+// literalized, or when Inline returns an error.
+type InlineReport struct {
+	Reasons []Reason
+}
+
+// A Reason is a single explanation in an InlineReport.
+type Reason struct {
+	Kind    ReasonKind
+	Pos     token.Pos // position in the caller file: the call, or one of its arguments
+	Decl    token.Pos // position of a related declaration (for example, a shadowing one), or NoPos
+	Message string
+}
+
+func (r Reason) String() string { return r.Kind.String() + ": " + r.Message }
+
+// A ReasonKind classifies the Reasons of an InlineReport.
+type ReasonKind int
+
+const (
+	EffectsOrder ReasonKind = iota + 1 // substitution would change the order of effects of the arguments
+	Shadowing                          // a name would refer to a different declaration after inlining
+	Unexported                         // the callee refers to a declaration the caller cannot access
+	BodyShape                          // the callee's defer or return statements prevent reduction
+	BindingDecl                        // a parameter must be bound by a declaration
+	Unsupported                        // the inliner does not support some aspect of the call
+)
+
+func (k ReasonKind) String() string {
+	switch k {
+	case EffectsOrder:
+		return "effects order"
+	case Shadowing:
+		return "shadowing"
+	case Unexported:
+		return "unexported reference"
+	case BodyShape:
+		return "body shape"
+	case BindingDecl:
+		return "binding declaration"
+	case Unsupported:
+		return "unsupported"
+	}
+	return fmt.Sprintf("ReasonKind(%d)", int(k))
+}
+
+// An explainer records a Reason; see [state.explain].
+// Its pos defaults to the call.
+type explainer = func(kind ReasonKind, pos, decl token.Pos, format string, args ...any)
+
+// explain records a Reason for the current inlining, if a report was
+// requested. Arguments that are syntax nodes, printed with %v, are
+// formatted as Go source.
+func (st *state) explain(kind ReasonKind, pos, decl token.Pos, format string, args ...any) {
+	if st.opts.Report == nil {
+		return
+	}
+	for i, arg := range args {
+		if n, ok := arg.(ast.Node); ok {
+			args[i] = debugFormatNode(token.NewFileSet(), n)
+		}
+	}
+	if !pos.IsValid() {
+		pos = st.caller.Call.Pos()
+	}
+	st.reasons = append(st.reasons, Reason{
+		Kind:    kind,
+		Pos:     pos,
+		Decl:    decl,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// reject records a Reason for the failure of the current inlining,
+// and returns it as an error.
+func (st *state) reject(kind ReasonKind, pos, decl token.Pos, format string, args ...any) error {
+	err := fmt.Errorf(format, args...)
+	st.explain(kind, pos, decl, "%v", err)
+	return err
+}